@@ -2,49 +2,370 @@ package grpc_client
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"net"
+	"io"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
 )
 
-var ErrInvalidIP = errors.New("invalid IP address")
+var (
+	ErrInvalidIP = errors.New("invalid IP address")
+	// ErrNotFound wraps sql.ErrNoRows, so callers can treat a missing location the same way as a missing row.
+	ErrNotFound = fmt.Errorf("location not found: %w", sql.ErrNoRows)
+	// ErrASNNotFound wraps sql.ErrNoRows, like ErrNotFound.
+	ErrASNNotFound = fmt.Errorf("asn not found: %w", sql.ErrNoRows)
+	// ErrImportNotFound wraps sql.ErrNoRows, like ErrNotFound.
+	ErrImportNotFound = fmt.Errorf("import not found: %w", sql.ErrNoRows)
+	// ErrCircuitOpen is the message of the codes.Unavailable error returned while the circuit breaker is open.
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+)
+
+// MissingLocationsError is returned by GetDistance when some of the IPs have no location. It wraps ErrNotFound.
+type MissingLocationsError struct {
+	IPs []string
+}
+
+func (e *MissingLocationsError) Error() string {
+	return fmt.Sprintf("location not found for %s", strings.Join(e.IPs, ", "))
+}
+
+func (e *MissingLocationsError) Unwrap() error {
+	return ErrNotFound
+}
+
+// roundRobinServiceConfig balances calls over every address the target resolves to, instead of the default
+// pick_first policy that sticks to a single server.
+const roundRobinServiceConfig = `{"loadBalancingConfig": [{"round_robin": {}}]}`
 
 type Client struct {
-	grpcClient pb.GeolocationClient
+	conn          *grpc.ClientConn
+	grpcClient    pb.GeolocationClient
+	importsClient pb.ImportServiceClient
 }
 
-func NewClient(host, port string) (*Client, error) {
-	var opts []grpc.DialOption
-	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%s", host, port), opts...)
-	if err != nil {
-		return nil, err
+// NewClient creates a client for the GRPC server(s) at target, which can be any target understood by GRPC
+// (e.g. dns:///importer:8080, which balances over all the addresses the name resolves to) or one built via
+// StaticTarget.
+//
+// The connection is established in the background; use Ready to wait for it.
+func NewClient(target string, opts ...Option) (*Client, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := o.validate(); err != nil {
+		return nil, fmt.Errorf("grpc client - invalid options: %w", err)
 	}
 
-	client := pb.NewGeolocationClient(conn)
+	// Interceptors run in order: the deadline wraps every attempt, and the circuit breaker sees the outcome of
+	// the call only after all retries are done
+	var interceptors []grpc.UnaryClientInterceptor
+	if o.callTimeout > 0 {
+		interceptors = append(interceptors, deadlineInterceptor(o.callTimeout))
+	}
+	if o.breakerThreshold > 0 {
+		interceptors = append(interceptors, breakerInterceptor(newCircuitBreaker(o.breakerThreshold, o.breakerCooldown)))
+	}
+	if o.maxAttempts > 1 {
+		interceptors = append(interceptors, retryInterceptor(o.maxAttempts, o.initialBackoff, o.maxBackoff))
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithResolvers(staticBuilder{}),
+		grpc.WithDefaultServiceConfig(roundRobinServiceConfig),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                o.keepaliveTime,
+			Timeout:             o.keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}
+	dialOpts = append(dialOpts, o.dialOptions...)
+
+	conn, err := grpc.Dial(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("grpc client - failed to dial %s: %w", target, err)
+	}
 
 	return &Client{
-		grpcClient: client,
+		conn:          conn,
+		grpcClient:    pb.NewGeolocationClient(conn),
+		importsClient: pb.NewImportServiceClient(conn),
 	}, nil
 }
 
-func (c *Client) GetLocationData(ctx context.Context, ip string) (*pb.LocationResponse, error) {
-	// Checking if the IP is valid
-	ipAddress := net.ParseIP(ip)
-	if ipAddress == nil {
+// Ready blocks until the client is connected to at least one server, returning an error if that doesn't happen
+// before ctx is done.
+func (c *Client) Ready(ctx context.Context) error {
+	c.conn.Connect()
+
+	for {
+		state := c.conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("grpc client - not ready (state %s): %w", state, ctx.Err())
+		}
+	}
+}
+
+// IsReady tells if the client is connected to at least one server, or still connecting, without blocking. Only
+// failing to connect (or the client being closed) makes it not ready. Connections left idle after a while without
+// calls are woken up, so they're ready again by the time they're needed.
+func (c *Client) IsReady() bool {
+	switch c.conn.GetState() {
+	case connectivity.Ready, connectivity.Connecting:
+		return true
+	case connectivity.Idle:
+		c.conn.Connect()
+		return true
+	default:
+		return false
+	}
+}
+
+// Close tears down the connection to the GRPC server(s).
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// LookupOption customizes a single GetLocationData call.
+type LookupOption func(*pb.LocationRequest)
+
+// AsOf resolves the IP against the dataset that was active at t, instead of the current one.
+func AsOf(t time.Time) LookupOption {
+	return func(req *pb.LocationRequest) {
+		req.AsOf = timestamppb.New(t)
+	}
+}
+
+// IncludeAttributes returns the named vendor attributes of the location, "*" returning all of them.
+func IncludeAttributes(names ...string) LookupOption {
+	return func(req *pb.LocationRequest) {
+		req.Include = append(req.Include, names...)
+	}
+}
+
+func (c *Client) GetLocationData(ctx context.Context, ip string, opts ...LookupOption) (*pb.LocationResponse, error) {
+	// Checking if the IP is valid, and sending it in canonical form
+	ipAddress, err := models.ParseIP(ip)
+	if err != nil {
 		return nil, ErrInvalidIP
 	}
 
 	req := &pb.LocationRequest{Ip: ipAddress.String()}
+	for _, opt := range opts {
+		opt(req)
+	}
+
 	data, err := c.grpcClient.GetLocationData(ctx, req)
 	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrNotFound
+		}
+
 		return nil, err
 	}
 
 	return data, nil
 }
+
+// GetASN gets the network containing ip and its autonomous system as of asOf, or currently if asOf is zero.
+func (c *Client) GetASN(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error) {
+	ipAddress, err := models.ParseIP(ip)
+	if err != nil {
+		return nil, ErrInvalidIP
+	}
+
+	req := &pb.ASNRequest{Ip: ipAddress.String()}
+	if !asOf.IsZero() {
+		req.AsOf = timestamppb.New(asOf)
+	}
+
+	data, err := c.grpcClient.GetASN(ctx, req)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrASNNotFound
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ListLocations lists the locations selected by req, calling fn with each page as it's received. It stops with the
+// first error, either receiving a page or returned by fn.
+func (c *Client) ListLocations(ctx context.Context, req *pb.ListLocationsRequest,
+	fn func(page *pb.ListLocationsResponse) error) error {
+
+	stream, err := c.grpcClient.ListLocations(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		page, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+}
+
+// FindNearbyLocations finds the locations in the area of req, either a circle or a bounding box.
+func (c *Client) FindNearbyLocations(ctx context.Context,
+	req *pb.NearbyLocationsRequest) ([]*pb.NearbyLocation, error) {
+
+	response, err := c.grpcClient.FindNearbyLocations(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.GetLocations(), nil
+}
+
+// GetDistance gets the distance between the locations of the IPs of req, returning a *MissingLocationsError if any
+// of them has no location.
+func (c *Client) GetDistance(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+	from, fromErr := models.ParseIP(req.GetFromIp())
+	to, toErr := models.ParseIP(req.GetToIp())
+	if fromErr != nil || toErr != nil {
+		return nil, ErrInvalidIP
+	}
+
+	// The request of the caller is left untouched
+	canonical := &pb.DistanceRequest{
+		FromIp:      from.String(),
+		ToIp:        to.String(),
+		Elapsed:     req.GetElapsed(),
+		MaxSpeedKmh: req.GetMaxSpeedKmh(),
+	}
+
+	data, err := c.grpcClient.GetDistance(ctx, canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data.GetMissingIps()) > 0 {
+		return nil, &MissingLocationsError{IPs: data.GetMissingIps()}
+	}
+
+	return data, nil
+}
+
+// GetStats summarizes the current locations.
+func (c *Client) GetStats(ctx context.Context) (*pb.StatsResponse, error) {
+	return c.grpcClient.GetStats(ctx, &pb.StatsRequest{})
+}
+
+// ListImports lists up to limit imports, most recent first. A zero limit uses the server default.
+func (c *Client) ListImports(ctx context.Context, limit int) ([]*pb.Import, error) {
+	data, err := c.importsClient.ListImports(ctx, &pb.ListImportsRequest{Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetImports(), nil
+}
+
+func (c *Client) GetImport(ctx context.Context, id int64) (*pb.Import, error) {
+	data, err := c.importsClient.GetImport(ctx, &pb.GetImportRequest{Id: id})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrImportNotFound
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// uploadChunkSize is the size of the chunks uploaded files are sent in.
+const uploadChunkSize = 64 * 1024
+
+// AdminContext returns a copy of ctx authenticating calls to admin methods (StartImport, UploadImport and
+// CancelImport) with token.
+func AdminContext(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// StartImport starts importing a file available to the GRPC server, returning the ID of the import.
+func (c *Client) StartImport(ctx context.Context, file string) (int64, error) {
+	data, err := c.importsClient.StartImport(ctx, &pb.StartImportRequest{File: file})
+	if err != nil {
+		return 0, err
+	}
+
+	return data.GetId(), nil
+}
+
+// UploadImport uploads the contents of src to the GRPC server, which imports them once they're fully received.
+// It returns the ID of the import.
+func (c *Client) UploadImport(ctx context.Context, src io.Reader) (int64, error) {
+	stream, err := c.importsClient.UploadImport(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, uploadChunkSize)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&pb.UploadImportRequest{Chunk: buf[:n]}); sendErr != nil {
+				// The server ended the stream, the actual error is returned by CloseAndRecv
+				if errors.Is(sendErr, io.EOF) {
+					break
+				}
+
+				return 0, sendErr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("grpc client - failed to read upload: %w", err)
+		}
+	}
+
+	data, err := stream.CloseAndRecv()
+	if err != nil {
+		return 0, err
+	}
+
+	return data.GetId(), nil
+}
+
+// CancelImport cancels a running import.
+func (c *Client) CancelImport(ctx context.Context, id int64) error {
+	_, err := c.importsClient.CancelImport(ctx, &pb.CancelImportRequest{Id: id})
+
+	return err
+}
@@ -1,18 +1,57 @@
 package grpc_client
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"errors"
+	"io"
+	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
 )
 
 type grpcClientMock struct {
 	GetLocationDataFn func(ctx context.Context, in *pb.LocationRequest) (*pb.LocationResponse, error)
+	GetASNFn          func(ctx context.Context, in *pb.ASNRequest) (*pb.ASNResponse, error)
+	ListLocationsFn   func(ctx context.Context, in *pb.ListLocationsRequest) (pb.Geolocation_ListLocationsClient, error)
+	FindNearbyFn      func(ctx context.Context, in *pb.NearbyLocationsRequest) (*pb.NearbyLocationsResponse, error)
+	GetDistanceFn     func(ctx context.Context, in *pb.DistanceRequest) (*pb.DistanceResponse, error)
+	GetStatsFn        func(ctx context.Context, in *pb.StatsRequest) (*pb.StatsResponse, error)
+}
+
+func (m *grpcClientMock) ListLocations(ctx context.Context, in *pb.ListLocationsRequest,
+	_ ...grpc.CallOption) (pb.Geolocation_ListLocationsClient, error) {
+
+	return m.ListLocationsFn(ctx, in)
+}
+
+func (m *grpcClientMock) FindNearbyLocations(ctx context.Context, in *pb.NearbyLocationsRequest,
+	_ ...grpc.CallOption) (*pb.NearbyLocationsResponse, error) {
+
+	return m.FindNearbyFn(ctx, in)
+}
+
+func (m *grpcClientMock) GetDistance(ctx context.Context, in *pb.DistanceRequest,
+	_ ...grpc.CallOption) (*pb.DistanceResponse, error) {
+
+	return m.GetDistanceFn(ctx, in)
+}
+
+func (m *grpcClientMock) GetStats(ctx context.Context, in *pb.StatsRequest,
+	_ ...grpc.CallOption) (*pb.StatsResponse, error) {
+
+	return m.GetStatsFn(ctx, in)
 }
 
 func (m *grpcClientMock) GetLocationData(ctx context.Context, in *pb.LocationRequest,
@@ -21,6 +60,10 @@ func (m *grpcClientMock) GetLocationData(ctx context.Context, in *pb.LocationReq
 	return m.GetLocationDataFn(ctx, in)
 }
 
+func (m *grpcClientMock) GetASN(ctx context.Context, in *pb.ASNRequest, _ ...grpc.CallOption) (*pb.ASNResponse, error) {
+	return m.GetASNFn(ctx, in)
+}
+
 func Test_GetLocationData(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -42,6 +85,16 @@ func Test_GetLocationData(t *testing.T) {
 			ipAddress:   "a",
 			expectedErr: ErrInvalidIP,
 		},
+		{
+			name:      "not found status should return ErrNotFound",
+			ipAddress: "192.168.0.1",
+			grpcClient: &grpcClientMock{
+				GetLocationDataFn: func(ctx context.Context, in *pb.LocationRequest) (*pb.LocationResponse, error) {
+					return nil, status.Error(codes.NotFound, "location not found")
+				},
+			},
+			expectedErr: ErrNotFound,
+		},
 		{
 			name:      "internal server error should return error",
 			ipAddress: "192.168.0.1",
@@ -55,6 +108,7 @@ func Test_GetLocationData(t *testing.T) {
 	}
 
 	for _, test := range tests {
+		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -66,3 +120,617 @@ func Test_GetLocationData(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetLocationData_addressForms(t *testing.T) {
+	tests := []struct {
+		name        string
+		ipAddress   string
+		expectedIP  string
+		expectedErr error
+	}{
+		{name: "IPv4", ipAddress: "192.168.0.1", expectedIP: "192.168.0.1"},
+		{name: "IPv4-mapped IPv6", ipAddress: "::ffff:192.168.0.1", expectedIP: "192.168.0.1"},
+		{name: "IPv6", ipAddress: "2001:0DB8:0000::0001", expectedIP: "2001:db8::1"},
+		{name: "IPv6 with a zone should return error", ipAddress: "fe80::1%eth0", expectedErr: ErrInvalidIP},
+		{name: "IPv4 with leading zeros should return error", ipAddress: "192.168.0.01", expectedErr: ErrInvalidIP},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := Client{grpcClient: &grpcClientMock{
+				GetLocationDataFn: func(ctx context.Context, in *pb.LocationRequest) (*pb.LocationResponse, error) {
+					require.Equal(t, test.expectedIP, in.GetIp())
+					return &pb.LocationResponse{}, nil
+				},
+			}}
+
+			_, err := client.GetLocationData(context.Background(), test.ipAddress)
+
+			require.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func Test_GetASN(t *testing.T) {
+	asOf := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		ipAddress    string
+		asOf         time.Time
+		getASNFn     func(ctx context.Context, in *pb.ASNRequest) (*pb.ASNResponse, error)
+		expectedErr  error
+		expectedAsOf bool
+	}{
+		{
+			name:      "success",
+			ipAddress: "192.168.0.1",
+			getASNFn: func(ctx context.Context, in *pb.ASNRequest) (*pb.ASNResponse, error) {
+				return &pb.ASNResponse{}, nil
+			},
+		},
+		{
+			name:      "as of is passed to the server",
+			ipAddress: "192.168.0.1",
+			asOf:      asOf,
+			getASNFn: func(ctx context.Context, in *pb.ASNRequest) (*pb.ASNResponse, error) {
+				if !in.GetAsOf().AsTime().Equal(asOf) {
+					return nil, errors.New("unexpected as_of")
+				}
+				return &pb.ASNResponse{}, nil
+			},
+		},
+		{
+			name:        "invalid IP address should return error",
+			ipAddress:   "a",
+			expectedErr: ErrInvalidIP,
+		},
+		{
+			name:      "not found status should return ErrASNNotFound",
+			ipAddress: "192.168.0.1",
+			getASNFn: func(ctx context.Context, in *pb.ASNRequest) (*pb.ASNResponse, error) {
+				return nil, status.Error(codes.NotFound, "asn not found")
+			},
+			expectedErr: ErrASNNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := Client{grpcClient: &grpcClientMock{GetASNFn: test.getASNFn}}
+
+			_, err := client.GetASN(context.Background(), test.ipAddress, test.asOf)
+
+			require.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+// listLocationsStreamMock receives pages, then err (io.EOF if nil).
+type listLocationsStreamMock struct {
+	grpc.ClientStream
+	pages []*pb.ListLocationsResponse
+	err   error
+}
+
+func (m *listLocationsStreamMock) Recv() (*pb.ListLocationsResponse, error) {
+	if len(m.pages) == 0 {
+		if m.err != nil {
+			return nil, m.err
+		}
+		return nil, io.EOF
+	}
+
+	page := m.pages[0]
+	m.pages = m.pages[1:]
+
+	return page, nil
+}
+
+func Test_ListLocations(t *testing.T) {
+	pages := []*pb.ListLocationsResponse{{NextCursor: "a"}, {}}
+	fnErr := errors.New("fn error")
+
+	tests := []struct {
+		name          string
+		stream        *listLocationsStreamMock
+		fnErr         error
+		expectedPages int
+		expectedErr   error
+	}{
+		{
+			name:          "every page is passed to fn",
+			stream:        &listLocationsStreamMock{pages: pages},
+			expectedPages: 2,
+		},
+		{
+			name:          "stream errors are returned",
+			stream:        &listLocationsStreamMock{pages: pages[:1], err: status.Error(codes.Internal, "failed")},
+			expectedPages: 1,
+			expectedErr:   status.Error(codes.Internal, "failed"),
+		},
+		{
+			name:          "fn errors stop the listing",
+			stream:        &listLocationsStreamMock{pages: pages},
+			fnErr:         fnErr,
+			expectedPages: 1,
+			expectedErr:   fnErr,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := Client{grpcClient: &grpcClientMock{
+				ListLocationsFn: func(ctx context.Context,
+					in *pb.ListLocationsRequest) (pb.Geolocation_ListLocationsClient, error) {
+
+					return test.stream, nil
+				},
+			}}
+
+			var received int
+			err := client.ListLocations(context.Background(), &pb.ListLocationsRequest{CountryCode: "BR"},
+				func(page *pb.ListLocationsResponse) error {
+					received++
+					return test.fnErr
+				})
+
+			require.Equal(t, test.expectedErr, err)
+			require.Equal(t, test.expectedPages, received)
+		})
+	}
+}
+
+type importsClientMock struct {
+	pb.ImportServiceClient
+	ListImportsFn func(ctx context.Context, in *pb.ListImportsRequest) (*pb.ListImportsResponse, error)
+	GetImportFn   func(ctx context.Context, in *pb.GetImportRequest) (*pb.Import, error)
+}
+
+func (m *importsClientMock) ListImports(ctx context.Context, in *pb.ListImportsRequest,
+	_ ...grpc.CallOption) (*pb.ListImportsResponse, error) {
+
+	return m.ListImportsFn(ctx, in)
+}
+
+func (m *importsClientMock) GetImport(ctx context.Context, in *pb.GetImportRequest,
+	_ ...grpc.CallOption) (*pb.Import, error) {
+
+	return m.GetImportFn(ctx, in)
+}
+
+func Test_GetImport(t *testing.T) {
+	tests := []struct {
+		name        string
+		getImportFn func(ctx context.Context, in *pb.GetImportRequest) (*pb.Import, error)
+		expectedErr error
+	}{
+		{
+			name: "success",
+			getImportFn: func(ctx context.Context, in *pb.GetImportRequest) (*pb.Import, error) {
+				return &pb.Import{Id: in.GetId()}, nil
+			},
+		},
+		{
+			name: "not found status should return ErrImportNotFound",
+			getImportFn: func(ctx context.Context, in *pb.GetImportRequest) (*pb.Import, error) {
+				return nil, status.Error(codes.NotFound, "import not found")
+			},
+			expectedErr: ErrImportNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := Client{importsClient: &importsClientMock{GetImportFn: test.getImportFn}}
+
+			_, err := client.GetImport(context.Background(), 1)
+
+			require.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func Test_ListImports(t *testing.T) {
+	client := Client{importsClient: &importsClientMock{
+		ListImportsFn: func(ctx context.Context, in *pb.ListImportsRequest) (*pb.ListImportsResponse, error) {
+			require.Equal(t, int32(5), in.GetLimit())
+			return &pb.ListImportsResponse{Imports: []*pb.Import{{Id: 2}, {Id: 1}}}, nil
+		},
+	}}
+
+	imports, err := client.ListImports(context.Background(), 5)
+
+	require.NoError(t, err)
+	require.Len(t, imports, 2)
+}
+
+func Test_retryInterceptor(t *testing.T) {
+	const (
+		getLocationData = "/grpc_server.Geolocation/GetLocationData"
+		startImport     = "/grpc_server.ImportService/StartImport"
+		cancelImport    = "/grpc_server.ImportService/CancelImport"
+	)
+
+	tests := []struct {
+		name             string
+		method           string
+		errs             []error
+		expectedAttempts int
+		expectedCode     codes.Code
+	}{
+		{
+			name:             "unavailable is retried until it succeeds",
+			method:           getLocationData,
+			errs:             []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), nil},
+			expectedAttempts: 3,
+			expectedCode:     codes.OK,
+		},
+		{
+			name:   "unavailable is retried up to the max attempts",
+			method: getLocationData,
+			errs: []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""),
+				status.Error(codes.Unavailable, ""), nil},
+			expectedAttempts: 3,
+			expectedCode:     codes.Unavailable,
+		},
+		{
+			name:             "other errors aren't retried",
+			method:           getLocationData,
+			errs:             []error{status.Error(codes.NotFound, ""), nil},
+			expectedAttempts: 1,
+			expectedCode:     codes.NotFound,
+		},
+		{
+			name:             "starting an import isn't retried",
+			method:           startImport,
+			errs:             []error{status.Error(codes.Unavailable, ""), nil},
+			expectedAttempts: 1,
+			expectedCode:     codes.Unavailable,
+		},
+		{
+			name:             "cancelling an import isn't retried",
+			method:           cancelImport,
+			errs:             []error{status.Error(codes.Unavailable, ""), nil},
+			expectedAttempts: 1,
+			expectedCode:     codes.Unavailable,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			attempts := 0
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+				opts ...grpc.CallOption) error {
+
+				err := test.errs[attempts]
+				attempts++
+				return err
+			}
+
+			interceptor := retryInterceptor(3, time.Millisecond, 2*time.Millisecond)
+			err := interceptor(context.Background(), test.method, nil, nil, nil, invoker)
+
+			require.Equal(t, test.expectedAttempts, attempts)
+			require.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func Test_NewClient_invalidRetry(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxAttempts    int
+		initialBackoff time.Duration
+		maxBackoff     time.Duration
+		expectedErr    bool
+	}{
+		{name: "valid backoffs", maxAttempts: 3, initialBackoff: time.Millisecond, maxBackoff: time.Second},
+		{name: "no backoff", maxAttempts: 3},
+		{name: "negative initial backoff", maxAttempts: 3, initialBackoff: -time.Second, expectedErr: true},
+		{name: "negative max backoff", maxAttempts: 3, maxBackoff: -time.Second, expectedErr: true},
+		{
+			name:           "max backoff less than the initial backoff",
+			maxAttempts:    3,
+			initialBackoff: time.Second,
+			maxBackoff:     time.Millisecond,
+			expectedErr:    true,
+		},
+		{name: "backoffs are ignored without retries", maxAttempts: 1, initialBackoff: -time.Second},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClient(StaticTarget("127.0.0.1:1"),
+				WithRetry(test.maxAttempts, test.initialBackoff, test.maxBackoff))
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			_ = client.Close()
+		})
+	}
+}
+
+func Test_circuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// Failures below the threshold keep the circuit closed
+	require.True(t, b.allow())
+	b.record(true)
+	require.True(t, b.allow())
+	b.record(true)
+
+	// Threshold reached: calls fail fast until the cooldown has passed
+	require.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+
+	// A single probe is let through; a failed probe opens the circuit again
+	require.True(t, b.allow())
+	require.False(t, b.allow())
+	b.record(true)
+	require.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+
+	// A successful probe closes the circuit
+	require.True(t, b.allow())
+	b.record(false)
+	require.True(t, b.allow())
+	require.True(t, b.allow())
+}
+
+type countingServer struct {
+	pb.UnimplementedGeolocationServer
+	calls int32
+}
+
+func (s *countingServer) GetLocationData(context.Context, *pb.LocationRequest) (*pb.LocationResponse, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return &pb.LocationResponse{}, nil
+}
+
+func Test_NewClient_roundRobin(t *testing.T) {
+	var servers []*countingServer
+	var addresses []string
+
+	for i := 0; i < 2; i++ {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		server := &countingServer{}
+		grpcServer := grpc.NewServer()
+		pb.RegisterGeolocationServer(grpcServer, server)
+		go func() { _ = grpcServer.Serve(lis) }()
+		t.Cleanup(grpcServer.Stop)
+
+		servers = append(servers, server)
+		addresses = append(addresses, lis.Addr().String())
+	}
+
+	client, err := NewClient(StaticTarget(addresses...))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, client.Ready(ctx))
+
+	// Round robin only includes a server once its connection is ready, so the first calls may all go to the same
+	// server; it's enough that both end up getting calls
+	require.Eventually(t, func() bool {
+		_, err := client.GetLocationData(ctx, "192.168.0.1")
+		require.NoError(t, err)
+
+		return atomic.LoadInt32(&servers[0].calls) > 0 && atomic.LoadInt32(&servers[1].calls) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func Test_Ready_unreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	// Nothing is listening on this address anymore
+	require.NoError(t, lis.Close())
+
+	client, err := NewClient(StaticTarget(lis.Addr().String()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, client.Ready(ctx), context.DeadlineExceeded)
+	require.False(t, client.IsReady())
+}
+
+func Test_IsReady_idle(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGeolocationServer(grpcServer, &countingServer{})
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	client, err := NewClient(StaticTarget(lis.Addr().String()),
+		WithDialOptions(grpc.WithIdleTimeout(50*time.Millisecond)))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, client.Ready(ctx))
+
+	// Without calls, the connection goes idle
+	require.Eventually(t, func() bool {
+		return client.conn.GetState() == connectivity.Idle
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Idle connections are ready, and woken up
+	require.True(t, client.IsReady())
+	require.Eventually(t, func() bool {
+		return client.conn.GetState() == connectivity.Ready
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+type uploadServer struct {
+	pb.UnimplementedImportServiceServer
+	received []byte
+	token    []string
+}
+
+func (s *uploadServer) UploadImport(stream pb.ImportService_UploadImportServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	s.token = md.Get("authorization")
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&pb.StartImportResponse{Id: 7})
+		}
+		if err != nil {
+			return err
+		}
+
+		s.received = append(s.received, chunk.GetChunk()...)
+	}
+}
+
+func Test_UploadImport(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &uploadServer{}
+	grpcServer := grpc.NewServer()
+	pb.RegisterImportServiceServer(grpcServer, server)
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	client, err := NewClient(StaticTarget(lis.Addr().String()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	// Larger than a chunk, so it's sent in several messages
+	contents := bytes.Repeat([]byte("1.1.1.1,BR,Brazil,Brasilia,0,0,1\n"), 5000)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := client.UploadImport(AdminContext(ctx, "secret"), bytes.NewReader(contents))
+
+	require.NoError(t, err)
+	require.Equal(t, int64(7), id)
+	require.Equal(t, contents, server.received)
+	require.Equal(t, []string{"Bearer secret"}, server.token)
+}
+
+func Test_FindNearbyLocations(t *testing.T) {
+	location := &pb.NearbyLocation{Location: &pb.LocationResponse{Ip: "1.1.1.1"}, DistanceKm: 14}
+
+	tests := []struct {
+		name         string
+		findNearbyFn func(ctx context.Context, in *pb.NearbyLocationsRequest) (*pb.NearbyLocationsResponse, error)
+		expected     []*pb.NearbyLocation
+		expectedErr  error
+	}{
+		{
+			name: "success",
+			findNearbyFn: func(ctx context.Context, in *pb.NearbyLocationsRequest) (*pb.NearbyLocationsResponse, error) {
+				return &pb.NearbyLocationsResponse{Locations: []*pb.NearbyLocation{location}}, nil
+			},
+			expected: []*pb.NearbyLocation{location},
+		},
+		{
+			name: "errors are returned as is",
+			findNearbyFn: func(ctx context.Context, in *pb.NearbyLocationsRequest) (*pb.NearbyLocationsResponse, error) {
+				return nil, status.Error(codes.InvalidArgument, "invalid bounding box")
+			},
+			expectedErr: status.Error(codes.InvalidArgument, "invalid bounding box"),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := Client{grpcClient: &grpcClientMock{FindNearbyFn: test.findNearbyFn}}
+
+			locations, err := client.FindNearbyLocations(context.Background(), &pb.NearbyLocationsRequest{})
+
+			require.Equal(t, test.expectedErr, err)
+			require.Equal(t, test.expected, locations)
+		})
+	}
+}
+
+func Test_GetDistance(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            *pb.DistanceRequest
+		getDistanceFn func(ctx context.Context, in *pb.DistanceRequest) (*pb.DistanceResponse, error)
+		expectedErr   error
+	}{
+		{
+			name: "IPs are sent in canonical form",
+			in:   &pb.DistanceRequest{FromIp: "2001:DB8::1", ToIp: "1.1.1.1"},
+			getDistanceFn: func(ctx context.Context, in *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+				if in.GetFromIp() != "2001:db8::1" {
+					return nil, errors.New("unexpected from_ip")
+				}
+				return &pb.DistanceResponse{DistanceKm: 10}, nil
+			},
+		},
+		{
+			name:        "invalid IP address should return error",
+			in:          &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "a"},
+			expectedErr: ErrInvalidIP,
+		},
+		{
+			name: "missing locations should return MissingLocationsError",
+			in:   &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "2.2.2.2"},
+			getDistanceFn: func(ctx context.Context, in *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+				return &pb.DistanceResponse{MissingIps: []string{"2.2.2.2"}}, nil
+			},
+			expectedErr: &MissingLocationsError{IPs: []string{"2.2.2.2"}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := Client{grpcClient: &grpcClientMock{GetDistanceFn: test.getDistanceFn}}
+
+			_, err := client.GetDistance(context.Background(), test.in)
+
+			require.Equal(t, test.expectedErr, err)
+		})
+	}
+
+	require.ErrorIs(t, &MissingLocationsError{IPs: []string{"2.2.2.2"}}, sql.ErrNoRows)
+}
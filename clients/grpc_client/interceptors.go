@@ -0,0 +1,103 @@
+package grpc_client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+)
+
+// deadlineInterceptor applies timeout to calls that don't already have an earlier deadline.
+func deadlineInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > timeout {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// retryableMethods are the read-only methods, which can be attempted again without side effects. Other methods
+// (e.g. ImportService.StartImport) are never retried, since a call failing with codes.Unavailable may still have
+// reached the server.
+var retryableMethods = map[string]bool{
+	"/" + pb.Geolocation_ServiceDesc.ServiceName + "/GetLocationData":     true,
+	"/" + pb.Geolocation_ServiceDesc.ServiceName + "/GetASN":              true,
+	"/" + pb.Geolocation_ServiceDesc.ServiceName + "/FindNearbyLocations": true,
+	"/" + pb.Geolocation_ServiceDesc.ServiceName + "/GetDistance":         true,
+	"/" + pb.Geolocation_ServiceDesc.ServiceName + "/GetStats":            true,
+	"/" + pb.ImportService_ServiceDesc.ServiceName + "/ListImports":       true,
+	"/" + pb.ImportService_ServiceDesc.ServiceName + "/GetImport":         true,
+}
+
+// retryInterceptor retries calls to the retryable methods failing with codes.Unavailable up to maxAttempts times in
+// total, with an exponential backoff (plus jitter) between attempts. It gives up early once ctx is done.
+func retryInterceptor(maxAttempts int, initialBackoff, maxBackoff time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+		if !retryableMethods[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		backoff := initialBackoff
+
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			if status.Code(err) != codes.Unavailable || attempt >= maxAttempts {
+				return err
+			}
+
+			// Full jitter, so clients that failed together don't retry together
+			wait := time.Duration(rand.Int63n(int64(backoff) + 1))
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// breakerInterceptor fails calls fast with codes.Unavailable while the circuit breaker is open.
+func breakerInterceptor(b *circuitBreaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+		if !b.allow() {
+			return status.Error(codes.Unavailable, ErrCircuitOpen.Error())
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.record(isBackendFailure(err))
+
+		return err
+	}
+}
+
+// isBackendFailure tells if err means the server couldn't be reached or didn't answer in time, as opposed to
+// the server answering with an error (e.g. codes.NotFound).
+func isBackendFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
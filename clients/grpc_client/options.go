@@ -0,0 +1,97 @@
+package grpc_client
+
+import (
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Option configures a Client created via NewClient.
+type Option func(*options)
+
+type options struct {
+	callTimeout time.Duration
+
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	keepaliveTime    time.Duration
+	keepaliveTimeout time.Duration
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	dialOptions []grpc.DialOption
+}
+
+func defaultOptions() options {
+	return options{
+		callTimeout:      2 * time.Second,
+		maxAttempts:      3,
+		initialBackoff:   100 * time.Millisecond,
+		maxBackoff:       time.Second,
+		keepaliveTime:    30 * time.Second,
+		keepaliveTimeout: 10 * time.Second,
+		breakerThreshold: 5,
+		breakerCooldown:  10 * time.Second,
+	}
+}
+
+// validate checks the options can be used, returning an error describing the first invalid one.
+func (o options) validate() error {
+	switch {
+	case o.maxAttempts > 1 && (o.initialBackoff < 0 || o.maxBackoff < 0):
+		return errors.New("retry backoffs can't be negative")
+	case o.maxAttempts > 1 && o.maxBackoff < o.initialBackoff:
+		return errors.New("retry max backoff can't be less than the initial backoff")
+	}
+
+	return nil
+}
+
+// WithCallTimeout sets the deadline applied to each call, retries included. Calls whose context already has an
+// earlier deadline keep it. A zero value disables the client-side deadline.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.callTimeout = timeout
+	}
+}
+
+// WithRetry sets how many times a call failing with codes.Unavailable is attempted, waiting an exponentially
+// growing backoff (starting at initialBackoff and capped at maxBackoff) between attempts. Only the read-only methods
+// are retried. maxAttempts <= 1 disables retries; otherwise the backoffs can't be negative, and NewClient fails if
+// they are.
+func WithRetry(maxAttempts int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.maxAttempts = maxAttempts
+		o.initialBackoff = initialBackoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// WithKeepalive sets how often the connection is pinged when idle and how long to wait for the ping to be
+// acknowledged before the connection is considered broken.
+func WithKeepalive(interval, timeout time.Duration) Option {
+	return func(o *options) {
+		o.keepaliveTime = interval
+		o.keepaliveTimeout = timeout
+	}
+}
+
+// WithCircuitBreaker opens the circuit after threshold consecutive failed calls, failing fast for cooldown before
+// letting a probe call through. A threshold <= 0 disables the circuit breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.breakerThreshold = threshold
+		o.breakerCooldown = cooldown
+	}
+}
+
+// WithDialOptions appends extra options to the ones used to dial the GRPC server.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
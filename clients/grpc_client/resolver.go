@@ -0,0 +1,48 @@
+package grpc_client
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// staticScheme is the target scheme for a fixed list of servers, e.g. static:///host1:8080,host2:8080
+const staticScheme = "static"
+
+// StaticTarget builds a target that balances calls over a fixed list of host:port addresses.
+func StaticTarget(addresses ...string) string {
+	return fmt.Sprintf("%s:///%s", staticScheme, strings.Join(addresses, ","))
+}
+
+// staticBuilder resolves static targets to the addresses listed in them. Unlike DNS, the list never changes.
+type staticBuilder struct{}
+
+func (staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	var addresses []resolver.Address
+	for _, addr := range strings.Split(target.Endpoint(), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addresses = append(addresses, resolver.Address{Addr: addr})
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("grpc client - no addresses in target %q", target.URL.String())
+	}
+
+	if err := cc.UpdateState(resolver.State{Addresses: addresses}); err != nil {
+		return nil, err
+	}
+
+	return staticResolver{}, nil
+}
+
+func (staticBuilder) Scheme() string {
+	return staticScheme
+}
+
+type staticResolver struct{}
+
+func (staticResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (staticResolver) Close() {}
@@ -1,46 +1,110 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/tiagocesar/geolocation/clients/grpc_client"
 	"github.com/tiagocesar/geolocation/handler/http"
+	"github.com/tiagocesar/geolocation/internal/config"
+	"github.com/tiagocesar/geolocation/internal/resolver"
 )
 
-const (
-	EnvHttpServerPort = "HTTP_SERVER_PORT"
+func main() {
+	cfg, err := config.Load("api", os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	EnvGrpcServerHost = "GRPC_SERVER_HOST"
-	EnvGrpcServerPort = "GRPC_SERVER_PORT"
-)
+	if err := cfg.HTTP.Validate(); err != nil {
+		log.Fatal(err)
+	}
 
-func main() {
-	var ok bool
+	if err := cfg.GRPC.ValidateClient(); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Effective configuration:\n%s", cfg)
 
-	// HTTP server vars
-	var httpHost, httpPort string
-	if httpPort, ok = os.LookupEnv(EnvHttpServerPort); !ok {
-		log.Fatalf("environment variable %s not set", EnvHttpServerPort)
+	grpcClient, err := grpc_client.NewClient(grpcTarget(cfg.GRPC),
+		grpc_client.WithCallTimeout(cfg.GRPC.CallTimeout),
+		grpc_client.WithRetry(cfg.GRPC.MaxAttempts, 100*time.Millisecond, time.Second))
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer func() { _ = grpcClient.Close() }()
 
-	// GRPC server vars
-	var grpcHost, grpcPort string
-	if grpcHost, ok = os.LookupEnv(EnvGrpcServerHost); !ok {
-		log.Fatalf("environment variable %s not set", EnvGrpcServerHost)
+	// Failing fast if the GRPC server can't be reached, instead of failing every request
+	readyCtx, cancel := context.WithTimeout(context.Background(), cfg.GRPC.StartupTimeout)
+	err = grpcClient.Ready(readyCtx)
+	cancel()
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	if grpcPort, ok = os.LookupEnv(EnvGrpcServerPort); !ok {
-		log.Fatalf("environment variable %s not set", EnvGrpcServerPort)
+	opts := []http.ServerOption{http.WithTrustedProxies(cfg.HTTP.TrustedProxyNetworks()...)}
+
+	// Hostnames are only resolved if enabled
+	hostResolver, err := newResolver(cfg.HTTP)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if hostResolver != nil {
+		opts = append(opts, http.WithResolver(hostResolver))
 	}
 
-	grpcClient, _ := grpc_client.NewClient(grpcHost, grpcPort)
+	httpServer := http.NewHttpServer(grpcClient, http.Timeouts{
+		Read:     cfg.HTTP.ReadTimeout,
+		Write:    cfg.HTTP.WriteTimeout,
+		Idle:     cfg.HTTP.IdleTimeout,
+		Request:  cfg.HTTP.RequestTimeout,
+		Shutdown: cfg.HTTP.ShutdownTimeout,
+	}, opts...)
 
-	httpServer := http.NewHttpServer(grpcClient)
+	// The server is stopped gracefully, draining in-flight requests, once a signal is received
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Printf("HTTP server starting on %s:%s", httpHost, httpPort)
-	httpServer.ConfigureAndServe(httpPort)
+	log.Printf("HTTP server starting on port %d, admin endpoints on port %d", cfg.HTTP.ServerPort, cfg.HTTP.AdminPort)
+	err = httpServer.ConfigureAndServe(ctx, strconv.Itoa(cfg.HTTP.ServerPort), strconv.Itoa(cfg.HTTP.AdminPort))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	log.Println("HTTP server exiting")
-	os.Exit(0)
+}
+
+// grpcTarget builds the GRPC target from either a list of addresses or a host and port. A host is resolved via
+// DNS, so calls are balanced over all the addresses it resolves to.
+func grpcTarget(cfg config.GRPC) string {
+	if len(cfg.ServerAddresses) > 0 {
+		return grpc_client.StaticTarget(cfg.ServerAddresses...)
+	}
+
+	return fmt.Sprintf("dns:///%s:%d", cfg.ServerHost, cfg.ServerPort)
+}
+
+// newResolver builds the resolver of the hostnames looked up instead of IPs: the hosts file if one is set, or else
+// DNS, with the resolve timeout and cache. It's nil if hostnames aren't resolved.
+func newResolver(cfg config.HTTP) (resolver.Resolver, error) {
+	if !cfg.ResolveHosts {
+		return nil, nil
+	}
+
+	r := resolver.System()
+	if cfg.HostsFile != "" {
+		hosts, err := resolver.LoadHostsFile(cfg.HostsFile)
+		if err != nil {
+			return nil, err
+		}
+		r = hosts
+	}
+
+	return resolver.NewCache(r, resolver.WithTimeout(cfg.ResolveTimeout), resolver.WithTTL(cfg.ResolveCacheTTL)), nil
 }
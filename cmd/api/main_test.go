@@ -0,0 +1,41 @@
+//go:build !integration
+
+package main
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/internal/config"
+)
+
+func Test_newResolver(t *testing.T) {
+	cfg, err := config.Load("test", nil)
+	require.NoError(t, err)
+
+	// Hostnames aren't resolved by default
+	r, err := newResolver(cfg.HTTP)
+	require.NoError(t, err)
+	require.Nil(t, r)
+
+	hostsFile := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(hostsFile, []byte("192.0.2.1 example.com\n"), 0o600))
+
+	cfg.HTTP.ResolveHosts = true
+	cfg.HTTP.HostsFile = hostsFile
+	r, err = newResolver(cfg.HTTP)
+	require.NoError(t, err)
+
+	addrs, err := r.Resolve(context.Background(), "example.com")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("192.0.2.1")}, addrs)
+
+	cfg.HTTP.HostsFile = filepath.Join(t.TempDir(), "missing")
+	_, err = newResolver(cfg.HTTP)
+	require.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/tiagocesar/geolocation/handler/grpc"
+	"github.com/tiagocesar/geolocation/internal/config"
+	"github.com/tiagocesar/geolocation/internal/processor"
+	"github.com/tiagocesar/geolocation/internal/repo"
+	"github.com/tiagocesar/geolocation/internal/repo/migrations"
+)
+
+func main() {
+	cfg, err := config.Load("geoserver", os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cfg.DB.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cfg.GRPC.ValidateServer(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Imports started on demand resolve duplicate IPs as configured for the importer
+	if err := processor.ValidateDuplicatePolicy(cfg.Importer.DuplicatePolicy); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Effective configuration:\n%s", cfg)
+
+	// Configuring access to the repository and opening the SQL connection
+	repository, err := repo.NewRepository(context.Background(), repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The importer applies migrations, the server only serves an up-to-date schema
+	if err := checkMigrations(context.Background(), repository); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Imports started on demand are cancelled once a signal is received
+	runner := processor.NewRunner(ctx, repository, cfg.Importer.TotalRoutines, cfg.Importer.UploadDir,
+		processor.WithProgressInterval(cfg.Importer.ProgressInterval), processor.WithBatchSize(cfg.Importer.BatchSize),
+		processor.WithThresholds(thresholds(cfg.Importer)),
+		processor.WithDuplicatePolicy(cfg.Importer.DuplicatePolicy))
+
+	if cfg.GRPC.AdminToken == "" {
+		log.Println("No admin token set, imports can't be started on demand")
+	}
+
+	listener, grpcServer, err := grpc.NewGrpcServer(strconv.Itoa(cfg.GRPC.ServerPort), repository,
+		grpc.WithImportRunner(runner, cfg.GRPC.AdminToken), grpc.WithMaxTravelSpeed(cfg.GRPC.MaxTravelSpeed))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Stopping the GRPC server gracefully once a signal is received
+	go func() {
+		<-ctx.Done()
+		log.Println("Got signal, stopping server")
+		grpcServer.GracefulStop()
+	}()
+
+	log.Println("Starting GRPC server")
+	if err := grpcServer.Serve(*listener); err != nil {
+		log.Fatal(err)
+	}
+
+	// Waiting for the cancelled import, if any, to record its status
+	runner.Wait()
+
+	log.Println("Shutdown successful")
+}
+
+// checkMigrations returns an error if migrations are pending, listing them.
+func checkMigrations(ctx context.Context, repository interface{ Migrator() (*migrations.Migrator, error) }) error {
+	migrator, err := repository.Migrator()
+	if err != nil {
+		return err
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%04d_%s", s.Version, s.Name))
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("the database schema isn't up-to-date, run \"importer migrate up\" to apply the pending "+
+			"migrations: %s", strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+// repoConfig converts the db settings to the repository configuration.
+func repoConfig(db config.DB) repo.Config {
+	return repo.Config{
+		DSN:             db.DSN,
+		User:            db.User,
+		Password:        db.Pass,
+		Host:            db.Host,
+		Port:            db.Port,
+		Database:        db.Name,
+		Schema:          db.Schema,
+		SSLMode:         db.SSLMode,
+		SSLRootCert:     db.SSLRootCert,
+		MaxOpenConns:    db.MaxOpenConns,
+		MaxIdleConns:    db.MaxIdleConns,
+		ConnMaxLifetime: db.ConnMaxLifetime,
+		ConnMaxIdleTime: db.ConnMaxIdleTime,
+		ConnectTimeout:  db.ConnectTimeout,
+	}
+}
+
+// thresholds converts the importer settings to the quality gates of the imported files.
+func thresholds(cfg config.Importer) processor.Thresholds {
+	return processor.Thresholds{
+		MaxInvalidPercent: cfg.MaxInvalidPercent,
+		MinAcceptedLines:  uint64(cfg.MinAcceptedLines),
+		MaxDeltaPercent:   cfg.MaxDeltaPercent,
+	}
+}
@@ -7,115 +7,289 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"sync"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/tiagocesar/geolocation/handler/grpc"
+	"github.com/tiagocesar/geolocation/internal/config"
 	"github.com/tiagocesar/geolocation/internal/processor"
 	"github.com/tiagocesar/geolocation/internal/repo"
+	"github.com/tiagocesar/geolocation/internal/repo/migrations"
+	"github.com/tiagocesar/geolocation/internal/watcher"
 )
 
-const (
-	totalRoutines = 10
+const usage = `usage:
+  importer [flags]                       migrates the database and imports the dump file
+  importer migrate up [flags]            applies all pending migrations
+  importer migrate down [steps] [flags]  reverts the last steps (a positive integer) migrations (default 1)
+  importer migrate status [flags]        lists migrations and whether they were applied
 
-	EnvDumpFile = "DUMP_FILE"
+With -dry-run, the dump file is only validated and a report is printed, without touching the database. The
+exit status is 2 if the lines of the file exceed the error thresholds (see -max-invalid-percent and
+-min-accepted-lines). Imports crossing the thresholds fail with the same exit status.`
 
-	EnvDbUser   = "DB_USER"
-	EnvDbPass   = "DB_PASS"
-	EnvDbHost   = "DB_HOST"
-	EnvDbPort   = "DB_PORT"
-	EnvDbSchema = "DB_SCHEMA"
-
-	EnvGrpcServerPort = "GRPC_SERVER_PORT"
-)
+// exitThresholdExceeded is the exit status of a dry run or import whose dump file exceeds the error thresholds.
+const exitThresholdExceeded = 2
 
 func main() {
-	var wg sync.WaitGroup
+	args := os.Args[1:]
+
+	// Imports and migrations are cancelled once a signal is received
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	if len(args) > 0 && args[0] == "migrate" {
+		err = runMigrate(ctx, args[1:])
+	} else {
+		err = runImport(ctx, args)
+	}
 
-	// Getting environment vars
-	envVars, err := getEnvVars()
 	if err != nil {
-		log.Fatal(err)
+		log.Println(err)
+		if errors.Is(err, processor.ErrThresholdExceeded) {
+			os.Exit(exitThresholdExceeded)
+		}
+		os.Exit(1)
 	}
 
-	// Configuring access to the repository and opening the SQL connection
-	repository, err := repo.NewRepository(envVars[EnvDbUser], envVars[EnvDbPass], envVars[EnvDbHost],
-		envVars[EnvDbPort], envVars[EnvDbSchema])
+	log.Println("Shutdown successful")
+}
+
+func runImport(ctx context.Context, args []string) error {
+	cfg, err := config.Load("importer", args)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	if err := cfg.Importer.Validate(); err != nil {
+		return err
 	}
 
-	wg.Add(1)
-	// Importing the dump file to the data store
-	go func() {
-		defer wg.Done()
+	if err := processor.ValidateDuplicatePolicy(cfg.Importer.DuplicatePolicy); err != nil {
+		return err
+	}
 
-		processor.NewFileProcessor(repository).ExecuteFileImport(context.Background(), envVars[EnvDumpFile], totalRoutines)
-	}()
+	// Dry runs don't need the database
+	if cfg.Importer.DryRun {
+		return dryRun(ctx, cfg.Importer)
+	}
+
+	if err := cfg.DB.Validate(); err != nil {
+		return err
+	}
 
-	// Starting the GRPC server with signals to gracefully stop it
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	log.Printf("Effective configuration:\n%s", cfg)
 
-	listener, grpcServer, err := grpc.NewGrpcServer(envVars[EnvGrpcServerPort], repository)
+	// Configuring access to the repository and opening the SQL connection
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	wg.Add(1)
-	go func() {
-		s := <-sigCh
-		log.Printf("Got signal %v, stopping server\n", s)
-		grpcServer.GracefulStop()
-		wg.Done()
-	}()
+	// The schema must be up-to-date before importing
+	migrator, err := repository.Migrator()
+	if err != nil {
+		return err
+	}
 
-	log.Println("Starting GRPC server")
-	err = grpcServer.Serve(*listener)
+	applied, err := migrator.Up(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	logMigrations("Applied", applied)
 
-	wg.Wait()
+	importFile := func(ctx context.Context, filename string) error {
+		fp := processor.NewFileProcessor(repository, processor.WithProgressInterval(cfg.Importer.ProgressInterval),
+			processor.WithBatchSize(cfg.Importer.BatchSize),
+			processor.WithThresholds(thresholds(cfg.Importer)),
+			processor.WithDuplicatePolicy(cfg.Importer.DuplicatePolicy), processor.WithDataset(cfg.Importer.Dataset))
 
-	log.Println("Shutdown successful")
+		_, err := fp.ExecuteFileImport(ctx, filename, cfg.Importer.TotalRoutines)
+
+		return err
+	}
+
+	switch cfg.Importer.Mode {
+	case config.ImporterModeWatch:
+		return watch(ctx, cfg.Importer.DumpFile, cfg.Importer.WatchInterval, func() error {
+			return importFile(ctx, cfg.Importer.DumpFile)
+		})
+	case config.ImporterModeDirectory:
+		w := watcher.New(cfg.Importer.WatchDir, repository, importFile,
+			watcher.WithPollInterval(cfg.Importer.WatchInterval), watcher.WithStableFor(cfg.Importer.StableFor))
+
+		return w.Run(ctx)
+	default:
+		return importFile(ctx, cfg.Importer.DumpFile)
+	}
 }
 
-// getEnvVars gets all environment variables necessary for this service to run.
-func getEnvVars() (map[string]string, error) {
-	result := make(map[string]string)
-	var ok bool
+// dryRun validates the dump file, printing a report of what importing it would do. It returns an error wrapping
+// processor.ErrThresholdExceeded if the lines of the file exceed the error thresholds. The row count delta isn't
+// checked, since the current dataset isn't known.
+func dryRun(ctx context.Context, cfg config.Importer) error {
+	report, err := processor.DryRun(ctx, cfg.DumpFile, processor.WithBatchSize(cfg.BatchSize),
+		processor.WithDuplicatePolicy(cfg.DuplicatePolicy))
+	if err != nil {
+		return err
+	}
 
-	// File name for the dump file
-	if result[EnvDumpFile], ok = os.LookupEnv(EnvDumpFile); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDumpFile))
+	if err := report.Print(os.Stdout); err != nil {
+		return err
 	}
 
-	// DB vars
-	if result[EnvDbUser], ok = os.LookupEnv(EnvDbUser); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbUser))
+	return thresholds(cfg).Check(report.Lines, 0)
+}
+
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usage)
 	}
 
-	if result[EnvDbPass], ok = os.LookupEnv(EnvDbPass); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbPass))
+	command, args := args[0], args[1:]
+
+	steps := 1
+	switch command {
+	case "up", "status":
+	case "down":
+		var err error
+		if steps, args, err = downSteps(args); err != nil {
+			return err
+		}
+	default:
+		return errors.New(usage)
 	}
 
-	if result[EnvDbHost], ok = os.LookupEnv(EnvDbHost); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbHost))
+	cfg, err := config.Load("importer migrate "+command, args)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.DB.Validate(); err != nil {
+		return err
+	}
+
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
+	if err != nil {
+		return err
+	}
+
+	migrator, err := repository.Migrator()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		logMigrations("Applied", applied)
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		logMigrations("Reverted", reverted)
+		return err
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = "applied at " + s.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%04d_%s: %s\n", s.Version, s.Name, appliedAt)
+		}
+
+		return nil
+	}
+}
+
+// downSteps parses the optional number of steps of migrate down, 1 if args start with the flags, returning the
+// remaining args. The number of steps must be a positive integer.
+func downSteps(args []string) (int, []string, error) {
+	if len(args) == 0 {
+		return 1, args, nil
 	}
 
-	if result[EnvDbPort], ok = os.LookupEnv(EnvDbPort); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbPort))
+	n, err := strconv.Atoi(args[0])
+	switch {
+	case err == nil && n > 0:
+		return n, args[1:], nil
+	case err != nil && strings.HasPrefix(args[0], "-"):
+		return 1, args, nil
+	default:
+		return 0, nil, fmt.Errorf("invalid number of steps %q, expected a positive integer\n%s", args[0], usage)
 	}
+}
+
+func logMigrations(action string, list []migrations.Migration) {
+	for _, m := range list {
+		log.Printf("%s migration %04d_%s\n", action, m.Version, m.Name)
+	}
+}
 
-	if result[EnvDbSchema], ok = os.LookupEnv(EnvDbSchema); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbSchema))
+// watch calls importFile for filename right away and then every time the file changes (based on its size and
+// modification time), checking for changes every interval until ctx is done.
+// Failed imports are logged and retried on the next change.
+func watch(ctx context.Context, filename string, interval time.Duration, importFile func() error) error {
+	var lastSize int64
+	var lastModTime time.Time
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		info, err := os.Stat(filename)
+		switch {
+		case err != nil:
+			log.Printf("Watching %s: %v\n", filename, err)
+		case info.Size() != lastSize || !info.ModTime().Equal(lastModTime):
+			lastSize, lastModTime = info.Size(), info.ModTime()
+
+			log.Printf("Importing %s\n", filename)
+			if err := importFile(); err != nil {
+				log.Println(err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
 	}
+}
 
-	// GRPC server
-	if result[EnvGrpcServerPort], ok = os.LookupEnv(EnvGrpcServerPort); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvGrpcServerPort))
+// repoConfig converts the db settings to the repository configuration.
+func repoConfig(db config.DB) repo.Config {
+	return repo.Config{
+		DSN:             db.DSN,
+		User:            db.User,
+		Password:        db.Pass,
+		Host:            db.Host,
+		Port:            db.Port,
+		Database:        db.Name,
+		Schema:          db.Schema,
+		SSLMode:         db.SSLMode,
+		SSLRootCert:     db.SSLRootCert,
+		MaxOpenConns:    db.MaxOpenConns,
+		MaxIdleConns:    db.MaxIdleConns,
+		ConnMaxLifetime: db.ConnMaxLifetime,
+		ConnMaxIdleTime: db.ConnMaxIdleTime,
+		ConnectTimeout:  db.ConnectTimeout,
 	}
+}
 
-	return result, nil
+// thresholds converts the importer settings to the quality gates of the imported files.
+func thresholds(cfg config.Importer) processor.Thresholds {
+	return processor.Thresholds{
+		MaxInvalidPercent: cfg.MaxInvalidPercent,
+		MinAcceptedLines:  uint64(cfg.MinAcceptedLines),
+		MaxDeltaPercent:   cfg.MaxDeltaPercent,
+	}
 }
@@ -0,0 +1,54 @@
+//go:build !integration
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_downSteps(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expectedSteps int
+		expectedArgs  []string
+		expectedErr   bool
+	}{
+		{name: "no args", args: []string{}, expectedSteps: 1, expectedArgs: []string{}},
+		{name: "steps", args: []string{"3"}, expectedSteps: 3, expectedArgs: []string{}},
+		{
+			name:          "steps and flags",
+			args:          []string{"2", "-db-user", "root"},
+			expectedSteps: 2,
+			expectedArgs:  []string{"-db-user", "root"},
+		},
+		{
+			name:          "flags only",
+			args:          []string{"-db-user", "root"},
+			expectedSteps: 1,
+			expectedArgs:  []string{"-db-user", "root"},
+		},
+		{name: "non-numeric steps", args: []string{"foo"}, expectedErr: true},
+		{name: "negative steps", args: []string{"-1"}, expectedErr: true},
+		{name: "zero steps", args: []string{"0"}, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			steps, args, err := downSteps(test.args)
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expectedSteps, steps)
+			require.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
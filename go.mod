@@ -3,12 +3,16 @@ module github.com/tiagocesar/geolocation
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/fsnotify/fsnotify v1.6.0
 	github.com/go-chi/chi/v5 v5.0.10
 	github.com/gocarina/gocsv v0.0.0-20230616125104-99d496ca653d
 	github.com/lib/pq v1.10.9
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/sync v0.3.0
 	google.golang.org/grpc v1.58.1
 	google.golang.org/protobuf v1.31.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -19,5 +23,4 @@ require (
 	golang.org/x/sys v0.12.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20230913181813-007df8e322eb // indirect
-	gopkg.in/yaml.v3 v3.0.0 // indirect
 )
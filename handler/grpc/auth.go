@@ -0,0 +1,68 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// adminMethods are the GRPC methods that require the admin token.
+var adminMethods = map[string]bool{
+	"/grpc_server.ImportService/StartImport":  true,
+	"/grpc_server.ImportService/UploadImport": true,
+	"/grpc_server.ImportService/CancelImport": true,
+}
+
+var (
+	errAdminDisabled = status.Error(codes.PermissionDenied, "admin methods are disabled")
+	errUnauthorized  = status.Error(codes.Unauthenticated, "missing or invalid admin token")
+)
+
+// authorizeAdmin checks that calls to admin methods carry token in an "authorization: Bearer <token>" header.
+// Every admin call is rejected if token is empty.
+func authorizeAdmin(ctx context.Context, token, method string) error {
+	if !adminMethods[method] {
+		return nil
+	}
+
+	if token == "" {
+		return errAdminDisabled
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		given := strings.TrimPrefix(value, "Bearer ")
+		if given != value && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			return nil
+		}
+	}
+
+	return errUnauthorized
+}
+
+func adminUnaryInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if err := authorizeAdmin(ctx, token, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func adminStreamInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorizeAdmin(ss.Context(), token, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,77 @@
+//go:build !integration
+
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func Test_authorizeAdmin(t *testing.T) {
+	const startImport = "/grpc_server.ImportService/StartImport"
+
+	tests := []struct {
+		name          string
+		token         string
+		method        string
+		header        string
+		expectedError error
+	}{
+		{
+			name:   "non admin methods don't need a token",
+			token:  "secret",
+			method: "/grpc_server.ImportService/ListImports",
+		},
+		{
+			name:   "valid token",
+			token:  "secret",
+			method: startImport,
+			header: "Bearer secret",
+		},
+		{
+			name:          "invalid token",
+			token:         "secret",
+			method:        startImport,
+			header:        "Bearer wrong",
+			expectedError: errUnauthorized,
+		},
+		{
+			name:          "token without the bearer scheme",
+			token:         "secret",
+			method:        startImport,
+			header:        "secret",
+			expectedError: errUnauthorized,
+		},
+		{
+			name:          "missing token",
+			token:         "secret",
+			method:        startImport,
+			expectedError: errUnauthorized,
+		},
+		{
+			name:          "admin methods are disabled without a token",
+			method:        startImport,
+			header:        "Bearer ",
+			expectedError: errAdminDisabled,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if test.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", test.header))
+			}
+
+			err := authorizeAdmin(ctx, test.token, test.method)
+
+			require.Equal(t, test.expectedError, err)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/netip"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/geo"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// defaultMaxTravelSpeed is the speed, in km/h, above which GetDistance flags travelling as impossible, unless set
+// through WithMaxTravelSpeed.
+const defaultMaxTravelSpeed = 1000
+
+// GetDistance gets the distance between the current locations of two IPs. IPs without a location are listed in the
+// response, instead of failing the call, so clients can tell which one is missing.
+func (h *grpcHandler) GetDistance(ctx context.Context, in *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+	maxSpeed := in.GetMaxSpeedKmh()
+	switch {
+	case maxSpeed == 0:
+		maxSpeed = h.maxTravelSpeed
+	case !(maxSpeed > 0):
+		return nil, status.Error(codes.InvalidArgument, "max_speed_kmh can't be negative")
+	}
+
+	var elapsed time.Duration
+	if in.GetElapsed() != nil {
+		if err := in.GetElapsed().CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid elapsed: %v", err)
+		}
+
+		if elapsed = in.GetElapsed().AsDuration(); elapsed <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "elapsed must be positive")
+		}
+	}
+
+	fromIP, err := requestIP("from_ip", in.GetFromIp())
+	if err != nil {
+		return nil, err
+	}
+	toIP, err := requestIP("to_ip", in.GetToIp())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.DistanceResponse{}
+
+	var locations []models.Geolocation
+	for _, ip := range []netip.Addr{fromIP, toIP} {
+		location, err := h.repository.GetLocationInfoByIP(ctx, ip, time.Time{})
+		switch {
+		case err == nil:
+			locations = append(locations, *location)
+		case errors.Is(err, sql.ErrNoRows):
+			response.MissingIps = append(response.MissingIps, ip.String())
+		default:
+			return nil, err
+		}
+	}
+
+	if len(response.MissingIps) > 0 {
+		return response, nil
+	}
+
+	from, to := locations[0], locations[1]
+	response.From = locationToProto(from, nil)
+	response.To = locationToProto(to, nil)
+	response.DistanceKm = geo.Distance(geo.Point{Latitude: from.Latitude, Longitude: from.Longitude},
+		geo.Point{Latitude: to.Latitude, Longitude: to.Longitude})
+
+	if elapsed > 0 {
+		// The IPs can be anywhere within the accuracy radius of their locations, so only the distance beyond them
+		// is certainly travelled
+		travelled := math.Max(0, response.DistanceKm-float64(from.AccuracyRadius+to.AccuracyRadius))
+
+		response.SpeedKmh = travelled / elapsed.Hours()
+		response.MaxSpeedKmh = maxSpeed
+		response.ImpossibleTravel = response.SpeedKmh > maxSpeed
+	}
+
+	return response, nil
+}
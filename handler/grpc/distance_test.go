@@ -0,0 +1,134 @@
+//go:build !integration
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// distanceRepository has the locations of Lisbon (1.1.1.1, within 10 km) and Porto (2.2.2.2, within 20 km), 274 km
+// apart.
+func distanceRepository() *mockRepository {
+	locations := map[string]models.Geolocation{
+		"1.1.1.1": {IpAddress: "1.1.1.1", City: "Lisbon", Latitude: 38.7223, Longitude: -9.1393, AccuracyRadius: 10},
+		"2.2.2.2": {IpAddress: "2.2.2.2", City: "Porto", Latitude: 41.1579, Longitude: -8.6291, AccuracyRadius: 20},
+	}
+
+	return &mockRepository{
+		GetLocationInfoByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
+			if ip.String() == "3.3.3.3" {
+				return nil, errors.New("connection refused")
+			}
+
+			location, ok := locations[ip.String()]
+			if !ok {
+				return nil, sql.ErrNoRows
+			}
+
+			return &location, nil
+		},
+	}
+}
+
+func Test_GetDistance(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          *pb.DistanceRequest
+		expected    *pb.DistanceResponse
+		expectedErr codes.Code
+	}{
+		{
+			name:     "distance only",
+			in:       &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "2.2.2.2"},
+			expected: &pb.DistanceResponse{DistanceKm: 274},
+		},
+		{
+			name: "possible travel",
+			in:   &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "2.2.2.2", Elapsed: durationpb.New(time.Hour)},
+			// The accuracy radius of both locations isn't counted as travelled
+			expected: &pb.DistanceResponse{DistanceKm: 274, SpeedKmh: 244, MaxSpeedKmh: 1000},
+		},
+		{
+			name: "impossible travel",
+			in:   &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "2.2.2.2", Elapsed: durationpb.New(10 * time.Minute)},
+			expected: &pb.DistanceResponse{DistanceKm: 274, SpeedKmh: 1466, MaxSpeedKmh: 1000,
+				ImpossibleTravel: true},
+		},
+		{
+			name: "max speed of the request",
+			in: &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "2.2.2.2", Elapsed: durationpb.New(time.Hour),
+				MaxSpeedKmh: 120},
+			expected: &pb.DistanceResponse{DistanceKm: 274, SpeedKmh: 244, MaxSpeedKmh: 120, ImpossibleTravel: true},
+		},
+		{
+			name:     "missing locations are listed",
+			in:       &pb.DistanceRequest{FromIp: "1.1.1.2", ToIp: "2.2.2.2", Elapsed: durationpb.New(time.Hour)},
+			expected: &pb.DistanceResponse{MissingIps: []string{"1.1.1.2"}},
+		},
+		{
+			name:     "IPs are looked up in canonical form",
+			in:       &pb.DistanceRequest{FromIp: "::ffff:1.1.1.1", ToIp: "2001:DB8::1"},
+			expected: &pb.DistanceResponse{MissingIps: []string{"2001:db8::1"}},
+		},
+		{
+			name:        "invalid IP",
+			in:          &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "fe80::1%eth0"},
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name:        "repository errors are returned",
+			in:          &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "3.3.3.3"},
+			expectedErr: codes.Unknown,
+		},
+		{
+			name:        "elapsed must be positive",
+			in:          &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "2.2.2.2", Elapsed: durationpb.New(0)},
+			expectedErr: codes.InvalidArgument,
+		},
+		{
+			name:        "negative max speed",
+			in:          &pb.DistanceRequest{FromIp: "1.1.1.1", ToIp: "2.2.2.2", MaxSpeedKmh: -1},
+			expectedErr: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grpcHandler{repository: distanceRepository(), maxTravelSpeed: defaultMaxTravelSpeed}
+
+			response, err := handler.GetDistance(context.Background(), test.in)
+			if test.expectedErr != codes.OK {
+				require.Equal(t, test.expectedErr, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+
+			require.Equal(t, test.expected.GetMissingIps(), response.GetMissingIps())
+			require.InDelta(t, test.expected.GetDistanceKm(), response.GetDistanceKm(), 1)
+			require.InDelta(t, test.expected.GetSpeedKmh(), response.GetSpeedKmh(), 1)
+			require.Equal(t, test.expected.GetMaxSpeedKmh(), response.GetMaxSpeedKmh())
+			require.Equal(t, test.expected.GetImpossibleTravel(), response.GetImpossibleTravel())
+
+			if len(test.expected.GetMissingIps()) == 0 {
+				require.Equal(t, test.in.GetFromIp(), response.GetFrom().GetIp())
+				require.Equal(t, test.in.GetToIp(), response.GetTo().GetIp())
+			}
+		})
+	}
+}
@@ -2,28 +2,97 @@ package grpc
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/geo"
 	"github.com/tiagocesar/geolocation/internal/models"
 )
 
+// ErrLocationNotFound is returned to GRPC clients when there's no location data for the requested IP.
+var ErrLocationNotFound = status.Error(codes.NotFound, "location not found")
+
+// ErrASNNotFound is returned to GRPC clients when no network of the ASN data contains the requested IP.
+var ErrASNNotFound = status.Error(codes.NotFound, "asn not found")
+
 type geolocationQuerier interface {
-	GetLocationInfoByIP(ctx context.Context, ipAddress string) (*models.Geolocation, error)
+	GetLocationInfoByIP(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error)
+	GetASNByIP(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error)
+	ListLocations(ctx context.Context, filter models.LocationFilter, after netip.Addr,
+		limit int) ([]models.Geolocation, error)
+	LocationsNear(ctx context.Context, center geo.Point, radiusKm float64,
+		limit int) ([]models.NearbyLocation, error)
+	LocationsWithin(ctx context.Context, box geo.Box, limit int) ([]models.Geolocation, error)
+	ActiveDatasetID(ctx context.Context, kind string) (int64, error)
+	LocationStats(ctx context.Context, topCities int) (*models.Stats, error)
+}
+
+// repository is everything the GRPC services query.
+type repository interface {
+	geolocationQuerier
+	importQuerier
 }
 
 type grpcHandler struct {
 	pb.UnimplementedGeolocationServer
 	repository geolocationQuerier
+	// maxTravelSpeed is the default speed, in km/h, above which GetDistance flags travelling as impossible
+	maxTravelSpeed float64
+	stats          statsCache
+}
+
+// ServerOption configures the GRPC server created via NewGrpcServer.
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	runner         importRunner
+	adminToken     string
+	maxTravelSpeed float64
 }
 
-func NewGrpcServer(port string, repository geolocationQuerier) (*net.Listener, *grpc.Server, error) {
+// WithMaxTravelSpeed sets the speed, in km/h, above which GetDistance flags travelling between two locations as
+// impossible, unless the request sets its own. The default is 1000 km/h, about the speed of an airliner.
+func WithMaxTravelSpeed(kmh float64) ServerOption {
+	return func(o *serverOptions) {
+		o.maxTravelSpeed = kmh
+	}
+}
+
+// WithImportRunner lets admins start and cancel imports on demand, authenticated by adminToken.
+// Without it, or with an empty token, those methods are rejected.
+func WithImportRunner(runner importRunner, adminToken string) ServerOption {
+	return func(o *serverOptions) {
+		o.runner = runner
+		o.adminToken = adminToken
+	}
+}
+
+func NewGrpcServer(port string, repository repository, opts ...ServerOption) (*net.Listener, *grpc.Server, error) {
+	o := serverOptions{maxTravelSpeed: defaultMaxTravelSpeed}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	handler := &grpcHandler{
+		repository:     repository,
+		maxTravelSpeed: o.maxTravelSpeed,
+	}
+	imports := &importHandler{
 		repository: repository,
+		runner:     o.runner,
+		now:        time.Now,
 	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
@@ -31,25 +100,135 @@ func NewGrpcServer(port string, repository geolocationQuerier) (*net.Listener, *
 		return nil, nil, fmt.Errorf("grpc server - failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	// Clients ping idle connections to detect broken ones; the default policy would reject pings this frequent
+	grpcServer := grpc.NewServer(
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		grpc.ChainUnaryInterceptor(adminUnaryInterceptor(o.adminToken)),
+		grpc.ChainStreamInterceptor(adminStreamInterceptor(o.adminToken)),
+	)
 	pb.RegisterGeolocationServer(grpcServer, handler)
+	pb.RegisterImportServiceServer(grpcServer, imports)
 	reflection.Register(grpcServer)
 
 	return &lis, grpcServer, nil
 }
 
 func (h *grpcHandler) GetLocationData(ctx context.Context, in *pb.LocationRequest) (*pb.LocationResponse, error) {
-	location, err := h.repository.GetLocationInfoByIP(ctx, in.GetIp())
+	// A zero time gets the current location
+	asOf, err := asOfTime(in.GetAsOf())
 	if err != nil {
 		return nil, err
 	}
 
+	ip, err := requestIP("ip", in.GetIp())
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := h.repository.GetLocationInfoByIP(ctx, ip, asOf)
+	switch {
+	case err == nil:
+		break
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrLocationNotFound
+	default:
+		return nil, err
+	}
+
+	return locationToProto(*location, in.GetInclude()), nil
+}
+
+// locationToProto converts a location, with the attributes named in include (see includedAttributes).
+func locationToProto(location models.Geolocation, include []string) *pb.LocationResponse {
 	return &pb.LocationResponse{
-		Ip:          location.IpAddress,
-		CountryCode: location.CountryCode,
-		Country:     location.Country,
-		City:        location.City,
-		Latitude:    location.Latitude,
-		Longitude:   location.Longitude,
+		Ip:             location.IpAddress,
+		CountryCode:    location.CountryCode,
+		Country:        location.Country,
+		City:           location.City,
+		Latitude:       location.Latitude,
+		Longitude:      location.Longitude,
+		MysteryValue:   location.MysteryValue,
+		Attributes:     includedAttributes(location.Attributes, include),
+		Region:         location.Region,
+		PostalCode:     location.PostalCode,
+		Timezone:       location.Timezone,
+		AccuracyRadius: int32(location.AccuracyRadius),
+		Continent:      location.Continent,
+	}
+}
+
+func (h *grpcHandler) GetASN(ctx context.Context, in *pb.ASNRequest) (*pb.ASNResponse, error) {
+	// A zero time gets the current network
+	asOf, err := asOfTime(in.GetAsOf())
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := requestIP("ip", in.GetIp())
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := h.repository.GetASNByIP(ctx, ip, asOf)
+	switch {
+	case err == nil:
+		break
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrASNNotFound
+	default:
+		return nil, err
+	}
+
+	return &pb.ASNResponse{
+		Network:        block.Network,
+		Asn:            block.ASN,
+		Organization:   block.Organization,
+		ConnectionType: block.ConnectionType,
 	}, nil
 }
+
+// requestIP parses the IP in the named field of a request into its canonical form (see models.ParseIP), so every
+// way of writing an IP finds the same location.
+func requestIP(field, ip string) (netip.Addr, error) {
+	addr, err := models.ParseIP(ip)
+	if err != nil {
+		return netip.Addr{}, status.Errorf(codes.InvalidArgument, "invalid %s %q", field, ip)
+	}
+
+	return addr, nil
+}
+
+// asOfTime converts the as_of field of a request, returning a zero time if it isn't set.
+func asOfTime(asOf *timestamppb.Timestamp) (time.Time, error) {
+	if asOf == nil {
+		return time.Time{}, nil
+	}
+
+	if err := asOf.CheckValid(); err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid as_of: %v", err)
+	}
+
+	return asOf.AsTime(), nil
+}
+
+// includedAttributes returns the attributes named in include, all of them if it has "*".
+func includedAttributes(attributes map[string]string, include []string) map[string]string {
+	var result map[string]string
+	for _, name := range include {
+		if name == "*" {
+			return attributes
+		}
+
+		if value, ok := attributes[name]; ok {
+			if result == nil {
+				result = make(map[string]string, len(include))
+			}
+			result[name] = value
+		}
+	}
+
+	return result
+}
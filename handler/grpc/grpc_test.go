@@ -6,20 +6,62 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/geo"
 	"github.com/tiagocesar/geolocation/internal/models"
 )
 
 type mockRepository struct {
-	GetLocationInfoByIPFn func(ctx context.Context, ipAddress string) (*models.Geolocation, error)
+	GetLocationInfoByIPFn func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error)
+	GetASNByIPFn          func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error)
+	ListLocationsFn       func(ctx context.Context, filter models.LocationFilter, after netip.Addr,
+		limit int) ([]models.Geolocation, error)
+	LocationsNearFn func(ctx context.Context, center geo.Point, radiusKm float64,
+		limit int) ([]models.NearbyLocation, error)
+	LocationsWithinFn func(ctx context.Context, box geo.Box, limit int) ([]models.Geolocation, error)
+	ActiveDatasetIDFn func(ctx context.Context, kind string) (int64, error)
+	LocationStatsFn   func(ctx context.Context, topCities int) (*models.Stats, error)
 }
 
-func (m *mockRepository) GetLocationInfoByIP(ctx context.Context, ipAddress string) (*models.Geolocation, error) {
-	return m.GetLocationInfoByIPFn(ctx, ipAddress)
+func (m *mockRepository) GetLocationInfoByIP(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
+	return m.GetLocationInfoByIPFn(ctx, ip, asOf)
+}
+
+func (m *mockRepository) ListLocations(ctx context.Context, filter models.LocationFilter, after netip.Addr,
+	limit int) ([]models.Geolocation, error) {
+
+	return m.ListLocationsFn(ctx, filter, after, limit)
+}
+
+func (m *mockRepository) LocationsNear(ctx context.Context, center geo.Point, radiusKm float64,
+	limit int) ([]models.NearbyLocation, error) {
+
+	return m.LocationsNearFn(ctx, center, radiusKm, limit)
+}
+
+func (m *mockRepository) LocationsWithin(ctx context.Context, box geo.Box, limit int) ([]models.Geolocation, error) {
+	return m.LocationsWithinFn(ctx, box, limit)
+}
+
+func (m *mockRepository) ActiveDatasetID(ctx context.Context, kind string) (int64, error) {
+	return m.ActiveDatasetIDFn(ctx, kind)
+}
+
+func (m *mockRepository) LocationStats(ctx context.Context, topCities int) (*models.Stats, error) {
+	return m.LocationStatsFn(ctx, topCities)
+}
+
+func (m *mockRepository) GetASNByIP(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error) {
+	return m.GetASNByIPFn(ctx, ip, asOf)
 }
 
 func Test_GetLocationData(t *testing.T) {
@@ -32,25 +74,25 @@ func Test_GetLocationData(t *testing.T) {
 		{
 			name: "success",
 			repository: &mockRepository{
-				GetLocationInfoByIPFn: func(s context.Context, ipAddress string) (*models.Geolocation, error) {
+				GetLocationInfoByIPFn: func(s context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
 					return mockGeolocation(), nil
 				},
 			},
 			expectedModel: mockGeolocation(),
 		},
 		{
-			name: "no row found - sql.ErrNoRows should not return an error from the GRPC server",
+			name: "no row found - sql.ErrNoRows should be returned as a NotFound status",
 			repository: &mockRepository{
-				GetLocationInfoByIPFn: func(ctx context.Context, ipAddress string) (*models.Geolocation, error) {
+				GetLocationInfoByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
 					return &models.Geolocation{}, sql.ErrNoRows
 				},
 			},
-			expectedError: nil,
+			expectedError: ErrLocationNotFound,
 		},
 		{
 			name: "an unexpected error happened",
 			repository: &mockRepository{
-				GetLocationInfoByIPFn: func(ctx context.Context, ipAddress string) (*models.Geolocation, error) {
+				GetLocationInfoByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
 					return nil, errors.New("random error")
 				},
 			},
@@ -59,6 +101,7 @@ func Test_GetLocationData(t *testing.T) {
 	}
 
 	for _, test := range tests {
+		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -66,7 +109,7 @@ func Test_GetLocationData(t *testing.T) {
 				repository: test.repository,
 			}
 
-			in := &pb.LocationRequest{Ip: ""}
+			in := &pb.LocationRequest{Ip: "192.168.0.1"}
 			result, err := handler.GetLocationData(context.Background(), in)
 
 			require.Equal(t, test.expectedError, err)
@@ -78,6 +121,217 @@ func Test_GetLocationData(t *testing.T) {
 				require.Equal(t, location.City, result.City)
 				require.Equal(t, location.Latitude, result.Latitude)
 				require.Equal(t, location.Longitude, result.Longitude)
+				require.Equal(t, location.MysteryValue, result.MysteryValue)
+				require.Equal(t, location.Region, result.Region)
+				require.Equal(t, location.PostalCode, result.PostalCode)
+				require.Equal(t, location.Timezone, result.Timezone)
+				require.Equal(t, int32(location.AccuracyRadius), result.AccuracyRadius)
+				require.Equal(t, location.Continent, result.Continent)
+			}
+		})
+	}
+}
+
+func Test_GetLocationData_asOf(t *testing.T) {
+	asOf := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		in            *pb.LocationRequest
+		expectedAsOf  time.Time
+		expectedError codes.Code
+	}{
+		{
+			name:         "no as_of gets the current location",
+			in:           &pb.LocationRequest{Ip: "192.168.0.1"},
+			expectedAsOf: time.Time{},
+		},
+		{
+			name:         "as_of is passed to the repository",
+			in:           &pb.LocationRequest{Ip: "192.168.0.1", AsOf: timestamppb.New(asOf)},
+			expectedAsOf: asOf,
+		},
+		{
+			name:          "invalid as_of returns invalid argument",
+			in:            &pb.LocationRequest{Ip: "192.168.0.1", AsOf: &timestamppb.Timestamp{Nanos: -1}},
+			expectedError: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotAsOf time.Time
+			handler := &grpcHandler{
+				repository: &mockRepository{
+					GetLocationInfoByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
+						gotAsOf = asOf
+						return mockGeolocation(), nil
+					},
+				},
+			}
+
+			_, err := handler.GetLocationData(context.Background(), test.in)
+
+			require.Equal(t, test.expectedError, status.Code(err))
+			require.True(t, test.expectedAsOf.Equal(gotAsOf))
+		})
+	}
+}
+
+func Test_GetLocationData_addressForms(t *testing.T) {
+	tests := []struct {
+		name          string
+		ip            string
+		expectedIP    netip.Addr
+		expectedError codes.Code
+	}{
+		{name: "IPv4", ip: "192.168.0.1", expectedIP: netip.MustParseAddr("192.168.0.1")},
+		{name: "IPv4-mapped IPv6", ip: "::ffff:192.168.0.1", expectedIP: netip.MustParseAddr("192.168.0.1")},
+		{name: "IPv6", ip: "2001:DB8:0::1", expectedIP: netip.MustParseAddr("2001:db8::1")},
+		{name: "IPv6 with a zone", ip: "fe80::1%eth0", expectedError: codes.InvalidArgument},
+		{name: "invalid IP", ip: "192.168.0", expectedError: codes.InvalidArgument},
+		{name: "missing IP", expectedError: codes.InvalidArgument},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grpcHandler{
+				repository: &mockRepository{
+					GetLocationInfoByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
+						require.Equal(t, test.expectedIP, ip)
+						return mockGeolocation(), nil
+					},
+				},
+			}
+
+			_, err := handler.GetLocationData(context.Background(), &pb.LocationRequest{Ip: test.ip})
+
+			require.Equal(t, test.expectedError, status.Code(err))
+		})
+	}
+}
+
+func Test_GetLocationData_include(t *testing.T) {
+	tests := []struct {
+		name               string
+		include            []string
+		expectedAttributes map[string]string
+	}{
+		{
+			name: "no attributes by default",
+		},
+		{
+			name:               "named attributes",
+			include:            []string{"asn", "missing"},
+			expectedAttributes: map[string]string{"asn": "AS123"},
+		},
+		{
+			name:               "all attributes",
+			include:            []string{"*"},
+			expectedAttributes: map[string]string{"asn": "AS123", "isp": "Unit Tests"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grpcHandler{
+				repository: &mockRepository{
+					GetLocationInfoByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.Geolocation, error) {
+						location := mockGeolocation()
+						location.Attributes = map[string]string{"asn": "AS123", "isp": "Unit Tests"}
+						return location, nil
+					},
+				},
+			}
+
+			in := &pb.LocationRequest{Ip: "192.168.0.1", Include: test.include}
+			result, err := handler.GetLocationData(context.Background(), in)
+
+			require.NoError(t, err)
+			require.Equal(t, test.expectedAttributes, result.GetAttributes())
+		})
+	}
+}
+
+func Test_GetASN(t *testing.T) {
+	block := &models.ASNBlock{Network: "192.168.0.0/16", ASN: 64496, Organization: "Unit Tests",
+		ConnectionType: models.ConnectionTypeHosting}
+
+	tests := []struct {
+		name          string
+		in            *pb.ASNRequest
+		getASNByIPFn  func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error)
+		expected      *pb.ASNResponse
+		expectedError codes.Code
+	}{
+		{
+			name: "success",
+			in:   &pb.ASNRequest{Ip: "192.168.0.1"},
+			getASNByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error) {
+				return block, nil
+			},
+			expected: &pb.ASNResponse{Network: "192.168.0.0/16", Asn: 64496, Organization: "Unit Tests",
+				ConnectionType: models.ConnectionTypeHosting},
+		},
+		{
+			name: "no network found returns not found",
+			in:   &pb.ASNRequest{Ip: "192.168.0.1"},
+			getASNByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error) {
+				return nil, sql.ErrNoRows
+			},
+			expectedError: codes.NotFound,
+		},
+		{
+			name: "IPv4-mapped IPv6 is looked up as IPv4",
+			in:   &pb.ASNRequest{Ip: "::ffff:192.168.0.1"},
+			getASNByIPFn: func(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error) {
+				if ip != netip.MustParseAddr("192.168.0.1") {
+					return nil, sql.ErrNoRows
+				}
+
+				return block, nil
+			},
+			expected: &pb.ASNResponse{Network: "192.168.0.0/16", Asn: 64496, Organization: "Unit Tests",
+				ConnectionType: models.ConnectionTypeHosting},
+		},
+		{
+			name:          "invalid as_of returns invalid argument",
+			in:            &pb.ASNRequest{Ip: "192.168.0.1", AsOf: &timestamppb.Timestamp{Nanos: -1}},
+			expectedError: codes.InvalidArgument,
+		},
+		{
+			name:          "invalid IP returns invalid argument",
+			in:            &pb.ASNRequest{Ip: "fe80::1%eth0"},
+			expectedError: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grpcHandler{
+				repository: &mockRepository{GetASNByIPFn: test.getASNByIPFn},
+			}
+
+			result, err := handler.GetASN(context.Background(), test.in)
+
+			require.Equal(t, test.expectedError, status.Code(err))
+			if test.expected != nil {
+				require.Equal(t, test.expected.GetNetwork(), result.GetNetwork())
+				require.Equal(t, test.expected.GetAsn(), result.GetAsn())
+				require.Equal(t, test.expected.GetOrganization(), result.GetOrganization())
+				require.Equal(t, test.expected.GetConnectionType(), result.GetConnectionType())
 			}
 		})
 	}
@@ -85,12 +339,17 @@ func Test_GetLocationData(t *testing.T) {
 
 func mockGeolocation() *models.Geolocation {
 	return &models.Geolocation{
-		IpAddress:    "192.168.0.1",
-		CountryCode:  "BR",
-		Country:      "Brazil",
-		City:         "Brasilia",
-		Latitude:     0,
-		Longitude:    0,
-		MysteryValue: "Home sweet home",
+		IpAddress:      "192.168.0.1",
+		CountryCode:    "BR",
+		Country:        "Brazil",
+		City:           "Brasilia",
+		Latitude:       0,
+		Longitude:      0,
+		MysteryValue:   "Home sweet home",
+		Region:         "Distrito Federal",
+		PostalCode:     "70000-000",
+		Timezone:       "America/Sao_Paulo",
+		AccuracyRadius: 50,
+		Continent:      "SA",
 	}
 }
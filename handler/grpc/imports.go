@@ -0,0 +1,203 @@
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+	"github.com/tiagocesar/geolocation/internal/processor"
+)
+
+const (
+	defaultImportsLimit = 20
+	maxImportsLimit     = 100
+)
+
+// ErrImportNotFound is returned to GRPC clients when there's no import with the requested ID.
+var ErrImportNotFound = status.Error(codes.NotFound, "import not found")
+
+type importQuerier interface {
+	ListImports(ctx context.Context, limit int) ([]models.Import, error)
+	GetImport(ctx context.Context, id int64) (*models.Import, error)
+}
+
+// importRunner runs imports on demand, see processor.Runner.
+type importRunner interface {
+	Start(dumpFile string) (int64, error)
+	Upload(src io.Reader) (int64, error)
+	Cancel(id int64) error
+}
+
+type importHandler struct {
+	pb.UnimplementedImportServiceServer
+	repository importQuerier
+	runner     importRunner
+	now        func() time.Time
+}
+
+func (h *importHandler) ListImports(ctx context.Context, in *pb.ListImportsRequest) (*pb.ListImportsResponse, error) {
+	limit := int(in.GetLimit())
+	switch {
+	case limit < 0:
+		return nil, status.Error(codes.InvalidArgument, "limit can't be negative")
+	case limit == 0:
+		limit = defaultImportsLimit
+	case limit > maxImportsLimit:
+		limit = maxImportsLimit
+	}
+
+	imports, err := h.repository.ListImports(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	now := h.now()
+	response := &pb.ListImportsResponse{Imports: make([]*pb.Import, 0, len(imports))}
+	for _, imp := range imports {
+		response.Imports = append(response.Imports, importToProto(imp, now))
+	}
+
+	return response, nil
+}
+
+func (h *importHandler) GetImport(ctx context.Context, in *pb.GetImportRequest) (*pb.Import, error) {
+	imp, err := h.repository.GetImport(ctx, in.GetId())
+	switch {
+	case err == nil:
+		break
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrImportNotFound
+	default:
+		return nil, err
+	}
+
+	return importToProto(*imp, h.now()), nil
+}
+
+func (h *importHandler) StartImport(_ context.Context, in *pb.StartImportRequest) (*pb.StartImportResponse, error) {
+	if h.runner == nil {
+		return nil, status.Error(codes.Unimplemented, "imports can't be started on this server")
+	}
+
+	if strings.TrimSpace(in.GetFile()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "file is required")
+	}
+
+	id, err := h.runner.Start(in.GetFile())
+	if err != nil {
+		return nil, runnerError(err)
+	}
+
+	return &pb.StartImportResponse{Id: id}, nil
+}
+
+func (h *importHandler) UploadImport(stream pb.ImportService_UploadImportServer) error {
+	if h.runner == nil {
+		return status.Error(codes.Unimplemented, "imports can't be started on this server")
+	}
+
+	id, err := h.runner.Upload(&uploadReader{stream: stream})
+	if err != nil {
+		return runnerError(err)
+	}
+
+	return stream.SendAndClose(&pb.StartImportResponse{Id: id})
+}
+
+func (h *importHandler) CancelImport(_ context.Context, in *pb.CancelImportRequest) (*pb.CancelImportResponse, error) {
+	if h.runner == nil {
+		return nil, status.Error(codes.Unimplemented, "imports can't be cancelled on this server")
+	}
+
+	if err := h.runner.Cancel(in.GetId()); err != nil {
+		return nil, runnerError(err)
+	}
+
+	return &pb.CancelImportResponse{}, nil
+}
+
+// runnerError maps the errors of the import runner to GRPC statuses.
+func runnerError(err error) error {
+	switch {
+	case errors.Is(err, processor.ErrImportRunning), errors.Is(err, processor.ErrImportNotRunning):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case status.Code(err) != codes.Unknown:
+		// Errors receiving an upload already have a status
+		return err
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// uploadReader reads the chunks of an upload as a single stream.
+type uploadReader struct {
+	stream pb.ImportService_UploadImportServer
+	buf    []byte
+}
+
+func (r *uploadReader) Read(b []byte) (int, error) {
+	for len(r.buf) == 0 {
+		chunk, err := r.stream.Recv()
+		if err != nil {
+			// io.EOF once the client is done sending
+			return 0, err
+		}
+
+		r.buf = chunk.GetChunk()
+	}
+
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+func importToProto(imp models.Import, now time.Time) *pb.Import {
+	result := &pb.Import{
+		Id:         imp.ID,
+		DatasetId:  imp.DatasetID,
+		SourceFile: imp.SourceFile,
+		FileSize:   imp.FileSize,
+		BytesRead:  imp.BytesRead,
+		Status:     imp.Status,
+		Lines: &pb.LineCounts{
+			Total:    imp.Lines.Total,
+			Accepted: imp.Lines.Accepted,
+			Invalid:  imp.Lines.Invalid,
+		},
+		Errors:     imp.Errors,
+		Error:      imp.Error,
+		StartedAt:  timestamppb.New(imp.StartedAt),
+		UpdatedAt:  timestamppb.New(imp.UpdatedAt),
+		Throughput: imp.Throughput(now),
+	}
+
+	if imp.FinishedAt != nil {
+		result.FinishedAt = timestamppb.New(*imp.FinishedAt)
+	}
+
+	if eta, ok := imp.ETA(now); ok {
+		result.Eta = durationpb.New(eta)
+	}
+
+	for _, c := range imp.Conflicts {
+		result.Conflicts = append(result.Conflicts, &pb.ImportConflict{
+			IpAddress: c.IpAddress,
+			Lines:     c.Lines,
+			KeptLine:  c.KeptLine,
+		})
+	}
+	result.TotalConflicts = imp.TotalConflicts
+
+	return result
+}
@@ -0,0 +1,245 @@
+//go:build !integration
+
+package grpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+	"github.com/tiagocesar/geolocation/internal/processor"
+)
+
+type mockImportRepository struct {
+	ListImportsFn func(ctx context.Context, limit int) ([]models.Import, error)
+	GetImportFn   func(ctx context.Context, id int64) (*models.Import, error)
+}
+
+func (m *mockImportRepository) ListImports(ctx context.Context, limit int) ([]models.Import, error) {
+	return m.ListImportsFn(ctx, limit)
+}
+
+func (m *mockImportRepository) GetImport(ctx context.Context, id int64) (*models.Import, error) {
+	return m.GetImportFn(ctx, id)
+}
+
+func Test_ListImports(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int32
+		expectedLimit int
+		expectedCode  codes.Code
+	}{
+		{name: "default limit", limit: 0, expectedLimit: defaultImportsLimit},
+		{name: "custom limit", limit: 5, expectedLimit: 5},
+		{name: "limit is capped", limit: 1000, expectedLimit: maxImportsLimit},
+		{name: "negative limit", limit: -1, expectedCode: codes.InvalidArgument},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotLimit int
+			handler := &importHandler{
+				repository: &mockImportRepository{
+					ListImportsFn: func(ctx context.Context, limit int) ([]models.Import, error) {
+						gotLimit = limit
+						return []models.Import{mockImport()}, nil
+					},
+				},
+				now: mockNow,
+			}
+
+			result, err := handler.ListImports(context.Background(), &pb.ListImportsRequest{Limit: test.limit})
+
+			require.Equal(t, test.expectedCode, status.Code(err))
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, test.expectedLimit, gotLimit)
+			require.Len(t, result.Imports, 1)
+		})
+	}
+}
+
+func Test_GetImport(t *testing.T) {
+	tests := []struct {
+		name          string
+		getImportFn   func(ctx context.Context, id int64) (*models.Import, error)
+		expectedError error
+	}{
+		{
+			name: "success",
+			getImportFn: func(ctx context.Context, id int64) (*models.Import, error) {
+				imp := mockImport()
+				return &imp, nil
+			},
+		},
+		{
+			name: "no row found - sql.ErrNoRows should be returned as a NotFound status",
+			getImportFn: func(ctx context.Context, id int64) (*models.Import, error) {
+				return nil, sql.ErrNoRows
+			},
+			expectedError: ErrImportNotFound,
+		},
+		{
+			name: "an unexpected error happened",
+			getImportFn: func(ctx context.Context, id int64) (*models.Import, error) {
+				return nil, errors.New("random error")
+			},
+			expectedError: errors.New("random error"),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &importHandler{
+				repository: &mockImportRepository{GetImportFn: test.getImportFn},
+				now:        mockNow,
+			}
+
+			result, err := handler.GetImport(context.Background(), &pb.GetImportRequest{Id: 1})
+
+			require.Equal(t, test.expectedError, err)
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, int64(1), result.Id)
+			require.Equal(t, models.ImportStatusRunning, result.Status)
+			require.Equal(t, uint64(50), result.Lines.Total)
+			require.Equal(t, uint64(3), result.Errors[models.ErrorReasonInvalidIP])
+			require.Len(t, result.Conflicts, 1)
+			require.Equal(t, []uint64{3, 8}, result.Conflicts[0].Lines)
+			require.Equal(t, uint64(3), result.Conflicts[0].KeptLine)
+			require.Equal(t, uint64(1), result.TotalConflicts)
+			// 50 lines in 10 seconds
+			require.Equal(t, 5.0, result.Throughput)
+			// A quarter of the file was read in 10 seconds
+			require.Equal(t, 30*time.Second, result.Eta.AsDuration())
+			require.Nil(t, result.FinishedAt)
+		})
+	}
+}
+
+type mockRunner struct {
+	StartFn  func(dumpFile string) (int64, error)
+	UploadFn func(src io.Reader) (int64, error)
+	CancelFn func(id int64) error
+}
+
+func (m *mockRunner) Start(dumpFile string) (int64, error) {
+	return m.StartFn(dumpFile)
+}
+
+func (m *mockRunner) Upload(src io.Reader) (int64, error) {
+	return m.UploadFn(src)
+}
+
+func (m *mockRunner) Cancel(id int64) error {
+	return m.CancelFn(id)
+}
+
+func Test_StartImport(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         string
+		runner       importRunner
+		expectedID   int64
+		expectedCode codes.Code
+	}{
+		{
+			name: "success",
+			file: "dump.csv",
+			runner: &mockRunner{StartFn: func(dumpFile string) (int64, error) {
+				return 3, nil
+			}},
+			expectedID: 3,
+		},
+		{
+			name:         "missing file",
+			runner:       &mockRunner{},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "an import is already running",
+			file: "dump.csv",
+			runner: &mockRunner{StartFn: func(dumpFile string) (int64, error) {
+				return 0, processor.ErrImportRunning
+			}},
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name:         "server without a runner",
+			file:         "dump.csv",
+			expectedCode: codes.Unimplemented,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &importHandler{runner: test.runner}
+
+			result, err := handler.StartImport(context.Background(), &pb.StartImportRequest{File: test.file})
+
+			require.Equal(t, test.expectedCode, status.Code(err))
+			require.Equal(t, test.expectedID, result.GetId())
+		})
+	}
+}
+
+func Test_CancelImport(t *testing.T) {
+	handler := &importHandler{runner: &mockRunner{CancelFn: func(id int64) error {
+		if id != 1 {
+			return processor.ErrImportNotRunning
+		}
+		return nil
+	}}}
+
+	_, err := handler.CancelImport(context.Background(), &pb.CancelImportRequest{Id: 1})
+	require.NoError(t, err)
+
+	_, err = handler.CancelImport(context.Background(), &pb.CancelImportRequest{Id: 2})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func mockNow() time.Time {
+	return time.Date(2022, 1, 1, 0, 0, 10, 0, time.UTC)
+}
+
+func mockImport() models.Import {
+	return models.Import{
+		ID:         1,
+		DatasetID:  2,
+		SourceFile: "dump.csv",
+		FileSize:   400,
+		BytesRead:  100,
+		Status:     models.ImportStatusRunning,
+		Lines:      models.LineCounts{Total: 50, Accepted: 47, Invalid: 3},
+		Errors:     map[string]uint64{models.ErrorReasonInvalidIP: 3},
+		StartedAt:  time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:  time.Date(2022, 1, 1, 0, 0, 9, 0, time.UTC),
+		Conflicts: []models.Conflict{
+			{IpAddress: "1.1.1.1", Lines: []uint64{3, 8}, KeptLine: 3},
+		},
+		TotalConflicts: 1,
+	}
+}
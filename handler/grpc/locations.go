@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// listPageSize is how many locations are sent per message by ListLocations.
+const listPageSize = 500
+
+var errInvalidCursor = status.Error(codes.InvalidArgument, "invalid cursor")
+
+// ListLocations streams the locations of a country in pages, each queried from the repository right before it's
+// sent, so large listings don't have to fit in memory. Every page carries the cursor resuming the listing after it.
+func (h *grpcHandler) ListLocations(in *pb.ListLocationsRequest, stream pb.Geolocation_ListLocationsServer) error {
+	filter := models.LocationFilter{
+		CountryCode: strings.TrimSpace(in.GetCountryCode()),
+		City:        strings.TrimSpace(in.GetCity()),
+	}
+	if filter.CountryCode == "" {
+		return status.Error(codes.InvalidArgument, "country_code is required")
+	}
+
+	limit := int(in.GetLimit())
+	if limit < 0 {
+		return status.Error(codes.InvalidArgument, "limit can't be negative")
+	}
+
+	after, err := decodeCursor(in.GetCursor())
+	if err != nil {
+		return err
+	}
+
+	for sent := 0; ; {
+		size := listPageSize
+		if limit > 0 && limit-sent < size {
+			size = limit - sent
+		}
+
+		// Querying one more location than sent tells if there's a next page
+		locations, err := h.repository.ListLocations(stream.Context(), filter, after, size+1)
+		if err != nil {
+			return err
+		}
+
+		more := len(locations) > size
+		if more {
+			locations = locations[:size]
+		}
+
+		page := &pb.ListLocationsResponse{Locations: make([]*pb.LocationResponse, 0, len(locations))}
+		for _, location := range locations {
+			page.Locations = append(page.Locations, locationToProto(location, nil))
+		}
+
+		if more {
+			last := locations[len(locations)-1].IpAddress
+			if after, err = models.ParseIP(last); err != nil {
+				return fmt.Errorf("listing locations after %q: %w", last, err)
+			}
+			page.NextCursor = encodeCursor(after)
+		}
+
+		if err := stream.Send(page); err != nil {
+			return err
+		}
+
+		sent += len(locations)
+		if !more || sent == limit {
+			return nil
+		}
+	}
+}
+
+// encodeCursor returns the cursor of a listing resuming after ip. Cursors are opaque to clients.
+func encodeCursor(ip netip.Addr) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(ip.String()))
+}
+
+// decodeCursor returns the IP a listing resumes after, the zero netip.Addr if cursor is empty.
+func decodeCursor(cursor string) (netip.Addr, error) {
+	if cursor == "" {
+		return netip.Addr{}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return netip.Addr{}, errInvalidCursor
+	}
+
+	ip, err := models.ParseIP(string(decoded))
+	if err != nil {
+		return netip.Addr{}, errInvalidCursor
+	}
+
+	return ip, nil
+}
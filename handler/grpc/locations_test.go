@@ -0,0 +1,182 @@
+//go:build !integration
+
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+type mockListLocationsStream struct {
+	grpc.ServerStream
+	pages []*pb.ListLocationsResponse
+}
+
+func (m *mockListLocationsStream) Context() context.Context {
+	return context.Background()
+}
+
+func (m *mockListLocationsStream) Send(page *pb.ListLocationsResponse) error {
+	m.pages = append(m.pages, page)
+	return nil
+}
+
+// listLocationsRepository lists n locations of BR, with IPs 10.0.x.y in order.
+func listLocationsRepository(n int) *mockRepository {
+	var all []models.Geolocation
+	for i := 0; i < n; i++ {
+		all = append(all, models.Geolocation{IpAddress: fmt.Sprintf("10.0.%d.%d", i/256, i%256), CountryCode: "BR"})
+	}
+
+	return &mockRepository{
+		ListLocationsFn: func(ctx context.Context, filter models.LocationFilter, after netip.Addr,
+			limit int) ([]models.Geolocation, error) {
+
+			start := 0
+			if after.IsValid() {
+				for start < len(all) && all[start].IpAddress != after.String() {
+					start++
+				}
+				start++
+			}
+
+			end := start + limit
+			if end > len(all) {
+				end = len(all)
+			}
+
+			return all[start:end], nil
+		},
+	}
+}
+
+func Test_ListLocations(t *testing.T) {
+	tests := []struct {
+		name              string
+		total             int
+		limit             int32
+		expectedPageSizes []int
+		expectedMore      bool
+	}{
+		{
+			name:              "a single page",
+			total:             10,
+			expectedPageSizes: []int{10},
+		},
+		{
+			name:              "several pages",
+			total:             listPageSize*2 + 1,
+			expectedPageSizes: []int{listPageSize, listPageSize, 1},
+		},
+		{
+			name:              "no locations",
+			expectedPageSizes: []int{0},
+		},
+		{
+			name:              "limited to fewer than the locations",
+			total:             listPageSize * 2,
+			limit:             listPageSize + 1,
+			expectedPageSizes: []int{listPageSize, 1},
+			expectedMore:      true,
+		},
+		{
+			name:              "limited to the exact number of locations",
+			total:             10,
+			limit:             10,
+			expectedPageSizes: []int{10},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grpcHandler{repository: listLocationsRepository(test.total)}
+			stream := &mockListLocationsStream{}
+
+			err := handler.ListLocations(&pb.ListLocationsRequest{CountryCode: "BR", Limit: test.limit}, stream)
+			require.NoError(t, err)
+
+			var sizes []int
+			for _, page := range stream.pages {
+				sizes = append(sizes, len(page.GetLocations()))
+			}
+			require.Equal(t, test.expectedPageSizes, sizes)
+
+			// Every page but the last one resumes the listing, and so does the last one if the limit was reached
+			last := stream.pages[len(stream.pages)-1]
+			for _, page := range stream.pages[:len(stream.pages)-1] {
+				require.NotEmpty(t, page.GetNextCursor())
+			}
+			require.Equal(t, test.expectedMore, last.GetNextCursor() != "")
+
+			if !test.expectedMore {
+				return
+			}
+
+			// Resuming from the cursor lists the remaining locations
+			next := &mockListLocationsStream{}
+			err = handler.ListLocations(&pb.ListLocationsRequest{CountryCode: "BR", Cursor: last.GetNextCursor()}, next)
+			require.NoError(t, err)
+
+			var resumed int
+			for _, page := range next.pages {
+				resumed += len(page.GetLocations())
+			}
+			require.Equal(t, test.total-int(test.limit), resumed)
+		})
+	}
+}
+
+func Test_ListLocations_invalidRequest(t *testing.T) {
+	cursor := func(ip string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(ip))
+	}
+
+	tests := []struct {
+		name string
+		in   *pb.ListLocationsRequest
+	}{
+		{
+			name: "missing country code",
+			in:   &pb.ListLocationsRequest{City: "Brasilia"},
+		},
+		{
+			name: "negative limit",
+			in:   &pb.ListLocationsRequest{CountryCode: "BR", Limit: -1},
+		},
+		{
+			name: "invalid cursor",
+			in:   &pb.ListLocationsRequest{CountryCode: "BR", Cursor: cursor("not an IP")},
+		},
+		{
+			name: "cursor of an IP with a zone",
+			in:   &pb.ListLocationsRequest{CountryCode: "BR", Cursor: cursor("fe80::1%eth0")},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grpcHandler{repository: listLocationsRepository(1)}
+
+			err := handler.ListLocations(test.in, &mockListLocationsStream{})
+
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/geo"
+)
+
+const (
+	// defaultNearbyLimit is how many locations FindNearbyLocations finds when the request doesn't set a limit
+	defaultNearbyLimit = 100
+	// maxNearbyLimit is the highest limit of FindNearbyLocations
+	maxNearbyLimit = 1000
+	// maxRadiusKm is the largest radius of the circles searched by FindNearbyLocations
+	maxRadiusKm = 1000
+)
+
+func (h *grpcHandler) FindNearbyLocations(ctx context.Context,
+	in *pb.NearbyLocationsRequest) (*pb.NearbyLocationsResponse, error) {
+
+	limit := int(in.GetLimit())
+	switch {
+	case limit == 0:
+		limit = defaultNearbyLimit
+	case limit < 0 || limit > maxNearbyLimit:
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxNearbyLimit)
+	}
+
+	response := &pb.NearbyLocationsResponse{}
+
+	switch area := in.GetArea().(type) {
+	case *pb.NearbyLocationsRequest_Circle:
+		center := geo.Point{Latitude: area.Circle.GetLatitude(), Longitude: area.Circle.GetLongitude()}
+		radiusKm := area.Circle.GetRadiusKm()
+
+		switch {
+		case !center.Valid():
+			return nil, status.Error(codes.InvalidArgument, "invalid center coordinates")
+		case !(radiusKm > 0 && radiusKm <= maxRadiusKm):
+			return nil, status.Errorf(codes.InvalidArgument, "radius_km must be above 0 and at most %d", maxRadiusKm)
+		}
+
+		locations, err := h.repository.LocationsNear(ctx, center, radiusKm, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, location := range locations {
+			response.Locations = append(response.Locations, &pb.NearbyLocation{
+				Location:   locationToProto(location.Geolocation, nil),
+				DistanceKm: location.DistanceKm,
+			})
+		}
+	case *pb.NearbyLocationsRequest_Box:
+		box := geo.Box{
+			MinLatitude:  area.Box.GetMinLatitude(),
+			MaxLatitude:  area.Box.GetMaxLatitude(),
+			MinLongitude: area.Box.GetMinLongitude(),
+			MaxLongitude: area.Box.GetMaxLongitude(),
+		}
+		if !box.Valid() {
+			return nil, status.Error(codes.InvalidArgument, "invalid bounding box")
+		}
+
+		locations, err := h.repository.LocationsWithin(ctx, box, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, location := range locations {
+			response.Locations = append(response.Locations, &pb.NearbyLocation{
+				Location: locationToProto(location, nil),
+			})
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "either a circle or a bounding box is required")
+	}
+
+	return response, nil
+}
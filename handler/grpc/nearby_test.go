@@ -0,0 +1,134 @@
+//go:build !integration
+
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/geo"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+func Test_FindNearbyLocations(t *testing.T) {
+	lisbon := models.Geolocation{IpAddress: "1.1.1.1", CountryCode: "PT", City: "Lisbon", Latitude: 38.7,
+		Longitude: -9.1}
+
+	repository := &mockRepository{
+		LocationsNearFn: func(ctx context.Context, center geo.Point, radiusKm float64,
+			limit int) ([]models.NearbyLocation, error) {
+
+			require.Equal(t, geo.Point{Latitude: 38.8, Longitude: -9.2}, center)
+			require.Equal(t, 50.0, radiusKm)
+			require.Equal(t, defaultNearbyLimit, limit)
+
+			return []models.NearbyLocation{{Geolocation: lisbon, DistanceKm: 14}}, nil
+		},
+		LocationsWithinFn: func(ctx context.Context, box geo.Box, limit int) ([]models.Geolocation, error) {
+			require.Equal(t, geo.Box{MinLatitude: 38, MaxLatitude: 39, MinLongitude: -10, MaxLongitude: -9}, box)
+			require.Equal(t, 10, limit)
+
+			return []models.Geolocation{lisbon}, nil
+		},
+	}
+	handler := &grpcHandler{repository: repository}
+
+	tests := []struct {
+		name     string
+		in       *pb.NearbyLocationsRequest
+		expected []*pb.NearbyLocation
+	}{
+		{
+			name: "circle",
+			in: &pb.NearbyLocationsRequest{Area: &pb.NearbyLocationsRequest_Circle{
+				Circle: &pb.Circle{Latitude: 38.8, Longitude: -9.2, RadiusKm: 50},
+			}},
+			expected: []*pb.NearbyLocation{{Location: locationToProto(lisbon, nil), DistanceKm: 14}},
+		},
+		{
+			name: "bounding box",
+			in: &pb.NearbyLocationsRequest{
+				Area: &pb.NearbyLocationsRequest_Box{
+					Box: &pb.BoundingBox{MinLatitude: 38, MinLongitude: -10, MaxLatitude: 39, MaxLongitude: -9},
+				},
+				Limit: 10,
+			},
+			expected: []*pb.NearbyLocation{{Location: locationToProto(lisbon, nil)}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			response, err := handler.FindNearbyLocations(context.Background(), test.in)
+			require.NoError(t, err)
+
+			require.Len(t, response.GetLocations(), len(test.expected))
+			for i, expected := range test.expected {
+				require.Equal(t, expected.GetDistanceKm(), response.GetLocations()[i].GetDistanceKm())
+				require.Equal(t, expected.GetLocation().GetIp(), response.GetLocations()[i].GetLocation().GetIp())
+				require.Equal(t, expected.GetLocation().GetCity(), response.GetLocations()[i].GetLocation().GetCity())
+			}
+		})
+	}
+}
+
+func Test_FindNearbyLocations_invalidRequest(t *testing.T) {
+	circle := func(latitude, longitude, radiusKm float64) *pb.NearbyLocationsRequest_Circle {
+		return &pb.NearbyLocationsRequest_Circle{
+			Circle: &pb.Circle{Latitude: latitude, Longitude: longitude, RadiusKm: radiusKm},
+		}
+	}
+
+	tests := []struct {
+		name string
+		in   *pb.NearbyLocationsRequest
+	}{
+		{
+			name: "missing area",
+			in:   &pb.NearbyLocationsRequest{},
+		},
+		{
+			name: "invalid center",
+			in:   &pb.NearbyLocationsRequest{Area: circle(91, 0, 10)},
+		},
+		{
+			name: "missing radius",
+			in:   &pb.NearbyLocationsRequest{Area: circle(0, 0, 0)},
+		},
+		{
+			name: "radius too large",
+			in:   &pb.NearbyLocationsRequest{Area: circle(0, 0, maxRadiusKm+1)},
+		},
+		{
+			name: "limit too large",
+			in:   &pb.NearbyLocationsRequest{Area: circle(0, 0, 10), Limit: maxNearbyLimit + 1},
+		},
+		{
+			name: "inverted bounding box",
+			in: &pb.NearbyLocationsRequest{Area: &pb.NearbyLocationsRequest_Box{
+				Box: &pb.BoundingBox{MinLatitude: 10, MaxLatitude: -10, MinLongitude: 0, MaxLongitude: 10},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &grpcHandler{repository: &mockRepository{}}
+
+			_, err := handler.FindNearbyLocations(context.Background(), test.in)
+
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
@@ -23,6 +23,18 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type GeolocationClient interface {
 	GetLocationData(ctx context.Context, in *LocationRequest, opts ...grpc.CallOption) (*LocationResponse, error)
+	// GetASN gets the most specific network containing an IP, and the autonomous system it belongs to
+	GetASN(ctx context.Context, in *ASNRequest, opts ...grpc.CallOption) (*ASNResponse, error)
+	// ListLocations streams the current locations of a country, optionally of a single city, sorted by IP, in pages
+	ListLocations(ctx context.Context, in *ListLocationsRequest, opts ...grpc.CallOption) (Geolocation_ListLocationsClient, error)
+	// FindNearbyLocations finds the current locations within a radius of a point, the nearest first, or inside a
+	// bounding box, sorted by IP
+	FindNearbyLocations(ctx context.Context, in *NearbyLocationsRequest, opts ...grpc.CallOption) (*NearbyLocationsResponse, error)
+	// GetDistance gets the great-circle distance between the current locations of two IPs and, given the time elapsed
+	// between them, tells if travelling that distance is possible
+	GetDistance(ctx context.Context, in *DistanceRequest, opts ...grpc.CallOption) (*DistanceResponse, error)
+	// GetStats summarizes the current locations
+	GetStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
 }
 
 type geolocationClient struct {
@@ -42,11 +54,91 @@ func (c *geolocationClient) GetLocationData(ctx context.Context, in *LocationReq
 	return out, nil
 }
 
+func (c *geolocationClient) GetASN(ctx context.Context, in *ASNRequest, opts ...grpc.CallOption) (*ASNResponse, error) {
+	out := new(ASNResponse)
+	err := c.cc.Invoke(ctx, "/grpc_server.Geolocation/GetASN", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geolocationClient) ListLocations(ctx context.Context, in *ListLocationsRequest, opts ...grpc.CallOption) (Geolocation_ListLocationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Geolocation_ServiceDesc.Streams[0], "/grpc_server.Geolocation/ListLocations", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &geolocationListLocationsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Geolocation_ListLocationsClient interface {
+	Recv() (*ListLocationsResponse, error)
+	grpc.ClientStream
+}
+
+type geolocationListLocationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *geolocationListLocationsClient) Recv() (*ListLocationsResponse, error) {
+	m := new(ListLocationsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *geolocationClient) FindNearbyLocations(ctx context.Context, in *NearbyLocationsRequest, opts ...grpc.CallOption) (*NearbyLocationsResponse, error) {
+	out := new(NearbyLocationsResponse)
+	err := c.cc.Invoke(ctx, "/grpc_server.Geolocation/FindNearbyLocations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geolocationClient) GetDistance(ctx context.Context, in *DistanceRequest, opts ...grpc.CallOption) (*DistanceResponse, error) {
+	out := new(DistanceResponse)
+	err := c.cc.Invoke(ctx, "/grpc_server.Geolocation/GetDistance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geolocationClient) GetStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/grpc_server.Geolocation/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GeolocationServer is the server API for Geolocation service.
 // All implementations must embed UnimplementedGeolocationServer
 // for forward compatibility
 type GeolocationServer interface {
 	GetLocationData(context.Context, *LocationRequest) (*LocationResponse, error)
+	// GetASN gets the most specific network containing an IP, and the autonomous system it belongs to
+	GetASN(context.Context, *ASNRequest) (*ASNResponse, error)
+	// ListLocations streams the current locations of a country, optionally of a single city, sorted by IP, in pages
+	ListLocations(*ListLocationsRequest, Geolocation_ListLocationsServer) error
+	// FindNearbyLocations finds the current locations within a radius of a point, the nearest first, or inside a
+	// bounding box, sorted by IP
+	FindNearbyLocations(context.Context, *NearbyLocationsRequest) (*NearbyLocationsResponse, error)
+	// GetDistance gets the great-circle distance between the current locations of two IPs and, given the time elapsed
+	// between them, tells if travelling that distance is possible
+	GetDistance(context.Context, *DistanceRequest) (*DistanceResponse, error)
+	// GetStats summarizes the current locations
+	GetStats(context.Context, *StatsRequest) (*StatsResponse, error)
 	mustEmbedUnimplementedGeolocationServer()
 }
 
@@ -57,6 +149,21 @@ type UnimplementedGeolocationServer struct {
 func (UnimplementedGeolocationServer) GetLocationData(context.Context, *LocationRequest) (*LocationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetLocationData not implemented")
 }
+func (UnimplementedGeolocationServer) GetASN(context.Context, *ASNRequest) (*ASNResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetASN not implemented")
+}
+func (UnimplementedGeolocationServer) ListLocations(*ListLocationsRequest, Geolocation_ListLocationsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListLocations not implemented")
+}
+func (UnimplementedGeolocationServer) FindNearbyLocations(context.Context, *NearbyLocationsRequest) (*NearbyLocationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindNearbyLocations not implemented")
+}
+func (UnimplementedGeolocationServer) GetDistance(context.Context, *DistanceRequest) (*DistanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDistance not implemented")
+}
+func (UnimplementedGeolocationServer) GetStats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
 func (UnimplementedGeolocationServer) mustEmbedUnimplementedGeolocationServer() {}
 
 // UnsafeGeolocationServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +195,99 @@ func _Geolocation_GetLocationData_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Geolocation_GetASN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ASNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServer).GetASN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.Geolocation/GetASN",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServer).GetASN(ctx, req.(*ASNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Geolocation_ListLocations_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListLocationsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GeolocationServer).ListLocations(m, &geolocationListLocationsServer{stream})
+}
+
+type Geolocation_ListLocationsServer interface {
+	Send(*ListLocationsResponse) error
+	grpc.ServerStream
+}
+
+type geolocationListLocationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *geolocationListLocationsServer) Send(m *ListLocationsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Geolocation_FindNearbyLocations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NearbyLocationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServer).FindNearbyLocations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.Geolocation/FindNearbyLocations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServer).FindNearbyLocations(ctx, req.(*NearbyLocationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Geolocation_GetDistance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DistanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServer).GetDistance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.Geolocation/GetDistance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServer).GetDistance(ctx, req.(*DistanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Geolocation_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeolocationServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.Geolocation/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeolocationServer).GetStats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Geolocation_ServiceDesc is the grpc.ServiceDesc for Geolocation service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +299,298 @@ var Geolocation_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetLocationData",
 			Handler:    _Geolocation_GetLocationData_Handler,
 		},
+		{
+			MethodName: "GetASN",
+			Handler:    _Geolocation_GetASN_Handler,
+		},
+		{
+			MethodName: "FindNearbyLocations",
+			Handler:    _Geolocation_FindNearbyLocations_Handler,
+		},
+		{
+			MethodName: "GetDistance",
+			Handler:    _Geolocation_GetDistance_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Geolocation_GetStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListLocations",
+			Handler:       _Geolocation_ListLocations_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "handler/grpc/schema/schema.proto",
+}
+
+// ImportServiceClient is the client API for ImportService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImportServiceClient interface {
+	ListImports(ctx context.Context, in *ListImportsRequest, opts ...grpc.CallOption) (*ListImportsResponse, error)
+	GetImport(ctx context.Context, in *GetImportRequest, opts ...grpc.CallOption) (*Import, error)
+	// StartImport imports a file available to the server in the background
+	StartImport(ctx context.Context, in *StartImportRequest, opts ...grpc.CallOption) (*StartImportResponse, error)
+	// UploadImport imports the uploaded file in the background, once it's fully received
+	UploadImport(ctx context.Context, opts ...grpc.CallOption) (ImportService_UploadImportClient, error)
+	CancelImport(ctx context.Context, in *CancelImportRequest, opts ...grpc.CallOption) (*CancelImportResponse, error)
+}
+
+type importServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImportServiceClient(cc grpc.ClientConnInterface) ImportServiceClient {
+	return &importServiceClient{cc}
+}
+
+func (c *importServiceClient) ListImports(ctx context.Context, in *ListImportsRequest, opts ...grpc.CallOption) (*ListImportsResponse, error) {
+	out := new(ListImportsResponse)
+	err := c.cc.Invoke(ctx, "/grpc_server.ImportService/ListImports", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *importServiceClient) GetImport(ctx context.Context, in *GetImportRequest, opts ...grpc.CallOption) (*Import, error) {
+	out := new(Import)
+	err := c.cc.Invoke(ctx, "/grpc_server.ImportService/GetImport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *importServiceClient) StartImport(ctx context.Context, in *StartImportRequest, opts ...grpc.CallOption) (*StartImportResponse, error) {
+	out := new(StartImportResponse)
+	err := c.cc.Invoke(ctx, "/grpc_server.ImportService/StartImport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *importServiceClient) UploadImport(ctx context.Context, opts ...grpc.CallOption) (ImportService_UploadImportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImportService_ServiceDesc.Streams[0], "/grpc_server.ImportService/UploadImport", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &importServiceUploadImportClient{stream}
+	return x, nil
+}
+
+type ImportService_UploadImportClient interface {
+	Send(*UploadImportRequest) error
+	CloseAndRecv() (*StartImportResponse, error)
+	grpc.ClientStream
+}
+
+type importServiceUploadImportClient struct {
+	grpc.ClientStream
+}
+
+func (x *importServiceUploadImportClient) Send(m *UploadImportRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *importServiceUploadImportClient) CloseAndRecv() (*StartImportResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(StartImportResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *importServiceClient) CancelImport(ctx context.Context, in *CancelImportRequest, opts ...grpc.CallOption) (*CancelImportResponse, error) {
+	out := new(CancelImportResponse)
+	err := c.cc.Invoke(ctx, "/grpc_server.ImportService/CancelImport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImportServiceServer is the server API for ImportService service.
+// All implementations must embed UnimplementedImportServiceServer
+// for forward compatibility
+type ImportServiceServer interface {
+	ListImports(context.Context, *ListImportsRequest) (*ListImportsResponse, error)
+	GetImport(context.Context, *GetImportRequest) (*Import, error)
+	// StartImport imports a file available to the server in the background
+	StartImport(context.Context, *StartImportRequest) (*StartImportResponse, error)
+	// UploadImport imports the uploaded file in the background, once it's fully received
+	UploadImport(ImportService_UploadImportServer) error
+	CancelImport(context.Context, *CancelImportRequest) (*CancelImportResponse, error)
+	mustEmbedUnimplementedImportServiceServer()
+}
+
+// UnimplementedImportServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedImportServiceServer struct {
+}
+
+func (UnimplementedImportServiceServer) ListImports(context.Context, *ListImportsRequest) (*ListImportsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListImports not implemented")
+}
+func (UnimplementedImportServiceServer) GetImport(context.Context, *GetImportRequest) (*Import, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetImport not implemented")
+}
+func (UnimplementedImportServiceServer) StartImport(context.Context, *StartImportRequest) (*StartImportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartImport not implemented")
+}
+func (UnimplementedImportServiceServer) UploadImport(ImportService_UploadImportServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadImport not implemented")
+}
+func (UnimplementedImportServiceServer) CancelImport(context.Context, *CancelImportRequest) (*CancelImportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelImport not implemented")
+}
+func (UnimplementedImportServiceServer) mustEmbedUnimplementedImportServiceServer() {}
+
+// UnsafeImportServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImportServiceServer will
+// result in compilation errors.
+type UnsafeImportServiceServer interface {
+	mustEmbedUnimplementedImportServiceServer()
+}
+
+func RegisterImportServiceServer(s grpc.ServiceRegistrar, srv ImportServiceServer) {
+	s.RegisterService(&ImportService_ServiceDesc, srv)
+}
+
+func _ImportService_ListImports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListImportsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImportServiceServer).ListImports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.ImportService/ListImports",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImportServiceServer).ListImports(ctx, req.(*ListImportsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImportService_GetImport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImportServiceServer).GetImport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.ImportService/GetImport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImportServiceServer).GetImport(ctx, req.(*GetImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImportService_StartImport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImportServiceServer).StartImport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.ImportService/StartImport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImportServiceServer).StartImport(ctx, req.(*StartImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImportService_UploadImport_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ImportServiceServer).UploadImport(&importServiceUploadImportServer{stream})
+}
+
+type ImportService_UploadImportServer interface {
+	SendAndClose(*StartImportResponse) error
+	Recv() (*UploadImportRequest, error)
+	grpc.ServerStream
+}
+
+type importServiceUploadImportServer struct {
+	grpc.ServerStream
+}
+
+func (x *importServiceUploadImportServer) SendAndClose(m *StartImportResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *importServiceUploadImportServer) Recv() (*UploadImportRequest, error) {
+	m := new(UploadImportRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ImportService_CancelImport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImportServiceServer).CancelImport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc_server.ImportService/CancelImport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImportServiceServer).CancelImport(ctx, req.(*CancelImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ImportService_ServiceDesc is the grpc.ServiceDesc for ImportService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ImportService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpc_server.ImportService",
+	HandlerType: (*ImportServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListImports",
+			Handler:    _ImportService_ListImports_Handler,
+		},
+		{
+			MethodName: "GetImport",
+			Handler:    _ImportService_GetImport_Handler,
+		},
+		{
+			MethodName: "StartImport",
+			Handler:    _ImportService_StartImport_Handler,
+		},
+		{
+			MethodName: "CancelImport",
+			Handler:    _ImportService_CancelImport_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadImport",
+			Handler:       _ImportService_UploadImport_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "handler/grpc/schema/schema.proto",
 }
@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// statsTopCities is how many cities per country GetStats counts.
+const statsTopCities = 10
+
+// statsCache keeps the stats of the active location dataset, so they're only computed again once another dataset
+// is activated, by whichever process imported it.
+type statsCache struct {
+	mu    sync.Mutex
+	stats *pb.StatsResponse
+}
+
+// GetStats summarizes the current locations, computing the stats only once per dataset.
+func (h *grpcHandler) GetStats(ctx context.Context, _ *pb.StatsRequest) (*pb.StatsResponse, error) {
+	datasetID, err := h.repository.ActiveDatasetID(ctx, models.DatasetKindLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Concurrent calls wait for the stats being computed instead of computing them again
+	h.stats.mu.Lock()
+	defer h.stats.mu.Unlock()
+
+	if h.stats.stats != nil && h.stats.stats.GetDatasetId() == datasetID {
+		return h.stats.stats, nil
+	}
+
+	stats, err := h.repository.LocationStats(ctx, statsTopCities)
+	if err != nil {
+		return nil, err
+	}
+
+	h.stats.stats = statsToProto(stats)
+
+	return h.stats.stats, nil
+}
+
+func statsToProto(stats *models.Stats) *pb.StatsResponse {
+	response := &pb.StatsResponse{
+		DatasetId:      stats.DatasetID,
+		SourceFile:     stats.SourceFile,
+		TotalLocations: stats.TotalLocations,
+		Ipv4Locations:  stats.IPv4Locations,
+		Ipv6Locations:  stats.IPv6Locations,
+	}
+
+	if stats.ActivatedAt != nil {
+		response.ActivatedAt = timestamppb.New(*stats.ActivatedAt)
+	}
+
+	for _, country := range stats.Countries {
+		c := &pb.CountryStats{
+			CountryCode: country.CountryCode,
+			Country:     country.Country,
+			Locations:   country.Locations,
+		}
+		for _, city := range country.TopCities {
+			c.TopCities = append(c.TopCities, &pb.CityStats{City: city.City, Locations: city.Locations})
+		}
+
+		response.Countries = append(response.Countries, c)
+	}
+
+	for _, prefix := range stats.Prefixes {
+		response.Prefixes = append(response.Prefixes,
+			&pb.PrefixStats{Prefix: prefix.Prefix, Locations: prefix.Locations})
+	}
+
+	return response
+}
@@ -0,0 +1,91 @@
+//go:build !integration
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+func Test_GetStats(t *testing.T) {
+	activatedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	datasetID := int64(1)
+	computed := 0
+	handler := &grpcHandler{repository: &mockRepository{
+		ActiveDatasetIDFn: func(ctx context.Context, kind string) (int64, error) {
+			require.Equal(t, models.DatasetKindLocation, kind)
+			return datasetID, nil
+		},
+		LocationStatsFn: func(ctx context.Context, topCities int) (*models.Stats, error) {
+			require.Equal(t, statsTopCities, topCities)
+			computed++
+
+			return &models.Stats{
+				DatasetID:      datasetID,
+				SourceFile:     "data_dump.csv",
+				ActivatedAt:    &activatedAt,
+				TotalLocations: 3,
+				IPv4Locations:  2,
+				IPv6Locations:  1,
+				Countries: []models.CountryStats{{
+					CountryCode: "BR",
+					Country:     "Brazil",
+					Locations:   3,
+					TopCities:   []models.CityStats{{City: "Brasilia", Locations: 2}, {City: "Recife", Locations: 1}},
+				}},
+				Prefixes: []models.PrefixStats{{Prefix: "1.0.0.0/8", Locations: 2}},
+			}, nil
+		},
+	}}
+
+	stats, err := handler.GetStats(context.Background(), &pb.StatsRequest{})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1), stats.GetDatasetId())
+	require.Equal(t, "data_dump.csv", stats.GetSourceFile())
+	require.Equal(t, activatedAt, stats.GetActivatedAt().AsTime())
+	require.Equal(t, uint64(3), stats.GetTotalLocations())
+	require.Equal(t, uint64(2), stats.GetIpv4Locations())
+	require.Equal(t, uint64(1), stats.GetIpv6Locations())
+	require.Len(t, stats.GetCountries(), 1)
+	require.Equal(t, "BR", stats.GetCountries()[0].GetCountryCode())
+	require.Len(t, stats.GetCountries()[0].GetTopCities(), 2)
+	require.Equal(t, "1.0.0.0/8", stats.GetPrefixes()[0].GetPrefix())
+
+	// The stats are cached while the dataset stays active
+	_, err = handler.GetStats(context.Background(), &pb.StatsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, 1, computed)
+
+	// and computed again once another one is activated
+	datasetID = 2
+	stats, err = handler.GetStats(context.Background(), &pb.StatsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), stats.GetDatasetId())
+	require.Equal(t, 2, computed)
+}
+
+func Test_GetStats_error(t *testing.T) {
+	handler := &grpcHandler{repository: &mockRepository{
+		ActiveDatasetIDFn: func(ctx context.Context, kind string) (int64, error) {
+			return 1, nil
+		},
+		LocationStatsFn: func(ctx context.Context, topCities int) (*models.Stats, error) {
+			return nil, errors.New("connection refused")
+		},
+	}}
+
+	_, err := handler.GetStats(context.Background(), &pb.StatsRequest{})
+	require.Error(t, err)
+
+	// Failures aren't cached
+	require.Nil(t, handler.stats.stats)
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/status"
+
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
+)
+
+type importAdmin interface {
+	StartImport(ctx context.Context, file string) (int64, error)
+	UploadImport(ctx context.Context, src io.Reader) (int64, error)
+	CancelImport(ctx context.Context, id int64) error
+}
+
+type startImportRequest struct {
+	// File is the path of the file to import, on the GRPC server
+	File string `json:"file"`
+}
+
+type startImportResponse struct {
+	ID int64 `json:"id"`
+}
+
+// adminContext authenticates the calls made to the GRPC server on behalf of req with its bearer token, which is
+// checked by the GRPC server. It returns false, after replying, if req has no bearer token.
+func adminContext(ctx context.Context, w http.ResponseWriter, req *http.Request) (context.Context, bool) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == req.Header.Get("Authorization") || strings.TrimSpace(token) == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte("missing bearer token"))
+		return nil, false
+	}
+
+	return grpc_client.AdminContext(ctx, token), true
+}
+
+// startImport starts an import of either a file available to the GRPC server, given as JSON
+// ({"file": "/path/to/dump.csv"}), or the CSV file sent as the request body.
+func (h *httpServer) startImport(w http.ResponseWriter, req *http.Request) {
+	var (
+		id  int64
+		err error
+	)
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		ctx, cancel := h.requestContext(req)
+		defer cancel()
+
+		ctx, ok := adminContext(ctx, w, req)
+		if !ok {
+			return
+		}
+
+		var body startImportRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || strings.TrimSpace(body.File) == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`invalid body, expected {"file": "<path>"}`))
+			return
+		}
+
+		id, err = h.admin.StartImport(ctx, body.File)
+	} else {
+		// The request timeout isn't applied to uploads, which take as long as the file takes to be sent (the admin
+		// server doesn't limit reading requests either, see serve)
+		ctx, ok := adminContext(req.Context(), w, req)
+		if !ok {
+			return
+		}
+
+		id, err = h.admin.UploadImport(ctx, req.Body)
+	}
+
+	if err != nil {
+		writeGrpcError(w, err)
+		return
+	}
+
+	j, _ := json.Marshal(startImportResponse{ID: id})
+
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = fmt.Fprint(w, string(j))
+}
+
+// cancelImport cancels a running import. The import stops in the background.
+func (h *httpServer) cancelImport(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	ctx, ok := adminContext(ctx, w, req)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid import ID"))
+		return
+	}
+
+	if err := h.admin.CancelImport(ctx, id); err != nil {
+		writeGrpcError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeGrpcError replies with the status code matching err, and the message of the GRPC status.
+func writeGrpcError(w http.ResponseWriter, err error) {
+	w.WriteHeader(grpcErrorStatus(err))
+	_, _ = w.Write([]byte(status.Convert(err).Message()))
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type mockImportAdmin struct {
+	StartImportFn  func(ctx context.Context, file string) (int64, error)
+	UploadImportFn func(ctx context.Context, src io.Reader) (int64, error)
+	CancelImportFn func(ctx context.Context, id int64) error
+}
+
+func (m *mockImportAdmin) StartImport(ctx context.Context, file string) (int64, error) {
+	return m.StartImportFn(ctx, file)
+}
+
+func (m *mockImportAdmin) UploadImport(ctx context.Context, src io.Reader) (int64, error) {
+	return m.UploadImportFn(ctx, src)
+}
+
+func (m *mockImportAdmin) CancelImport(ctx context.Context, id int64) error {
+	return m.CancelImportFn(ctx, id)
+}
+
+// tokenOf gets the token sent to the GRPC server with the calls made with ctx.
+func tokenOf(ctx context.Context) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func TestHandler_startImport(t *testing.T) {
+	admin := &mockImportAdmin{
+		StartImportFn: func(ctx context.Context, file string) (int64, error) {
+			if tokenOf(ctx) != "Bearer secret" {
+				return 0, status.Error(codes.Unauthenticated, "missing or invalid admin token")
+			}
+			if file == "running.csv" {
+				return 0, status.Error(codes.FailedPrecondition, "an import is already running")
+			}
+			return 1, nil
+		},
+		UploadImportFn: func(ctx context.Context, src io.Reader) (int64, error) {
+			contents, _ := io.ReadAll(src)
+			if string(contents) != "ip_address\n" {
+				return 0, status.Error(codes.InvalidArgument, "unexpected upload")
+			}
+			return 2, nil
+		},
+	}
+
+	tests := []struct {
+		name             string
+		contentType      string
+		authorization    string
+		body             string
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name:             "file path",
+			contentType:      "application/json",
+			authorization:    "Bearer secret",
+			body:             `{"file": "dump.csv"}`,
+			expectedRespCode: http.StatusAccepted,
+			expectedRespBody: `{"id":1}`,
+		},
+		{
+			name:             "upload",
+			contentType:      "text/csv",
+			authorization:    "Bearer secret",
+			body:             "ip_address\n",
+			expectedRespCode: http.StatusAccepted,
+			expectedRespBody: `{"id":2}`,
+		},
+		{
+			name:             "missing token should return unauthorized",
+			contentType:      "application/json",
+			body:             `{"file": "dump.csv"}`,
+			expectedRespCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "invalid token should return unauthorized",
+			contentType:      "application/json",
+			authorization:    "Bearer wrong",
+			body:             `{"file": "dump.csv"}`,
+			expectedRespCode: http.StatusUnauthorized,
+		},
+		{
+			name:             "missing file should return bad request",
+			contentType:      "application/json",
+			authorization:    "Bearer secret",
+			body:             `{}`,
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "import already running should return conflict",
+			contentType:      "application/json",
+			authorization:    "Bearer secret",
+			body:             `{"file": "running.csv"}`,
+			expectedRespCode: http.StatusConflict,
+			expectedRespBody: "an import is already running",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/admin/imports", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			h := httpServer{admin: admin}
+			h.startImport(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedRespBody != "" {
+				require.Equal(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_serve_slowUpload(t *testing.T) {
+	h := &httpServer{
+		admin: &mockImportAdmin{
+			UploadImportFn: func(ctx context.Context, src io.Reader) (int64, error) {
+				contents, err := io.ReadAll(src)
+				if err != nil || string(contents) != strings.Repeat("ip_address\n", 5) {
+					return 0, status.Error(codes.InvalidArgument, "unexpected upload")
+				}
+				return 1, nil
+			},
+		},
+		timeouts: Timeouts{Read: 50 * time.Millisecond, Write: 50 * time.Millisecond, Shutdown: time.Second},
+	}
+
+	apiListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	adminListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.serve(ctx, apiListener, adminListener)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-errCh)
+	}()
+
+	// The upload takes longer than the read and write timeouts of the API
+	body, writer := io.Pipe()
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(30 * time.Millisecond)
+			_, _ = writer.Write([]byte("ip_address\n"))
+		}
+		_ = writer.Close()
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+adminListener.Addr().String()+"/admin/imports", body)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "text/csv")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	// The admin endpoints aren't served with the API
+	req, err = http.NewRequest(http.MethodPost, "http://"+apiListener.Addr().String()+"/admin/imports", nil)
+	require.NoError(t, err)
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
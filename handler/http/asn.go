@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+type asnFinder interface {
+	GetASN(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error)
+}
+
+func (h *httpServer) getASN(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	// as_of (RFC 3339) resolves the IP against the ASN data that was current at that time
+	var asOf time.Time
+	if s := req.URL.Query().Get("as_of"); s != "" {
+		var err error
+		asOf, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid as_of timestamp, expected RFC 3339"))
+			return
+		}
+	}
+
+	result, err := h.asn.GetASN(ctx, chi.URLParam(req, "ip"), asOf)
+	switch {
+	case err == nil:
+		break
+	case errors.Is(err, grpc_client.ErrInvalidIP):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid IP address"))
+		return
+	default:
+		w.WriteHeader(grpcErrorStatus(err))
+		return
+	}
+
+	j, _ := json.Marshal(toASNBlock(result))
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, string(j))
+}
+
+func toASNBlock(response *pb.ASNResponse) models.ASNBlock {
+	return models.ASNBlock{
+		Network:        response.GetNetwork(),
+		ASN:            response.GetAsn(),
+		Organization:   response.GetOrganization(),
+		ConnectionType: response.GetConnectionType(),
+	}
+}
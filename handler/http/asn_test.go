@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+)
+
+type mockASNFinder struct {
+	GetASNFn func(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error)
+}
+
+func (m *mockASNFinder) GetASN(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error) {
+	return m.GetASNFn(ctx, ip, asOf)
+}
+
+func TestHandler_getASN(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		getASNFn         func(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error)
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name: "success",
+			getASNFn: func(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error) {
+				return &pb.ASNResponse{Network: "192.168.0.0/16", Asn: 64496, Organization: "Unit Tests",
+					ConnectionType: "hosting"}, nil
+			},
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"network":"192.168.0.0/16","asn":64496,"organization":"Unit Tests",` +
+				`"connection_type":"hosting"}`,
+		},
+		{
+			name:  "as_of is passed to the GRPC client",
+			query: "?as_of=2023-01-02T03:04:05Z",
+			getASNFn: func(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error) {
+				if !asOf.Equal(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)) {
+					return nil, context.Canceled
+				}
+				return &pb.ASNResponse{}, nil
+			},
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:             "invalid as_of should return bad request",
+			query:            "?as_of=yesterday",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name: "invalid IP should return bad request",
+			getASNFn: func(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error) {
+				return nil, grpc_client.ErrInvalidIP
+			},
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name: "IP not found should return not found",
+			getASNFn: func(ctx context.Context, ip string, asOf time.Time) (*pb.ASNResponse, error) {
+				return nil, grpc_client.ErrASNNotFound
+			},
+			expectedRespCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := httpServer{asn: &mockASNFinder{GetASNFn: test.getASNFn}}
+
+			rr := httptest.NewRecorder()
+
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("ip", "192.168.0.1")
+
+			req := httptest.NewRequest(http.MethodGet, "/asn/192.168.0.1"+test.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			h.getASN(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedRespBody != "" {
+				require.Equal(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
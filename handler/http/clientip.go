@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+var errUnknownClientIP = errors.New("unknown client IP")
+
+// clientIP works out the IP of the client that made req. It's the address the request came from unless that's one
+// of the trusted proxies, in which case the addresses the proxies forwarded the request for (the Forwarded header,
+// or X-Forwarded-For without it) are walked from the nearest one, until one that isn't a trusted proxy is found.
+//
+// Forwarding headers are easily spoofed, so they're only believed as far as they were added by trusted proxies.
+func clientIP(req *http.Request, trustedProxies []netip.Prefix) (netip.Addr, error) {
+	remote, err := netip.ParseAddrPort(req.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}, errUnknownClientIP
+	}
+
+	// The zone of a link-local address only makes sense on this host
+	ip := remote.Addr().Unmap().WithZone("")
+
+	hops := forwardedFor(req.Header)
+	for i := len(hops) - 1; i >= 0 && trusted(ip, trustedProxies); i-- {
+		hop, err := models.ParseIP(hops[i])
+		if err != nil {
+			// The proxy was asked to hide the client (e.g. for=unknown), or the header is garbled: the proxy is the
+			// nearest the request can be traced to
+			break
+		}
+
+		ip = hop
+	}
+
+	return ip, nil
+}
+
+// trusted reports if ip is one of the trusted proxies.
+func trusted(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedFor returns the addresses a request was forwarded for, the client first and the nearest proxy last, from
+// the Forwarded header (RFC 7239) if it's set, or else from X-Forwarded-For. Ports and brackets are left out.
+func forwardedFor(header http.Header) []string {
+	var hops []string
+
+	if forwarded := header.Values("Forwarded"); len(forwarded) > 0 {
+		for _, element := range strings.Split(strings.Join(forwarded, ","), ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					hop = forwardedNode(strings.Trim(value, `"`))
+				}
+			}
+
+			// An element without for= still is a hop, of a client that can't be traced
+			hops = append(hops, hop)
+		}
+
+		return hops
+	}
+
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	return hops
+}
+
+// forwardedNode returns the IP of a node of the Forwarded header: an IPv4 address or a bracketed IPv6 address, both
+// with an optional port.
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.Index(node, "]"); end > 0 {
+			return node[1:end]
+		}
+
+		return node
+	}
+
+	host, _, _ := strings.Cut(node, ":")
+	return host
+}
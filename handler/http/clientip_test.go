@@ -0,0 +1,134 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_clientIP(t *testing.T) {
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		header         http.Header
+		trustedProxies []netip.Prefix
+		expected       string
+		expectedErr    error
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:51234",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "direct IPv6 client",
+			remoteAddr: "[2001:DB8::7]:51234",
+			expected:   "2001:db8::7",
+		},
+		{
+			name:       "direct IPv4-mapped IPv6 client",
+			remoteAddr: "[::ffff:203.0.113.7]:51234",
+			expected:   "203.0.113.7",
+		},
+		{
+			name:       "forwarding headers are ignored without trusted proxies",
+			remoteAddr: "10.0.0.1:51234",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.7"}},
+			expected:   "10.0.0.1",
+		},
+		{
+			name:           "forwarding headers of untrusted clients are ignored",
+			remoteAddr:     "198.51.100.1:51234",
+			header:         http.Header{"X-Forwarded-For": {"203.0.113.7"}},
+			trustedProxies: trustedProxies,
+			expected:       "198.51.100.1",
+		},
+		{
+			name:           "X-Forwarded-For of a trusted proxy",
+			remoteAddr:     "10.0.0.1:51234",
+			header:         http.Header{"X-Forwarded-For": {"203.0.113.7"}},
+			trustedProxies: trustedProxies,
+			expected:       "203.0.113.7",
+		},
+		{
+			name:           "X-Forwarded-For through a chain of trusted proxies",
+			remoteAddr:     "10.0.0.1:51234",
+			header:         http.Header{"X-Forwarded-For": {"203.0.113.7, 10.0.0.3", "10.0.0.2"}},
+			trustedProxies: trustedProxies,
+			expected:       "203.0.113.7",
+		},
+		{
+			name:           "X-Forwarded-For spoofed by the client is ignored",
+			remoteAddr:     "10.0.0.1:51234",
+			header:         http.Header{"X-Forwarded-For": {"192.0.2.1, 203.0.113.7"}},
+			trustedProxies: trustedProxies,
+			expected:       "203.0.113.7",
+		},
+		{
+			name:           "X-Forwarded-For with only trusted proxies",
+			remoteAddr:     "10.0.0.1:51234",
+			header:         http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			trustedProxies: trustedProxies,
+			expected:       "10.0.0.3",
+		},
+		{
+			name:           "invalid X-Forwarded-For stops at the proxy",
+			remoteAddr:     "10.0.0.1:51234",
+			header:         http.Header{"X-Forwarded-For": {"203.0.113.7, garbage"}},
+			trustedProxies: trustedProxies,
+			expected:       "10.0.0.1",
+		},
+		{
+			name:       "Forwarded of a trusted proxy",
+			remoteAddr: "[fd00::1]:51234",
+			header: http.Header{
+				"Forwarded":       {`for="[2001:db8::7]:4711";proto=https, for=10.0.0.2:8080`},
+				"X-Forwarded-For": {"192.0.2.1"},
+			},
+			trustedProxies: trustedProxies,
+			expected:       "2001:db8::7",
+		},
+		{
+			name:           "Forwarded with the parameters in any order and case",
+			remoteAddr:     "10.0.0.1:51234",
+			header:         http.Header{"Forwarded": {"proto=http;For=203.0.113.7;by=10.0.0.1"}},
+			trustedProxies: trustedProxies,
+			expected:       "203.0.113.7",
+		},
+		{
+			name:           "Forwarded hiding the client stops at the proxy",
+			remoteAddr:     "10.0.0.1:51234",
+			header:         http.Header{"Forwarded": {"for=unknown, for=10.0.0.2"}},
+			trustedProxies: trustedProxies,
+			expected:       "10.0.0.2",
+		},
+		{
+			name:        "unknown remote address",
+			remoteAddr:  "@",
+			expectedErr: errUnknownClientIP,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/locations/me", nil)
+			req.RemoteAddr = test.remoteAddr
+			req.Header = test.header
+
+			ip, err := clientIP(req, test.trustedProxies)
+
+			require.Equal(t, test.expectedErr, err)
+			if test.expectedErr == nil {
+				require.Equal(t, test.expected, ip.String())
+			}
+		})
+	}
+}
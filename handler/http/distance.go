@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+type distanceFinder interface {
+	GetDistance(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error)
+}
+
+// distance is the response of getDistance.
+type distance struct {
+	From       models.Geolocation `json:"from"`
+	To         models.Geolocation `json:"to"`
+	DistanceKm float64            `json:"distance_km"`
+	// Travel is only set when the request has the elapsed time
+	Travel *travel `json:"travel,omitempty"`
+}
+
+// travel tells if the distance between two locations can be travelled in the elapsed time.
+type travel struct {
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	SpeedKmh       float64 `json:"speed_kmh"`
+	MaxSpeedKmh    float64 `json:"max_speed_kmh"`
+	Impossible     bool    `json:"impossible"`
+}
+
+// getDistance gets the distance between the locations of two IPs (from and to). Given the time elapsed between them
+// (elapsed, in Go duration format, e.g. 90m), it also tells if travelling that distance is impossible, above
+// max_speed_kmh (the server default if not set). IPs without a location are listed in a not found response, as
+// {"missing_ips": [...]}.
+func (h *httpServer) getDistance(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	query := req.URL.Query()
+	distanceReq := &pb.DistanceRequest{
+		FromIp: strings.TrimSpace(query.Get("from")),
+		ToIp:   strings.TrimSpace(query.Get("to")),
+	}
+
+	if e := query.Get("elapsed"); e != "" {
+		elapsed, err := time.ParseDuration(e)
+		if err != nil || elapsed <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid elapsed, expected a positive duration (e.g. 90m)"))
+			return
+		}
+
+		distanceReq.Elapsed = durationpb.New(elapsed)
+	}
+
+	if s := query.Get("max_speed_kmh"); s != "" {
+		maxSpeed, err := strconv.ParseFloat(s, 64)
+		if err != nil || !(maxSpeed > 0) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid max_speed_kmh, expected a positive number"))
+			return
+		}
+
+		distanceReq.MaxSpeedKmh = maxSpeed
+	}
+
+	result, err := h.distance.GetDistance(ctx, distanceReq)
+
+	var missing *grpc_client.MissingLocationsError
+	switch {
+	case err == nil:
+		break
+	case errors.Is(err, grpc_client.ErrInvalidIP):
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid IP address"))
+		return
+	case errors.As(err, &missing):
+		j, _ := json.Marshal(map[string][]string{"missing_ips": missing.IPs})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write(j)
+		return
+	default:
+		w.WriteHeader(grpcErrorStatus(err))
+		return
+	}
+
+	response := distance{
+		From:       toLocation(result.GetFrom()),
+		To:         toLocation(result.GetTo()),
+		DistanceKm: result.GetDistanceKm(),
+	}
+	if distanceReq.Elapsed != nil {
+		response.Travel = &travel{
+			ElapsedSeconds: distanceReq.Elapsed.AsDuration().Seconds(),
+			SpeedKmh:       result.GetSpeedKmh(),
+			MaxSpeedKmh:    result.GetMaxSpeedKmh(),
+			Impossible:     result.GetImpossibleTravel(),
+		}
+	}
+
+	j, _ := json.Marshal(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(j)
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+)
+
+type mockDistanceFinder struct {
+	GetDistanceFn func(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error)
+}
+
+func (m *mockDistanceFinder) GetDistance(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+	return m.GetDistanceFn(ctx, req)
+}
+
+func TestHandler_getDistance(t *testing.T) {
+	lisbon := &pb.LocationResponse{Ip: "1.1.1.1", CountryCode: "PT", Country: "Portugal", City: "Lisbon",
+		Latitude: 38.7, Longitude: -9.1}
+	porto := &pb.LocationResponse{Ip: "2.2.2.2", CountryCode: "PT", Country: "Portugal", City: "Porto",
+		Latitude: 41.2, Longitude: -8.6}
+
+	tests := []struct {
+		name             string
+		query            string
+		getDistanceFn    func(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error)
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name:  "distance only",
+			query: "?from=1.1.1.1&to=2.2.2.2",
+			getDistanceFn: func(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+				return &pb.DistanceResponse{From: lisbon, To: porto, DistanceKm: 274}, nil
+			},
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"from":{"ip_address":"1.1.1.1","country_code":"PT","country":"Portugal",` +
+				`"city":"Lisbon","latitude":38.7,"longitude":-9.1},"to":{"ip_address":"2.2.2.2","country_code":"PT",` +
+				`"country":"Portugal","city":"Porto","latitude":41.2,"longitude":-8.6},"distance_km":274}`,
+		},
+		{
+			name:  "travel plausibility",
+			query: "?from=1.1.1.1&to=2.2.2.2&elapsed=10m&max_speed_kmh=900",
+			getDistanceFn: func(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+				require.Equal(t, 10*time.Minute, req.GetElapsed().AsDuration())
+				require.Equal(t, 900.0, req.GetMaxSpeedKmh())
+
+				return &pb.DistanceResponse{From: lisbon, To: porto, DistanceKm: 274, SpeedKmh: 1644,
+					MaxSpeedKmh: 900, ImpossibleTravel: true}, nil
+			},
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:  "missing locations should return not found",
+			query: "?from=1.1.1.1&to=3.3.3.3",
+			getDistanceFn: func(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+				return nil, &grpc_client.MissingLocationsError{IPs: []string{"3.3.3.3"}}
+			},
+			expectedRespCode: http.StatusNotFound,
+			expectedRespBody: `{"missing_ips":["3.3.3.3"]}`,
+		},
+		{
+			name:  "invalid IP should return bad request",
+			query: "?from=1.1.1.1",
+			getDistanceFn: func(ctx context.Context, req *pb.DistanceRequest) (*pb.DistanceResponse, error) {
+				return nil, grpc_client.ErrInvalidIP
+			},
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "invalid elapsed should return bad request",
+			query:            "?from=1.1.1.1&to=2.2.2.2&elapsed=-1h",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "invalid max speed should return bad request",
+			query:            "?from=1.1.1.1&to=2.2.2.2&elapsed=1h&max_speed_kmh=fast",
+			expectedRespCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := httpServer{distance: &mockDistanceFinder{GetDistanceFn: test.getDistanceFn}}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/distance"+test.query, nil)
+
+			h.getDistance(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedRespBody != "" {
+				require.Equal(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+	"github.com/tiagocesar/geolocation/internal/resolver"
+)
+
+// maxHostAddresses is how many addresses of a host are looked up, at most.
+const maxHostAddresses = 16
+
+// hostLocations are the locations of the addresses a host resolves to.
+type hostLocations struct {
+	Host      string        `json:"host"`
+	Addresses []hostAddress `json:"addresses"`
+}
+
+type hostAddress struct {
+	IpAddress string `json:"ip_address"`
+	// Location is nil if the address has no location
+	Location *models.Geolocation `json:"location,omitempty"`
+}
+
+// writeHostLocations resolves host and writes the location of each of its addresses, looked up with the options in
+// the query of req. Hosts resolving to many addresses only get the first maxHostAddresses of them.
+func (h *httpServer) writeHostLocations(w http.ResponseWriter, req *http.Request, host string) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	opts, err := lookupOptions(req.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	addrs, err := h.resolver.Resolve(ctx, host)
+	switch {
+	case err == nil:
+		break
+	case errors.Is(err, resolver.ErrHostNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("host not found"))
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		w.WriteHeader(http.StatusGatewayTimeout)
+		return
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(addrs) > maxHostAddresses {
+		addrs = addrs[:maxHostAddresses]
+	}
+
+	result := hostLocations{Host: host, Addresses: make([]hostAddress, 0, len(addrs))}
+	for _, addr := range addrs {
+		address := hostAddress{IpAddress: addr.String()}
+
+		location, err := h.grpcClient.GetLocationData(ctx, address.IpAddress, opts...)
+		switch {
+		case err == nil:
+			l := toLocation(location)
+			address.Location = &l
+		case errors.Is(err, sql.ErrNoRows):
+			break
+		case errors.Is(err, context.DeadlineExceeded), status.Code(err) == codes.DeadlineExceeded:
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		result.Addresses = append(result.Addresses, address)
+	}
+
+	j, _ := json.Marshal(result)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(j)
+}
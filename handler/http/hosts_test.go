@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/resolver"
+)
+
+type mockResolver struct {
+	ResolveFn func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+func (m *mockResolver) Resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	return m.ResolveFn(ctx, host)
+}
+
+func TestHandler_getGeolocationData_hosts(t *testing.T) {
+	hosts := resolver.NewStatic(map[string][]netip.Addr{
+		"example.com": {netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")},
+	})
+
+	tests := []struct {
+		name             string
+		ip               string
+		resolver         resolver.Resolver
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name:             "every address of the host is listed with its location",
+			ip:               "example.com",
+			resolver:         hosts,
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"host":"example.com","addresses":[{"ip_address":"192.0.2.1","location":{` +
+				`"ip_address":"192.0.2.1","country_code":"PT","country":"Portugal","city":"Lisbon","latitude":0,` +
+				`"longitude":0}},{"ip_address":"2001:db8::1"}]}`,
+		},
+		{
+			name:             "IPs are looked up as usual",
+			ip:               "192.0.2.1",
+			resolver:         hosts,
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"ip_address":"192.0.2.1","country_code":"PT","country":"Portugal","city":"Lisbon",` +
+				`"latitude":0,"longitude":0}`,
+		},
+		{
+			name:             "hosts without addresses should return not found",
+			ip:               "missing.example.com",
+			resolver:         hosts,
+			expectedRespCode: http.StatusNotFound,
+			expectedRespBody: "host not found",
+		},
+		{
+			name: "resolver timeouts should return gateway timeout",
+			ip:   "example.com",
+			resolver: &mockResolver{ResolveFn: func(ctx context.Context, host string) ([]netip.Addr, error) {
+				return nil, context.DeadlineExceeded
+			}},
+			expectedRespCode: http.StatusGatewayTimeout,
+		},
+		{
+			name: "resolver errors should return internal server error",
+			ip:   "example.com",
+			resolver: &mockResolver{ResolveFn: func(ctx context.Context, host string) ([]netip.Addr, error) {
+				return nil, errors.New("connection refused")
+			}},
+			expectedRespCode: http.StatusInternalServerError,
+		},
+		{
+			name:             "hostnames are invalid IPs without a resolver",
+			ip:               "example.com",
+			expectedRespCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := httpServer{
+				grpcClient: &mockGrpcClient{
+					GetLocationDataFn: func(ctx context.Context, ip string,
+						opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+
+						switch ip {
+						case "192.0.2.1":
+							return &pb.LocationResponse{Ip: ip, CountryCode: "PT", Country: "Portugal", City: "Lisbon"}, nil
+						case "2001:db8::1":
+							return nil, grpc_client.ErrNotFound
+						default:
+							return nil, grpc_client.ErrInvalidIP
+						}
+					},
+				},
+				resolver: test.resolver,
+			}
+
+			rr := httptest.NewRecorder()
+
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("ip", test.ip)
+
+			req := httptest.NewRequest(http.MethodGet, "/locations/"+test.ip, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			h.getGeolocationData(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedRespBody != "" {
+				require.Equal(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
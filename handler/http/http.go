@@ -6,41 +6,198 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/tiagocesar/geolocation/clients/grpc_client"
 	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
 	"github.com/tiagocesar/geolocation/internal/models"
+	"github.com/tiagocesar/geolocation/internal/resolver"
 )
 
 type locationFinder interface {
-	GetLocationData(ctx context.Context, ip string) (*pb.LocationResponse, error)
+	GetLocationData(ctx context.Context, ip string, opts ...grpc_client.LookupOption) (*pb.LocationResponse, error)
+}
+
+type readinessChecker interface {
+	IsReady() bool
+}
+
+// Timeouts holds the limits applied to connections and requests served by the HTTP server.
+type Timeouts struct {
+	// Read, Write and Idle are applied to each connection (see http.Server).
+	Read  time.Duration
+	Write time.Duration
+	Idle  time.Duration
+	// Request is the deadline for each call made to the GRPC server on behalf of a request.
+	Request time.Duration
+	// Shutdown is how long in-flight requests have to finish once the server is stopping.
+	Shutdown time.Duration
 }
 
 type httpServer struct {
 	grpcClient locationFinder
+	locations  locationLister
+	nearby     nearbyFinder
+	distance   distanceFinder
+	stats      statsGetter
+	asn        asnFinder
+	imports    importLister
+	admin      importAdmin
+	readiness  readinessChecker
+	timeouts   Timeouts
+	// trustedProxies are the networks of the proxies whose forwarding headers tell the IP of the client
+	trustedProxies []netip.Prefix
+	// resolver resolves the hostnames looked up instead of IPs, which are rejected if it's nil
+	resolver resolver.Resolver
+}
+
+// ServerOption configures the HTTP server created via NewHttpServer.
+type ServerOption func(*httpServer)
+
+// WithTrustedProxies has /locations/me believe the Forwarded and X-Forwarded-For headers of requests coming from
+// the given networks. Without it, the client is always the address the request came from.
+func WithTrustedProxies(networks ...netip.Prefix) ServerOption {
+	return func(h *httpServer) {
+		h.trustedProxies = networks
+	}
 }
 
-func NewHttpServer(client *grpc_client.Client) *httpServer {
-	return &httpServer{
+// WithResolver has /locations/{ip} resolve hostnames through r, returning the locations of all of their addresses.
+// Without it, hostnames are rejected as invalid IPs.
+func WithResolver(r resolver.Resolver) ServerOption {
+	return func(h *httpServer) {
+		h.resolver = r
+	}
+}
+
+func NewHttpServer(client *grpc_client.Client, timeouts Timeouts, opts ...ServerOption) *httpServer {
+	h := &httpServer{
 		grpcClient: client,
+		locations:  client,
+		nearby:     client,
+		distance:   client,
+		stats:      client,
+		asn:        client,
+		imports:    client,
+		admin:      client,
+		readiness:  client,
+		timeouts:   timeouts,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+
+	return h
+}
+
+// ConfigureAndServe serves the API on port, and the admin endpoints on adminPort, until ctx is done, then drains
+// in-flight requests before returning. An error is returned if the servers can't be started or don't shut down
+// cleanly.
+func (h *httpServer) ConfigureAndServe(ctx context.Context, port, adminPort string) error {
+	apiListener, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		return fmt.Errorf("http server - failed to listen: %w", err)
+	}
+
+	adminListener, err := net.Listen("tcp", fmt.Sprintf(":%s", adminPort))
+	if err != nil {
+		_ = apiListener.Close()
+		return fmt.Errorf("http server - failed to listen: %w", err)
+	}
+
+	return h.serve(ctx, apiListener, adminListener)
 }
 
-func (h *httpServer) ConfigureAndServe(port string) {
+// serve serves the API and the admin endpoints on the given listeners until ctx is done.
+func (h *httpServer) serve(ctx context.Context, apiListener, adminListener net.Listener) error {
 	router := chi.NewRouter()
 	router.Use(middleware.Recoverer)
 
 	router.Get("/health", health)
+	router.Get("/ready", h.ready)
+	router.Get("/locations", h.listLocations)
+	router.Get("/locations/near", h.findNearbyLocations)
+	router.Get("/locations/me", h.getCallerGeolocationData)
 	router.Get("/locations/{ip}", h.getGeolocationData)
+	router.Get("/asn/{ip}", h.getASN)
+	router.Get("/distance", h.getDistance)
+	router.Get("/stats", h.getStats)
+	router.Get("/imports", h.listImports)
+	router.Get("/imports/{id}", h.getImport)
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%s", port), router); err != nil {
-		log.Fatalf("Failed to start HTTP server")
+	adminRouter := chi.NewRouter()
+	adminRouter.Use(middleware.Recoverer)
+
+	adminRouter.Get("/health", health)
+	adminRouter.Post("/admin/imports", h.startImport)
+	adminRouter.Delete("/admin/imports/{id}", h.cancelImport)
+
+	servers := map[*http.Server]net.Listener{
+		{
+			Handler:      router,
+			ReadTimeout:  h.timeouts.Read,
+			WriteTimeout: h.timeouts.Write,
+			IdleTimeout:  h.timeouts.Idle,
+			// Streamed responses extend the write deadline of their connection as they go
+			ConnContext: withConn,
+		}: apiListener,
+		// Uploads take as long as the file takes to be sent, so only reading the headers is limited
+		{
+			Handler:           adminRouter,
+			ReadHeaderTimeout: h.timeouts.Read,
+			IdleTimeout:       h.timeouts.Idle,
+		}: adminListener,
+	}
+
+	errCh := make(chan error, len(servers))
+	for server, listener := range servers {
+		server, listener := server, listener
+		go func() {
+			errCh <- server.Serve(listener)
+		}()
+	}
+
+	var serveErr error
+	select {
+	case err := <-errCh:
+		serveErr = fmt.Errorf("http server - failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), h.timeouts.Shutdown)
+	defer cancel()
+
+	for server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil && serveErr == nil {
+			serveErr = fmt.Errorf("http server - failed to shut down: %w", err)
+		}
+	}
+
+	return serveErr
+}
+
+type connKey struct{}
+
+// withConn stores the connection of the requests in their context, see extendWriteDeadline.
+func withConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// extendWriteDeadline gives the response to req another timeout to be written, instead of the write timeout of the
+// server counted from the start of the request. It does nothing if timeout is 0 or the connection isn't known.
+func extendWriteDeadline(req *http.Request, timeout time.Duration) {
+	if conn, ok := req.Context().Value(connKey{}).(net.Conn); ok && timeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(timeout))
 	}
 }
 
@@ -49,8 +206,27 @@ func health(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// ready reports if the API can currently reach the GRPC server.
+func (h *httpServer) ready(w http.ResponseWriter, _ *http.Request) {
+	if !h.readiness.IsReady() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = fmt.Fprint(w, "grpc server unreachable")
+		return
+	}
+
+	_, _ = fmt.Fprint(w, "ok")
+}
+
+// requestContext is the context of the calls made to the GRPC server on behalf of req, with the request timeout.
+func (h *httpServer) requestContext(req *http.Request) (context.Context, context.CancelFunc) {
+	if h.timeouts.Request > 0 {
+		return context.WithTimeout(req.Context(), h.timeouts.Request)
+	}
+
+	return context.WithCancel(req.Context())
+}
+
 func (h *httpServer) getGeolocationData(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
 	ip := chi.URLParam(req, "ip")
 
 	if strings.TrimSpace(ip) == "" {
@@ -59,16 +235,61 @@ func (h *httpServer) getGeolocationData(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	result, err := h.grpcClient.GetLocationData(ctx, ip)
+	if h.resolver != nil && resolver.ValidHost(ip) {
+		h.writeHostLocations(w, req, ip)
+		return
+	}
+
+	h.writeLocation(w, req, ip, false)
+}
+
+// getCallerGeolocationData gets the location of the client making the request (see clientIP).
+func (h *httpServer) getCallerGeolocationData(w http.ResponseWriter, req *http.Request) {
+	ip, err := clientIP(req, h.trustedProxies)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("can't tell the IP of the client"))
+		return
+	}
+
+	h.writeLocation(w, req, ip.String(), true)
+}
+
+// writeLocation writes the location of ip, looked up with the options in the query of req. If echoIP is set, the
+// IP is written even if it has no location, as {"ip_address": "..."}, so clients learn which IP was looked up.
+func (h *httpServer) writeLocation(w http.ResponseWriter, req *http.Request, ip string, echoIP bool) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	opts, err := lookupOptions(req.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	result, err := h.grpcClient.GetLocationData(ctx, ip, opts...)
 	switch {
 	case err == nil:
 		break
 	case errors.Is(err, sql.ErrNoRows):
+		if !echoIP {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		j, _ := json.Marshal(map[string]string{"ip_address": ip})
+
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write(j)
 		return
 	case errors.Is(err, grpc_client.ErrInvalidIP):
 		w.WriteHeader(http.StatusBadRequest)
 		return
+	case errors.Is(err, context.DeadlineExceeded), status.Code(err) == codes.DeadlineExceeded:
+		w.WriteHeader(http.StatusGatewayTimeout)
+		return
 	default:
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -84,11 +305,56 @@ func (h *httpServer) getGeolocationData(w http.ResponseWriter, req *http.Request
 
 func toLocation(response *pb.LocationResponse) models.Geolocation {
 	return models.Geolocation{
-		IpAddress:   response.GetIp(),
-		CountryCode: response.GetCountryCode(),
-		Country:     response.GetCountry(),
-		City:        response.GetCity(),
-		Latitude:    response.GetLatitude(),
-		Longitude:   response.GetLongitude(),
+		IpAddress:      response.GetIp(),
+		CountryCode:    response.GetCountryCode(),
+		Country:        response.GetCountry(),
+		City:           response.GetCity(),
+		Latitude:       response.GetLatitude(),
+		Longitude:      response.GetLongitude(),
+		MysteryValue:   response.GetMysteryValue(),
+		Attributes:     response.GetAttributes(),
+		Region:         response.GetRegion(),
+		PostalCode:     response.GetPostalCode(),
+		Timezone:       response.GetTimezone(),
+		AccuracyRadius: int(response.GetAccuracyRadius()),
+		Continent:      response.GetContinent(),
+	}
+}
+
+// lookupOptions returns the options of the location lookups of a request, from its query:
+//
+//   - as_of (RFC 3339) resolves the IP against the data that was current at that time;
+//   - include (or its alias fields) is a comma-separated list of the vendor attributes to return, "*" for all.
+func lookupOptions(query url.Values) ([]grpc_client.LookupOption, error) {
+	var opts []grpc_client.LookupOption
+	if asOf := query.Get("as_of"); asOf != "" {
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			return nil, errors.New("invalid as_of timestamp, expected RFC 3339")
+		}
+
+		opts = append(opts, grpc_client.AsOf(t))
 	}
+
+	if include := attributeNames(query); len(include) > 0 {
+		opts = append(opts, grpc_client.IncludeAttributes(include...))
+	}
+
+	return opts, nil
+}
+
+// attributeNames returns the attribute names of the include and fields query parameters.
+func attributeNames(query url.Values) []string {
+	var names []string
+	for _, param := range []string{"include", "fields"} {
+		for _, value := range query[param] {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	return names
 }
@@ -4,23 +4,33 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
 	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
 	"github.com/tiagocesar/geolocation/internal/models"
 )
 
 type mockGrpcClient struct {
-	GetLocationDataFn func(ctx context.Context, ip string) (*pb.LocationResponse, error)
+	GetLocationDataFn func(ctx context.Context, ip string, opts ...grpc_client.LookupOption) (*pb.LocationResponse, error)
 }
 
-func (m *mockGrpcClient) GetLocationData(ctx context.Context, ip string) (*pb.LocationResponse, error) {
-	return m.GetLocationDataFn(ctx, ip)
+func (m *mockGrpcClient) GetLocationData(ctx context.Context, ip string,
+	opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+
+	return m.GetLocationDataFn(ctx, ip, opts...)
 }
 
 func TestHandler_getGeolocationData(t *testing.T) {
@@ -34,11 +44,15 @@ func TestHandler_getGeolocationData(t *testing.T) {
 		{
 			name: "success",
 			grpcClientMock: &mockGrpcClient{
-				GetLocationDataFn: func(ctx context.Context, ip string) (*pb.LocationResponse, error) {
+				GetLocationDataFn: func(ctx context.Context, ip string, opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
 					return &pb.LocationResponse{
-						Ip:          "192.168.0.1",
-						CountryCode: "ZZZ",
-						Country:     "Unit Tests",
+						Ip:             "192.168.0.1",
+						CountryCode:    "ZZZ",
+						Country:        "Unit Tests",
+						MysteryValue:   "42",
+						Attributes:     map[string]string{"asn": "AS123"},
+						Timezone:       "UTC",
+						AccuracyRadius: 10,
 					}, nil
 				},
 			},
@@ -46,9 +60,13 @@ func TestHandler_getGeolocationData(t *testing.T) {
 			expectedRespCode: http.StatusOK,
 			expectedRespBody: func() string {
 				ev := models.Geolocation{
-					IpAddress:   "192.168.0.1",
-					CountryCode: "ZZZ",
-					Country:     "Unit Tests",
+					IpAddress:      "192.168.0.1",
+					CountryCode:    "ZZZ",
+					Country:        "Unit Tests",
+					MysteryValue:   "42",
+					Attributes:     map[string]string{"asn": "AS123"},
+					Timezone:       "UTC",
+					AccuracyRadius: 10,
 				}
 
 				s, _ := json.Marshal(ev)
@@ -66,16 +84,27 @@ func TestHandler_getGeolocationData(t *testing.T) {
 		{
 			name: "IP not found should return not found",
 			grpcClientMock: &mockGrpcClient{
-				func(ctx context.Context, ip string) (*pb.LocationResponse, error) {
+				func(ctx context.Context, ip string, opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
 					return nil, sql.ErrNoRows
 				},
 			},
 			ipAddress:        "192.168.0.1",
 			expectedRespCode: http.StatusNotFound,
 		},
+		{
+			name: "GRPC deadline exceeded should return gateway timeout",
+			grpcClientMock: &mockGrpcClient{
+				func(ctx context.Context, ip string, opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+					return nil, status.Error(codes.DeadlineExceeded, "context deadline exceeded")
+				},
+			},
+			ipAddress:        "192.168.0.1",
+			expectedRespCode: http.StatusGatewayTimeout,
+		},
 	}
 
 	for _, test := range tests {
+		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -103,3 +132,324 @@ func TestHandler_getGeolocationData(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_getGeolocationData_asOf(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		expectedRespCode int
+		expectedAsOf     *timestamppb.Timestamp
+	}{
+		{
+			name:             "no as_of",
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:             "valid as_of is passed to the GRPC client",
+			query:            "?as_of=2023-01-02T03:04:05Z",
+			expectedRespCode: http.StatusOK,
+			expectedAsOf:     timestamppb.New(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+		},
+		{
+			name:             "invalid as_of should return bad request",
+			query:            "?as_of=yesterday",
+			expectedRespCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotReq pb.LocationRequest
+			h := httpServer{
+				grpcClient: &mockGrpcClient{
+					GetLocationDataFn: func(ctx context.Context, ip string,
+						opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+
+						for _, opt := range opts {
+							opt(&gotReq)
+						}
+						return &pb.LocationResponse{}, nil
+					},
+				},
+			}
+
+			rr := httptest.NewRecorder()
+
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("ip", "192.168.0.1")
+
+			req := httptest.NewRequest(http.MethodGet, "/locations/192.168.0.1"+test.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			h.getGeolocationData(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			require.Equal(t, test.expectedAsOf.AsTime(), gotReq.GetAsOf().AsTime())
+		})
+	}
+}
+
+func TestHandler_getGeolocationData_include(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		expectedInclude []string
+	}{
+		{
+			name: "no attributes",
+		},
+		{
+			name:            "include is passed to the GRPC client",
+			query:           "?include=asn,%20isp",
+			expectedInclude: []string{"asn", "isp"},
+		},
+		{
+			name:            "fields is an alias of include",
+			query:           "?fields=*",
+			expectedInclude: []string{"*"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotReq pb.LocationRequest
+			h := httpServer{
+				grpcClient: &mockGrpcClient{
+					GetLocationDataFn: func(ctx context.Context, ip string,
+						opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+
+						for _, opt := range opts {
+							opt(&gotReq)
+						}
+						return &pb.LocationResponse{}, nil
+					},
+				},
+			}
+
+			rr := httptest.NewRecorder()
+
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("ip", "192.168.0.1")
+
+			req := httptest.NewRequest(http.MethodGet, "/locations/192.168.0.1"+test.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			h.getGeolocationData(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Equal(t, test.expectedInclude, gotReq.GetInclude())
+		})
+	}
+}
+
+func TestHandler_getCallerGeolocationData(t *testing.T) {
+	tests := []struct {
+		name             string
+		remoteAddr       string
+		forwardedFor     string
+		err              error
+		expectedIP       string
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name:             "the location of the client is returned with its IP",
+			remoteAddr:       "203.0.113.7:51234",
+			expectedIP:       "203.0.113.7",
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"ip_address":"203.0.113.7","country_code":"PT","country":"Portugal","city":"Lisbon",` +
+				`"latitude":0,"longitude":0}`,
+		},
+		{
+			name:             "the client behind a trusted proxy",
+			remoteAddr:       "10.0.0.1:51234",
+			forwardedFor:     "2001:db8::7",
+			expectedIP:       "2001:db8::7",
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:             "a client without a location still gets its IP",
+			remoteAddr:       "203.0.113.7:51234",
+			err:              grpc_client.ErrNotFound,
+			expectedIP:       "203.0.113.7",
+			expectedRespCode: http.StatusNotFound,
+			expectedRespBody: `{"ip_address":"203.0.113.7"}`,
+		},
+		{
+			name:             "unknown client should return bad request",
+			remoteAddr:       "@",
+			expectedRespCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := httpServer{
+				grpcClient: &mockGrpcClient{
+					GetLocationDataFn: func(ctx context.Context, ip string,
+						opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+
+						require.Equal(t, test.expectedIP, ip)
+						if test.err != nil {
+							return nil, test.err
+						}
+
+						return &pb.LocationResponse{Ip: ip, CountryCode: "PT", Country: "Portugal", City: "Lisbon"}, nil
+					},
+				},
+				trustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			}
+
+			rr := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/locations/me", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+
+			h.getCallerGeolocationData(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedRespBody != "" {
+				require.Equal(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_getGeolocationData_requestDeadline(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+
+	h := httpServer{
+		grpcClient: &mockGrpcClient{
+			GetLocationDataFn: func(ctx context.Context, ip string, opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+				deadline, hasDeadline = ctx.Deadline()
+				return &pb.LocationResponse{}, nil
+			},
+		},
+		timeouts: Timeouts{Request: time.Minute},
+	}
+
+	ctx := chi.NewRouteContext()
+	ctx.URLParams.Add("ip", "192.168.0.1")
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/locations/{ip}", nil)
+	require.NoError(t, err)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+	h.getGeolocationData(httptest.NewRecorder(), req)
+
+	require.True(t, hasDeadline)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+func TestHandler_ConfigureAndServe_shutdown(t *testing.T) {
+	started := make(chan struct{})
+	h := &httpServer{
+		grpcClient: &mockGrpcClient{
+			GetLocationDataFn: func(ctx context.Context, ip string,
+				opts ...grpc_client.LookupOption) (*pb.LocationResponse, error) {
+
+				close(started)
+				time.Sleep(200 * time.Millisecond)
+				return &pb.LocationResponse{Ip: ip, CountryCode: "PT"}, nil
+			},
+		},
+		timeouts: Timeouts{Shutdown: 5 * time.Second},
+	}
+
+	apiListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	adminListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.serve(ctx, apiListener, adminListener)
+	}()
+
+	// A slow request is in flight when the server is stopped
+	type response struct {
+		code int
+		body string
+		err  error
+	}
+	respCh := make(chan response, 1)
+	go func() {
+		resp, err := http.Get("http://" + apiListener.Addr().String() + "/locations/192.168.0.1")
+		if err != nil {
+			respCh <- response{err: err}
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		respCh <- response{code: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+
+	// The request was drained, rather than cut off
+	resp := <-respCh
+	require.NoError(t, resp.err)
+	require.Equal(t, http.StatusOK, resp.code)
+	require.Contains(t, resp.body, `"country_code":"PT"`)
+
+	// Once shut down, new requests are refused
+	_, err = http.Get("http://" + apiListener.Addr().String() + "/health")
+	require.Error(t, err)
+}
+
+type mockReadiness bool
+
+func (m mockReadiness) IsReady() bool {
+	return bool(m)
+}
+
+func TestHandler_ready(t *testing.T) {
+	tests := []struct {
+		name             string
+		ready            bool
+		expectedRespCode int
+	}{
+		{name: "GRPC server reachable", ready: true, expectedRespCode: http.StatusOK},
+		{name: "GRPC server unreachable", ready: false, expectedRespCode: http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+
+			h := httpServer{readiness: mockReadiness(test.ready)}
+			h.ready(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+		})
+	}
+}
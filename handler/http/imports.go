@@ -0,0 +1,154 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+type importLister interface {
+	ListImports(ctx context.Context, limit int) ([]*pb.Import, error)
+	GetImport(ctx context.Context, id int64) (*pb.Import, error)
+}
+
+// importResponse is an import as returned by the API, with the live stats computed by the GRPC server.
+type importResponse struct {
+	models.Import
+	// Throughput is the number of lines processed per second
+	Throughput float64 `json:"throughput"`
+	// ETASeconds estimates how long a running import will take to finish
+	ETASeconds *float64 `json:"eta_seconds,omitempty"`
+}
+
+func (h *httpServer) listImports(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	// limit is optional, the GRPC server applies a default when it's missing
+	var limit int
+	if l := req.URL.Query().Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("invalid limit, expected a positive number"))
+			return
+		}
+	}
+
+	result, err := h.imports.ListImports(ctx, limit)
+	if err != nil {
+		w.WriteHeader(grpcErrorStatus(err))
+		return
+	}
+
+	imports := make([]importResponse, 0, len(result))
+	for _, imp := range result {
+		imports = append(imports, toImport(imp))
+	}
+
+	j, _ := json.Marshal(imports)
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, string(j))
+}
+
+func (h *httpServer) getImport(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	id, err := strconv.ParseInt(chi.URLParam(req, "id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid import ID"))
+		return
+	}
+
+	result, err := h.imports.GetImport(ctx, id)
+	if err != nil {
+		w.WriteHeader(grpcErrorStatus(err))
+		return
+	}
+
+	j, _ := json.Marshal(toImport(result))
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, string(j))
+}
+
+// grpcErrorStatus maps an error returned by the GRPC client to the HTTP status code of the response.
+func grpcErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case status.Code(err) == codes.InvalidArgument:
+		return http.StatusBadRequest
+	case status.Code(err) == codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case status.Code(err) == codes.PermissionDenied:
+		return http.StatusForbidden
+	case status.Code(err) == codes.FailedPrecondition:
+		return http.StatusConflict
+	case status.Code(err) == codes.Unimplemented:
+		return http.StatusNotImplemented
+	case errors.Is(err, context.DeadlineExceeded), status.Code(err) == codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func toImport(response *pb.Import) importResponse {
+	imp := importResponse{
+		Import: models.Import{
+			ID:         response.GetId(),
+			DatasetID:  response.GetDatasetId(),
+			SourceFile: response.GetSourceFile(),
+			FileSize:   response.GetFileSize(),
+			BytesRead:  response.GetBytesRead(),
+			Status:     response.GetStatus(),
+			Lines: models.LineCounts{
+				Total:    response.GetLines().GetTotal(),
+				Accepted: response.GetLines().GetAccepted(),
+				Invalid:  response.GetLines().GetInvalid(),
+			},
+			Errors:    response.GetErrors(),
+			Error:     response.GetError(),
+			StartedAt: response.GetStartedAt().AsTime(),
+			UpdatedAt: response.GetUpdatedAt().AsTime(),
+		},
+		Throughput: response.GetThroughput(),
+	}
+
+	if response.GetFinishedAt() != nil {
+		finishedAt := response.GetFinishedAt().AsTime()
+		imp.FinishedAt = &finishedAt
+	}
+
+	if response.GetEta() != nil {
+		eta := response.GetEta().AsDuration().Seconds()
+		imp.ETASeconds = &eta
+	}
+
+	for _, c := range response.GetConflicts() {
+		imp.Conflicts = append(imp.Conflicts, models.Conflict{
+			IpAddress: c.GetIpAddress(),
+			Lines:     c.GetLines(),
+			KeptLine:  c.GetKeptLine(),
+		})
+	}
+	imp.TotalConflicts = response.GetTotalConflicts()
+
+	return imp
+}
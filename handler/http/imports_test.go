@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tiagocesar/geolocation/clients/grpc_client"
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+type mockImportLister struct {
+	ListImportsFn func(ctx context.Context, limit int) ([]*pb.Import, error)
+	GetImportFn   func(ctx context.Context, id int64) (*pb.Import, error)
+}
+
+func (m *mockImportLister) ListImports(ctx context.Context, limit int) ([]*pb.Import, error) {
+	return m.ListImportsFn(ctx, limit)
+}
+
+func (m *mockImportLister) GetImport(ctx context.Context, id int64) (*pb.Import, error) {
+	return m.GetImportFn(ctx, id)
+}
+
+func TestHandler_listImports(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		expectedRespCode int
+		expectedLimit    int
+	}{
+		{
+			name:             "no limit",
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:             "limit is passed to the GRPC client",
+			query:            "?limit=5",
+			expectedRespCode: http.StatusOK,
+			expectedLimit:    5,
+		},
+		{
+			name:             "invalid limit should return bad request",
+			query:            "?limit=-1",
+			expectedRespCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotLimit int
+			h := httpServer{
+				imports: &mockImportLister{
+					ListImportsFn: func(ctx context.Context, limit int) ([]*pb.Import, error) {
+						gotLimit = limit
+						return []*pb.Import{mockImport()}, nil
+					},
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/imports"+test.query, nil)
+
+			h.listImports(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			require.Equal(t, test.expectedLimit, gotLimit)
+
+			var imports []importResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &imports))
+			require.Len(t, imports, 1)
+		})
+	}
+}
+
+func TestHandler_getImport(t *testing.T) {
+	tests := []struct {
+		name             string
+		id               string
+		getImportFn      func(ctx context.Context, id int64) (*pb.Import, error)
+		expectedRespCode int
+	}{
+		{
+			name: "success",
+			id:   "1",
+			getImportFn: func(ctx context.Context, id int64) (*pb.Import, error) {
+				return mockImport(), nil
+			},
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:             "invalid ID should return bad request",
+			id:               "a",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name: "import not found should return not found",
+			id:   "2",
+			getImportFn: func(ctx context.Context, id int64) (*pb.Import, error) {
+				return nil, grpc_client.ErrImportNotFound
+			},
+			expectedRespCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			rr := httptest.NewRecorder()
+
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("id", test.id)
+
+			req := httptest.NewRequest(http.MethodGet, "/imports/{id}", nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+
+			h := httpServer{
+				imports: &mockImportLister{GetImportFn: test.getImportFn},
+			}
+
+			h.getImport(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var imp importResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &imp))
+			require.Equal(t, int64(1), imp.ID)
+			require.Equal(t, models.ImportStatusRunning, imp.Status)
+			require.Equal(t, models.LineCounts{Total: 50, Accepted: 47, Invalid: 3}, imp.Lines)
+			require.Equal(t, 5.0, imp.Throughput)
+			require.NotNil(t, imp.ETASeconds)
+			require.Equal(t, 30.0, *imp.ETASeconds)
+			require.Equal(t, []models.Conflict{{IpAddress: "1.1.1.1", Lines: []uint64{3, 8}, KeptLine: 3}},
+				imp.Conflicts)
+			require.Nil(t, imp.FinishedAt)
+		})
+	}
+}
+
+func mockImport() *pb.Import {
+	return &pb.Import{
+		Id:         1,
+		DatasetId:  2,
+		SourceFile: "dump.csv",
+		FileSize:   400,
+		BytesRead:  100,
+		Status:     models.ImportStatusRunning,
+		Lines:      &pb.LineCounts{Total: 50, Accepted: 47, Invalid: 3},
+		Errors:     map[string]uint64{models.ErrorReasonInvalidIP: 3},
+		StartedAt:  timestamppb.New(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:  timestamppb.New(time.Date(2022, 1, 1, 0, 0, 9, 0, time.UTC)),
+		Throughput: 5,
+		Eta:        durationpb.New(30 * time.Second),
+		Conflicts: []*pb.ImportConflict{
+			{IpAddress: "1.1.1.1", Lines: []uint64{3, 8}, KeptLine: 3},
+		},
+		TotalConflicts: 1,
+	}
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+)
+
+const (
+	// defaultListLimit is how many locations are listed when the request doesn't set a limit.
+	defaultListLimit = 1000
+	// maxListLimit is how many locations can be listed at once, the next ones being listed via the cursor.
+	maxListLimit = 100_000
+)
+
+type locationLister interface {
+	ListLocations(ctx context.Context, req *pb.ListLocationsRequest, fn func(page *pb.ListLocationsResponse) error) error
+}
+
+// listLocations lists the current locations of a country (country_code), optionally of a single city (city), sorted
+// by IP. Up to limit locations are listed (default 1000, at most 100000); next_cursor, when set, is the cursor query
+// parameter listing the next ones.
+//
+// The locations are written as they're received from the GRPC server, so large listings don't have to fit in
+// memory. Each page gets the write timeout of the server to be written, rather than the whole listing. Errors after
+// the first page can't change the status anymore, and abort the response instead.
+func (h *httpServer) listLocations(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	countryCode := strings.TrimSpace(query.Get("country_code"))
+	if countryCode == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("country_code is required"))
+		return
+	}
+
+	limit := defaultListLimit
+	if l := query.Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(fmt.Sprintf("invalid limit, expected a number between 1 and %d", maxListLimit)))
+			return
+		}
+	}
+
+	listReq := &pb.ListLocationsRequest{
+		CountryCode: countryCode,
+		City:        strings.TrimSpace(query.Get("city")),
+		Cursor:      query.Get("cursor"),
+		Limit:       int32(limit),
+	}
+
+	// The listing isn't bound to the request timeout, since it takes as long as the client reads it
+	lw := &listWriter{w: w, extendDeadline: func() { extendWriteDeadline(req, h.timeouts.Write) }}
+	if err := h.locations.ListLocations(req.Context(), listReq, lw.writePage); err != nil {
+		if !lw.started {
+			w.WriteHeader(grpcErrorStatus(err))
+			return
+		}
+
+		log.Printf("Listing locations of %s failed: %v\n", countryCode, err)
+		panic(http.ErrAbortHandler)
+	}
+
+	lw.close()
+}
+
+// listWriter writes the pages of a listing as a single JSON object, {"locations": [...], "next_cursor": "..."}.
+type listWriter struct {
+	w http.ResponseWriter
+	// extendDeadline, if set, is called before each page is written
+	extendDeadline func()
+	started        bool
+	count          int
+	cursor         string
+	err            error
+}
+
+func (lw *listWriter) writePage(page *pb.ListLocationsResponse) error {
+	if lw.extendDeadline != nil {
+		lw.extendDeadline()
+	}
+
+	if !lw.started {
+		lw.started = true
+		lw.w.Header().Set("Content-Type", "application/json")
+		lw.w.WriteHeader(http.StatusOK)
+		lw.write([]byte(`{"locations":[`))
+	}
+
+	for _, location := range page.GetLocations() {
+		if lw.count > 0 {
+			lw.write([]byte(","))
+		}
+		lw.count++
+
+		j, _ := json.Marshal(toLocation(location))
+		lw.write(j)
+	}
+
+	lw.cursor = page.GetNextCursor()
+
+	if flusher, ok := lw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	// Stops the listing if the client went away
+	return lw.err
+}
+
+func (lw *listWriter) close() {
+	if !lw.started {
+		_ = lw.writePage(&pb.ListLocationsResponse{})
+	}
+
+	j, _ := json.Marshal(lw.cursor)
+	lw.write([]byte(`],"next_cursor":`))
+	lw.write(j)
+	lw.write([]byte("}"))
+}
+
+// write writes b unless a previous write failed, keeping the first error.
+func (lw *listWriter) write(b []byte) {
+	if lw.err == nil {
+		_, lw.err = lw.w.Write(b)
+	}
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+)
+
+type mockLocationLister struct {
+	ListLocationsFn func(ctx context.Context, req *pb.ListLocationsRequest,
+		fn func(page *pb.ListLocationsResponse) error) error
+}
+
+func (m *mockLocationLister) ListLocations(ctx context.Context, req *pb.ListLocationsRequest,
+	fn func(page *pb.ListLocationsResponse) error) error {
+
+	return m.ListLocationsFn(ctx, req, fn)
+}
+
+func TestHandler_listLocations(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		pages            []*pb.ListLocationsResponse
+		listErr          error
+		expectedReq      *pb.ListLocationsRequest
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name:  "pages are written as a single list",
+			query: "?country_code=NL&city=Amsterdam&limit=3&cursor=abc",
+			pages: []*pb.ListLocationsResponse{
+				{Locations: []*pb.LocationResponse{{Ip: "1.1.1.1"}, {Ip: "1.1.1.2"}}, NextCursor: "def"},
+				{Locations: []*pb.LocationResponse{{Ip: "1.1.1.3"}}, NextCursor: "ghi"},
+			},
+			expectedReq:      &pb.ListLocationsRequest{CountryCode: "NL", City: "Amsterdam", Cursor: "abc", Limit: 3},
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"locations":[` +
+				`{"ip_address":"1.1.1.1","country_code":"","country":"","city":"","latitude":0,"longitude":0},` +
+				`{"ip_address":"1.1.1.2","country_code":"","country":"","city":"","latitude":0,"longitude":0},` +
+				`{"ip_address":"1.1.1.3","country_code":"","country":"","city":"","latitude":0,"longitude":0}` +
+				`],"next_cursor":"ghi"}`,
+		},
+		{
+			name:             "no locations",
+			query:            "?country_code=NL",
+			pages:            []*pb.ListLocationsResponse{{}},
+			expectedReq:      &pb.ListLocationsRequest{CountryCode: "NL", Limit: defaultListLimit},
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"locations":[],"next_cursor":""}`,
+		},
+		{
+			name:             "missing country code should return bad request",
+			query:            "?city=Amsterdam",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "invalid limit should return bad request",
+			query:            "?country_code=NL&limit=0",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "limit above the maximum should return bad request",
+			query:            "?country_code=NL&limit=100001",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "limit overflowing int32 should return bad request",
+			query:            "?country_code=NL&limit=4294967296",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "invalid cursor should return bad request",
+			query:            "?country_code=NL&cursor=abc",
+			listErr:          status.Error(codes.InvalidArgument, "invalid cursor"),
+			expectedReq:      &pb.ListLocationsRequest{CountryCode: "NL", Cursor: "abc", Limit: defaultListLimit},
+			expectedRespCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotReq *pb.ListLocationsRequest
+			h := httpServer{
+				locations: &mockLocationLister{
+					ListLocationsFn: func(ctx context.Context, req *pb.ListLocationsRequest,
+						fn func(page *pb.ListLocationsResponse) error) error {
+
+						gotReq = req
+						if test.listErr != nil {
+							return test.listErr
+						}
+
+						for _, page := range test.pages {
+							if err := fn(page); err != nil {
+								return err
+							}
+						}
+						return nil
+					},
+				},
+			}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/locations"+test.query, nil)
+
+			h.listLocations(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedReq != nil {
+				require.Equal(t, test.expectedReq.String(), gotReq.String())
+			}
+			if test.expectedRespBody != "" {
+				require.JSONEq(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_listLocations_abortsOnLateErrors(t *testing.T) {
+	h := httpServer{
+		locations: &mockLocationLister{
+			ListLocationsFn: func(ctx context.Context, req *pb.ListLocationsRequest,
+				fn func(page *pb.ListLocationsResponse) error) error {
+
+				_ = fn(&pb.ListLocationsResponse{Locations: []*pb.LocationResponse{{Ip: "1.1.1.1"}}, NextCursor: "a"})
+				return status.Error(codes.Unavailable, "server went away")
+			},
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/locations?country_code=NL", nil)
+
+	// The status was already sent, so the response is aborted rather than completed
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() { h.listLocations(rr, req) })
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestHandler_serve_slowListing(t *testing.T) {
+	h := &httpServer{
+		locations: &mockLocationLister{
+			ListLocationsFn: func(ctx context.Context, req *pb.ListLocationsRequest,
+				fn func(page *pb.ListLocationsResponse) error) error {
+
+				for i := 0; i < 5; i++ {
+					time.Sleep(30 * time.Millisecond)
+					if err := fn(&pb.ListLocationsResponse{Locations: []*pb.LocationResponse{{Ip: "1.1.1.1"}}}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		timeouts: Timeouts{Read: time.Second, Write: 50 * time.Millisecond, Shutdown: time.Second},
+	}
+
+	apiListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	adminListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- h.serve(ctx, apiListener, adminListener)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-errCh)
+	}()
+
+	// The listing takes longer than the write timeout, each of its pages doesn't
+	resp, err := http.Get("http://" + apiListener.Addr().String() + "/locations?country_code=NL")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var listing struct {
+		Locations []json.RawMessage `json:"locations"`
+	}
+	require.NoError(t, json.Unmarshal(body, &listing))
+	require.Len(t, listing.Locations, 5)
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+type nearbyFinder interface {
+	FindNearbyLocations(ctx context.Context, req *pb.NearbyLocationsRequest) ([]*pb.NearbyLocation, error)
+}
+
+// findNearbyLocations finds the current locations within radius_km of a point (lat and lon), the nearest first, or
+// inside a bounding box (min_lat, min_lon, max_lat and max_lon), sorted by IP. Up to limit locations are found
+// (default 100).
+func (h *httpServer) findNearbyLocations(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	nearbyReq, err := nearbyLocationsRequest(req.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	result, err := h.nearby.FindNearbyLocations(ctx, nearbyReq)
+	if err != nil {
+		w.WriteHeader(grpcErrorStatus(err))
+		return
+	}
+
+	locations := make([]models.NearbyLocation, 0, len(result))
+	for _, location := range result {
+		locations = append(locations, models.NearbyLocation{
+			Geolocation: toLocation(location.GetLocation()),
+			DistanceKm:  location.GetDistanceKm(),
+		})
+	}
+
+	j, _ := json.Marshal(map[string]interface{}{"locations": locations})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(j)
+}
+
+// nearbyLocationsRequest reads the area and limit of a search from the query parameters. The ranges are checked by
+// the GRPC server.
+func nearbyLocationsRequest(query url.Values) (*pb.NearbyLocationsRequest, error) {
+	nearbyReq := &pb.NearbyLocationsRequest{}
+
+	if l := query.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid limit, expected a positive number")
+		}
+
+		nearbyReq.Limit = int32(limit)
+	}
+
+	if query.Has("lat") || query.Has("lon") || query.Has("radius_km") {
+		values, err := floatParams(query, "lat", "lon", "radius_km")
+		if err != nil {
+			return nil, err
+		}
+
+		nearbyReq.Area = &pb.NearbyLocationsRequest_Circle{
+			Circle: &pb.Circle{Latitude: values[0], Longitude: values[1], RadiusKm: values[2]},
+		}
+
+		return nearbyReq, nil
+	}
+
+	values, err := floatParams(query, "min_lat", "min_lon", "max_lat", "max_lon")
+	if err != nil {
+		return nil, fmt.Errorf("either lat, lon and radius_km or a bounding box is required: %w", err)
+	}
+
+	nearbyReq.Area = &pb.NearbyLocationsRequest_Box{
+		Box: &pb.BoundingBox{MinLatitude: values[0], MinLongitude: values[1], MaxLatitude: values[2],
+			MaxLongitude: values[3]},
+	}
+
+	return nearbyReq, nil
+}
+
+// floatParams parses the query parameters of the given names, all of them required.
+func floatParams(query url.Values, names ...string) ([]float64, error) {
+	values := make([]float64, 0, len(names))
+	for _, name := range names {
+		s := query.Get(name)
+		if s == "" {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s, expected a number", name)
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+)
+
+type mockNearbyFinder struct {
+	FindNearbyLocationsFn func(ctx context.Context, req *pb.NearbyLocationsRequest) ([]*pb.NearbyLocation, error)
+}
+
+func (m *mockNearbyFinder) FindNearbyLocations(ctx context.Context,
+	req *pb.NearbyLocationsRequest) ([]*pb.NearbyLocation, error) {
+
+	return m.FindNearbyLocationsFn(ctx, req)
+}
+
+func TestHandler_findNearbyLocations(t *testing.T) {
+	lisbon := &pb.LocationResponse{Ip: "1.1.1.1", CountryCode: "PT", Country: "Portugal", City: "Lisbon",
+		Latitude: 38.7, Longitude: -9.1}
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedReq      *pb.NearbyLocationsRequest
+		err              error
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name:  "circle",
+			query: "?lat=38.8&lon=-9.2&radius_km=50",
+			expectedReq: &pb.NearbyLocationsRequest{Area: &pb.NearbyLocationsRequest_Circle{
+				Circle: &pb.Circle{Latitude: 38.8, Longitude: -9.2, RadiusKm: 50},
+			}},
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"locations":[{"ip_address":"1.1.1.1","country_code":"PT","country":"Portugal",` +
+				`"city":"Lisbon","latitude":38.7,"longitude":-9.1,"distance_km":14}]}`,
+		},
+		{
+			name:  "bounding box with a limit",
+			query: "?min_lat=38&min_lon=-10&max_lat=39&max_lon=-9&limit=10",
+			expectedReq: &pb.NearbyLocationsRequest{
+				Area: &pb.NearbyLocationsRequest_Box{
+					Box: &pb.BoundingBox{MinLatitude: 38, MinLongitude: -10, MaxLatitude: 39, MaxLongitude: -9},
+				},
+				Limit: 10,
+			},
+			expectedRespCode: http.StatusOK,
+		},
+		{
+			name:             "missing radius should return bad request",
+			query:            "?lat=38.8&lon=-9.2",
+			expectedRespCode: http.StatusBadRequest,
+			expectedRespBody: "radius_km is required",
+		},
+		{
+			name:             "missing area should return bad request",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "invalid coordinates should return bad request",
+			query:            "?lat=north&lon=-9.2&radius_km=50",
+			expectedRespCode: http.StatusBadRequest,
+			expectedRespBody: "invalid lat, expected a number",
+		},
+		{
+			name:             "invalid limit should return bad request",
+			query:            "?lat=38.8&lon=-9.2&radius_km=50&limit=0",
+			expectedRespCode: http.StatusBadRequest,
+		},
+		{
+			name:             "areas rejected by the GRPC server should return bad request",
+			query:            "?lat=38.8&lon=-9.2&radius_km=5000",
+			err:              status.Error(codes.InvalidArgument, "radius_km must be above 0 and at most 1000"),
+			expectedRespCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := httpServer{nearby: &mockNearbyFinder{
+				FindNearbyLocationsFn: func(ctx context.Context,
+					req *pb.NearbyLocationsRequest) ([]*pb.NearbyLocation, error) {
+
+					if test.err != nil {
+						return nil, test.err
+					}
+
+					require.True(t, proto.Equal(test.expectedReq, req), "unexpected request %v", req)
+
+					return []*pb.NearbyLocation{{Location: lisbon, DistanceKm: 14}}, nil
+				},
+			}}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/locations/near"+test.query, nil)
+
+			h.findNearbyLocations(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedRespBody != "" {
+				require.Equal(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+type statsGetter interface {
+	GetStats(ctx context.Context) (*pb.StatsResponse, error)
+}
+
+// getStats summarizes the current locations: their number, per IP version, per country (with the top cities of each
+// one) and per /8 prefix, along with the dataset they were imported with.
+func (h *httpServer) getStats(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := h.requestContext(req)
+	defer cancel()
+
+	result, err := h.stats.GetStats(ctx)
+	if err != nil {
+		w.WriteHeader(grpcErrorStatus(err))
+		return
+	}
+
+	j, _ := json.Marshal(toStats(result))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(j)
+}
+
+func toStats(response *pb.StatsResponse) models.Stats {
+	stats := models.Stats{
+		DatasetID:      response.GetDatasetId(),
+		SourceFile:     response.GetSourceFile(),
+		TotalLocations: response.GetTotalLocations(),
+		IPv4Locations:  response.GetIpv4Locations(),
+		IPv6Locations:  response.GetIpv6Locations(),
+		Countries:      make([]models.CountryStats, 0, len(response.GetCountries())),
+		Prefixes:       make([]models.PrefixStats, 0, len(response.GetPrefixes())),
+	}
+
+	if response.GetActivatedAt() != nil {
+		activatedAt := response.GetActivatedAt().AsTime()
+		stats.ActivatedAt = &activatedAt
+	}
+
+	for _, country := range response.GetCountries() {
+		c := models.CountryStats{
+			CountryCode: country.GetCountryCode(),
+			Country:     country.GetCountry(),
+			Locations:   country.GetLocations(),
+			TopCities:   make([]models.CityStats, 0, len(country.GetTopCities())),
+		}
+		for _, city := range country.GetTopCities() {
+			c.TopCities = append(c.TopCities, models.CityStats{City: city.GetCity(), Locations: city.GetLocations()})
+		}
+
+		stats.Countries = append(stats.Countries, c)
+	}
+
+	for _, prefix := range response.GetPrefixes() {
+		stats.Prefixes = append(stats.Prefixes,
+			models.PrefixStats{Prefix: prefix.GetPrefix(), Locations: prefix.GetLocations()})
+	}
+
+	return stats
+}
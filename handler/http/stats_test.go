@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/tiagocesar/geolocation/handler/grpc/schema"
+)
+
+type mockStatsGetter struct {
+	GetStatsFn func(ctx context.Context) (*pb.StatsResponse, error)
+}
+
+func (m *mockStatsGetter) GetStats(ctx context.Context) (*pb.StatsResponse, error) {
+	return m.GetStatsFn(ctx)
+}
+
+func TestHandler_getStats(t *testing.T) {
+	tests := []struct {
+		name             string
+		getStatsFn       func(ctx context.Context) (*pb.StatsResponse, error)
+		expectedRespCode int
+		expectedRespBody string
+	}{
+		{
+			name: "success",
+			getStatsFn: func(ctx context.Context) (*pb.StatsResponse, error) {
+				return &pb.StatsResponse{
+					DatasetId:      3,
+					SourceFile:     "data_dump.csv",
+					ActivatedAt:    timestamppb.New(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)),
+					TotalLocations: 3,
+					Ipv4Locations:  2,
+					Ipv6Locations:  1,
+					Countries: []*pb.CountryStats{{
+						CountryCode: "BR",
+						Country:     "Brazil",
+						Locations:   3,
+						TopCities:   []*pb.CityStats{{City: "Brasilia", Locations: 3}},
+					}},
+					Prefixes: []*pb.PrefixStats{{Prefix: "1.0.0.0/8", Locations: 2}},
+				}, nil
+			},
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"dataset_id":3,"source_file":"data_dump.csv","activated_at":"2023-01-02T03:04:05Z",` +
+				`"total_locations":3,"ipv4_locations":2,"ipv6_locations":1,"countries":[{"country_code":"BR",` +
+				`"country":"Brazil","locations":3,"top_cities":[{"city":"Brasilia","locations":3}]}],` +
+				`"prefixes":[{"prefix":"1.0.0.0/8","locations":2}]}`,
+		},
+		{
+			name: "no dataset",
+			getStatsFn: func(ctx context.Context) (*pb.StatsResponse, error) {
+				return &pb.StatsResponse{}, nil
+			},
+			expectedRespCode: http.StatusOK,
+			expectedRespBody: `{"dataset_id":0,"total_locations":0,"ipv4_locations":0,"ipv6_locations":0,` +
+				`"countries":[],"prefixes":[]}`,
+		},
+		{
+			name: "GRPC server unavailable should return internal server error",
+			getStatsFn: func(ctx context.Context) (*pb.StatsResponse, error) {
+				return nil, status.Error(codes.Unavailable, "connection refused")
+			},
+			expectedRespCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := httpServer{stats: &mockStatsGetter{GetStatsFn: test.getStatsFn}}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/stats", nil)
+
+			h.getStats(rr, req)
+
+			require.Equal(t, test.expectedRespCode, rr.Code)
+			if test.expectedRespBody != "" {
+				require.Equal(t, test.expectedRespBody, rr.Body.String())
+			}
+		})
+	}
+}
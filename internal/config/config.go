@@ -0,0 +1,652 @@
+// Package config loads the configuration shared by the services.
+//
+// Values are read, in increasing order of precedence, from the defaults, a YAML or TOML file (set via the -config
+// flag or the CONFIG_FILE environment variable), environment variables and command line flags. Each setting is
+// described by the tags of its field:
+//
+//   - yaml: key in the configuration file, within its section (a table in TOML files);
+//   - env: environment variable. Secrets can also be read from the file named by the same variable with a _FILE
+//     suffix (e.g. DB_PASS_FILE);
+//   - flag: command line flag;
+//   - default: value used when the setting isn't set anywhere else;
+//   - secret: the value is redacted when the configuration is printed.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+const (
+	EnvConfigFile = "CONFIG_FILE"
+
+	secretFileSuffix = "_FILE"
+	redacted         = "[REDACTED]"
+)
+
+// Config holds the settings of all services; each service validates the sections it uses.
+type Config struct {
+	DB       DB       `yaml:"db"`
+	GRPC     GRPC     `yaml:"grpc"`
+	HTTP     HTTP     `yaml:"http"`
+	Importer Importer `yaml:"importer"`
+}
+
+type DB struct {
+	// DSN is a full connection string, overriding the other connection settings
+	DSN         string `yaml:"dsn" env:"DB_DSN" flag:"db-dsn" secret:"true"`
+	User        string `yaml:"user" env:"DB_USER" flag:"db-user"`
+	Pass        string `yaml:"pass" env:"DB_PASS" flag:"db-pass" secret:"true"`
+	Host        string `yaml:"host" env:"DB_HOST" flag:"db-host" default:"localhost"`
+	Port        int    `yaml:"port" env:"DB_PORT" flag:"db-port" default:"5432"`
+	Name        string `yaml:"name" env:"DB_NAME" flag:"db-name" default:"geolocation"`
+	Schema      string `yaml:"schema" env:"DB_SCHEMA" flag:"db-schema" default:"public"`
+	SSLMode     string `yaml:"ssl_mode" env:"DB_SSL_MODE" flag:"db-ssl-mode" default:"disable"`
+	SSLRootCert string `yaml:"ssl_root_cert" env:"DB_SSL_ROOT_CERT" flag:"db-ssl-root-cert"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" default:"20"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" default:"10"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time" default:"5m"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT" flag:"db-connect-timeout" default:"1m"`
+}
+
+type GRPC struct {
+	ServerHost      string        `yaml:"server_host" env:"GRPC_SERVER_HOST" flag:"grpc-server-host" default:"localhost"`
+	ServerPort      int           `yaml:"server_port" env:"GRPC_SERVER_PORT" flag:"grpc-server-port" default:"8080"`
+	ServerAddresses []string      `yaml:"server_addresses" env:"GRPC_SERVER_ADDRESSES" flag:"grpc-server-addresses"`
+	CallTimeout     time.Duration `yaml:"call_timeout" env:"GRPC_CALL_TIMEOUT" flag:"grpc-call-timeout" default:"2s"`
+	MaxAttempts     int           `yaml:"max_attempts" env:"GRPC_MAX_ATTEMPTS" flag:"grpc-max-attempts" default:"3"`
+	StartupTimeout  time.Duration `yaml:"startup_timeout" env:"GRPC_STARTUP_TIMEOUT" flag:"grpc-startup-timeout" default:"30s"`
+	// AdminToken authenticates the admin methods of the GRPC server, which are disabled if it's empty
+	AdminToken string `yaml:"admin_token" env:"ADMIN_TOKEN" flag:"admin-token" secret:"true"`
+	// MaxTravelSpeed is the speed, in km/h, above which travelling between the locations of two IPs is flagged as
+	// impossible
+	MaxTravelSpeed float64 `yaml:"max_travel_speed" env:"GRPC_MAX_TRAVEL_SPEED" flag:"grpc-max-travel-speed" default:"1000"`
+}
+
+type HTTP struct {
+	ServerPort      int           `yaml:"server_port" env:"HTTP_SERVER_PORT" flag:"http-server-port" default:"8081"`
+	AdminPort       int           `yaml:"admin_port" env:"HTTP_ADMIN_PORT" flag:"http-admin-port" default:"8082"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" flag:"http-read-timeout" default:"5s"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"http-write-timeout" default:"10s"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"http-idle-timeout" default:"60s"`
+	RequestTimeout  time.Duration `yaml:"request_timeout" env:"HTTP_REQUEST_TIMEOUT" flag:"http-request-timeout" default:"3s"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT" flag:"http-shutdown-timeout" default:"15s"`
+	// TrustedProxies are the networks (e.g. 10.0.0.0/8) or IPs of the proxies whose forwarding headers tell the IP
+	// of the client
+	TrustedProxies []string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" flag:"http-trusted-proxies"`
+	// ResolveHosts has hostnames looked up instead of IPs resolved, rather than rejected
+	ResolveHosts bool `yaml:"resolve_hosts" env:"HTTP_RESOLVE_HOSTS" flag:"http-resolve-hosts"`
+	// HostsFile resolves hostnames from a file in the format of /etc/hosts, instead of DNS
+	HostsFile       string        `yaml:"hosts_file" env:"HTTP_HOSTS_FILE" flag:"http-hosts-file"`
+	ResolveTimeout  time.Duration `yaml:"resolve_timeout" env:"HTTP_RESOLVE_TIMEOUT" flag:"http-resolve-timeout" default:"2s"`
+	ResolveCacheTTL time.Duration `yaml:"resolve_cache_ttl" env:"HTTP_RESOLVE_CACHE_TTL" flag:"http-resolve-cache-ttl" default:"5m"`
+}
+
+type Importer struct {
+	DumpFile      string        `yaml:"dump_file" env:"DUMP_FILE" flag:"dump-file"`
+	Mode          string        `yaml:"mode" env:"IMPORTER_MODE" flag:"mode" default:"once"`
+	WatchInterval time.Duration `yaml:"watch_interval" env:"IMPORTER_WATCH_INTERVAL" flag:"watch-interval" default:"1m"`
+	TotalRoutines int           `yaml:"total_routines" env:"IMPORTER_TOTAL_ROUTINES" flag:"total-routines" default:"10"`
+	// BatchSize is how many lines are committed per transaction, along with a checkpoint imports can resume from
+	BatchSize int `yaml:"batch_size" env:"IMPORTER_BATCH_SIZE" flag:"batch-size" default:"1000"`
+	// ProgressInterval is how often the progress of a running import is saved
+	ProgressInterval time.Duration `yaml:"progress_interval" env:"IMPORTER_PROGRESS_INTERVAL" flag:"progress-interval" default:"1s"`
+	// UploadDir is where files uploaded to the GRPC server are stored while they're imported (the default
+	// directory for temporary files if empty)
+	UploadDir string `yaml:"upload_dir" env:"IMPORTER_UPLOAD_DIR" flag:"upload-dir"`
+	// WatchDir is the directory watched for new dump files in directory mode
+	WatchDir string `yaml:"watch_dir" env:"IMPORTER_WATCH_DIR" flag:"watch-dir"`
+	// StableFor is how long a dropped file's size must not change before it's imported, unless it has a marker
+	StableFor time.Duration `yaml:"stable_for" env:"IMPORTER_STABLE_FOR" flag:"stable-for" default:"10s"`
+	// DuplicatePolicy resolves the conflicts between lines with the same IP and different data: first-wins,
+	// last-wins, most-complete or reject-all
+	DuplicatePolicy string `yaml:"duplicate_policy" env:"IMPORTER_DUPLICATE_POLICY" flag:"duplicate-policy" default:"first-wins"`
+	// Dataset is the kind of the dump files: location or asn
+	Dataset string `yaml:"dataset" env:"IMPORTER_DATASET" flag:"dataset" default:"location"`
+	// DryRun validates the dump file and reports what importing it would do, without touching the database
+	DryRun bool `yaml:"dry_run" env:"IMPORTER_DRY_RUN" flag:"dry-run"`
+	// MaxInvalidPercent is the maximum percentage of invalid lines of a dump file, 100 allowing any
+	MaxInvalidPercent float64 `yaml:"max_invalid_percent" env:"IMPORTER_MAX_INVALID_PERCENT" flag:"max-invalid-percent" default:"100"`
+	// MinAcceptedLines is the minimum number of valid lines of a dump file
+	MinAcceptedLines int `yaml:"min_accepted_lines" env:"IMPORTER_MIN_ACCEPTED_LINES" flag:"min-accepted-lines" default:"1"`
+	// MaxDeltaPercent is the maximum difference between the valid lines of a dump file and the rows of the current
+	// dataset, as a percentage of the latter (0 allowing any difference)
+	MaxDeltaPercent float64 `yaml:"max_delta_percent" env:"IMPORTER_MAX_DELTA_PERCENT" flag:"max-delta-percent" default:"0"`
+}
+
+const (
+	// ImporterModeOnce imports the dump file and exits, with a status code reflecting the result of the import
+	ImporterModeOnce = "once"
+	// ImporterModeWatch keeps running, importing the dump file again every time it changes
+	ImporterModeWatch = "watch"
+	// ImporterModeDirectory keeps running, importing every new dump file dropped into the watch directory
+	ImporterModeDirectory = "directory"
+)
+
+// Load builds the configuration of the program called name from the defaults, the configuration file,
+// environment variables and args (the command line flags, without the program name).
+func Load(name string, args []string) (*Config, error) {
+	var cfg Config
+	settings := cfg.settings()
+
+	for _, s := range settings {
+		if s.def == "" {
+			continue
+		}
+
+		if err := s.set(s.def); err != nil {
+			return nil, fmt.Errorf("config - invalid default for %s: %w", s.key, err)
+		}
+	}
+
+	// Flags are parsed first to find the configuration file, but only applied last
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(EnvConfigFile), "path to a YAML or TOML configuration file")
+
+	flagValues := make(map[string]*flagValue, len(settings))
+	for _, s := range settings {
+		if s.flag != "" {
+			flagValues[s.flag] = &flagValue{raw: s.def, isBool: s.value.Kind() == reflect.Bool}
+			fs.Var(flagValues[s.flag], s.flag, fmt.Sprintf("%s (env %s)", s.key, s.env))
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// The keys of the settings set anywhere but in the defaults
+	set := make(map[string]bool)
+
+	if *configFile != "" {
+		keys, err := cfg.loadFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			set[key] = true
+		}
+	}
+
+	for _, s := range settings {
+		ok, err := s.loadEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		set[s.key] = set[s.key] || ok
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && err == nil {
+				if setErr := s.set(flagValues[f.Name].raw); setErr != nil {
+					err = fmt.Errorf("config - invalid value for flag -%s: %w", f.Name, setErr)
+				}
+				set[s.key] = true
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The schema used to be the name of the database, which would silently be connected to under its default name
+	if set["db.schema"] && !set["db.name"] && cfg.DB.DSN == "" {
+		return nil, errors.New("config - db schema is set without db name: the schema (DB_SCHEMA) is the " +
+			"search_path of the connections, the database is set via db name (DB_NAME)")
+	}
+
+	return &cfg, nil
+}
+
+// flagValue holds the raw value of a flag until it's applied. Bool settings are bool flags, so they can be set
+// without a value (e.g. -dry-run).
+type flagValue struct {
+	raw    string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return v.raw
+}
+
+func (v *flagValue) Set(raw string) error {
+	if v.isBool {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return err
+		}
+	}
+
+	v.raw = raw
+
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// loadFile loads the configuration file at path, in YAML or TOML depending on its extension, returning the keys of
+// the settings it sets.
+func (c *Config) loadFile(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	switch ext {
+	case ".yaml", ".yml", ".toml":
+	default:
+		return nil, fmt.Errorf("config - unsupported configuration file format %q", ext)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config - failed to read configuration file: %w", err)
+	}
+
+	var keys []string
+	if ext == ".toml" {
+		keys, err = c.loadTOML(content)
+	} else {
+		keys, err = c.loadYAML(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config - failed to parse configuration file %s: %w", path, err)
+	}
+
+	return keys, nil
+}
+
+// loadYAML loads a YAML configuration file, returning the keys of the settings it sets.
+func (c *Config) loadYAML(content []byte) ([]string, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	// Decoding again, as the configuration doesn't tell which settings were in the file
+	var sections map[string]map[string]yaml.Node
+	if err := yaml.Unmarshal(content, &sections); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for section, values := range sections {
+		for key := range values {
+			keys = append(keys, section+"."+key)
+		}
+	}
+
+	return keys, nil
+}
+
+// loadTOML loads a TOML configuration file, returning the keys of the settings it sets. Its tables and keys are
+// the sections and keys of the YAML file.
+func (c *Config) loadTOML(content []byte) ([]string, error) {
+	var sections map[string]map[string]toml.Primitive
+	md, err := toml.Decode(string(content), &sections)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]setting)
+	for _, s := range c.settings() {
+		settings[s.key] = s
+	}
+
+	var keys []string
+	for section, values := range sections {
+		// Keys outside of the tables are decoded as empty sections
+		if md.Type(section) != "Hash" {
+			return nil, fmt.Errorf("unknown setting %s", section)
+		}
+
+		for key, value := range values {
+			s, ok := settings[section+"."+key]
+			if !ok {
+				return nil, fmt.Errorf("unknown setting %s.%s", section, key)
+			}
+
+			if err := md.PrimitiveDecode(value, s.value.Addr().Interface()); err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %w", s.key, err)
+			}
+
+			keys = append(keys, s.key)
+		}
+	}
+
+	return keys, nil
+}
+
+// String prints the effective configuration, one setting per line, with secrets redacted.
+func (c *Config) String() string {
+	var sb strings.Builder
+
+	for _, s := range c.settings() {
+		value := s.String()
+		if s.secret && value != "" {
+			value = redacted
+		}
+
+		_, _ = fmt.Fprintf(&sb, "%s: %s\n", s.key, value)
+	}
+
+	return sb.String()
+}
+
+func (c DB) Validate() error {
+	switch {
+	case c.MaxOpenConns < 0 || c.MaxIdleConns < 0:
+		return errors.New("config - db connection pool sizes can't be negative")
+	case c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns:
+		return errors.New("config - db max idle connections can't exceed max open connections")
+	case c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0:
+		return errors.New("config - db connection lifetimes can't be negative")
+	case c.ConnectTimeout <= 0:
+		return errors.New("config - db connect timeout must be positive")
+	}
+
+	// The remaining settings are part of the DSN, when it's set
+	if c.DSN != "" {
+		return nil
+	}
+
+	switch {
+	case strings.TrimSpace(c.User) == "":
+		return errors.New("config - db user is required")
+	case strings.TrimSpace(c.Host) == "":
+		return errors.New("config - db host is required")
+	case strings.TrimSpace(c.Name) == "":
+		return errors.New("config - db name is required")
+	}
+
+	switch c.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return fmt.Errorf("config - invalid db ssl mode %q", c.SSLMode)
+	}
+
+	return validatePort("db port", c.Port)
+}
+
+// ValidateServer validates the settings used to serve GRPC.
+func (c GRPC) ValidateServer() error {
+	if c.MaxTravelSpeed <= 0 {
+		return errors.New("config - grpc max travel speed must be positive")
+	}
+
+	return validatePort("grpc server port", c.ServerPort)
+}
+
+// ValidateClient validates the settings used to connect to the GRPC server.
+func (c GRPC) ValidateClient() error {
+	if len(c.ServerAddresses) == 0 {
+		if strings.TrimSpace(c.ServerHost) == "" {
+			return errors.New("config - either grpc server addresses or host are required")
+		}
+
+		if err := validatePort("grpc server port", c.ServerPort); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case c.CallTimeout < 0:
+		return errors.New("config - grpc call timeout can't be negative")
+	case c.MaxAttempts < 1:
+		return errors.New("config - grpc max attempts must be at least 1")
+	case c.StartupTimeout <= 0:
+		return errors.New("config - grpc startup timeout must be positive")
+	}
+
+	return nil
+}
+
+func (c HTTP) Validate() error {
+	if err := validatePort("http server port", c.ServerPort); err != nil {
+		return err
+	}
+
+	if err := validatePort("http admin port", c.AdminPort); err != nil {
+		return err
+	}
+
+	if c.AdminPort == c.ServerPort {
+		return errors.New("config - http admin port must differ from the server port")
+	}
+
+	for name, d := range map[string]time.Duration{
+		"read":     c.ReadTimeout,
+		"write":    c.WriteTimeout,
+		"idle":     c.IdleTimeout,
+		"request":  c.RequestTimeout,
+		"shutdown": c.ShutdownTimeout,
+		"resolve":  c.ResolveTimeout,
+	} {
+		if d < 0 {
+			return fmt.Errorf("config - http %s timeout can't be negative", name)
+		}
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if _, err := trustedProxy(proxy); err != nil {
+			return fmt.Errorf("config - invalid http trusted proxy %q", proxy)
+		}
+	}
+
+	if c.ResolveCacheTTL < 0 {
+		return errors.New("config - http resolve cache ttl can't be negative")
+	}
+
+	return nil
+}
+
+// TrustedProxyNetworks are the networks of the trusted proxies, an IP being a network of its own.
+func (c HTTP) TrustedProxyNetworks() []netip.Prefix {
+	networks := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, proxy := range c.TrustedProxies {
+		if network, err := trustedProxy(proxy); err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	return networks
+}
+
+// trustedProxy parses a trusted proxy, either a network or an IP.
+func trustedProxy(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		return models.ParseNetwork(proxy)
+	}
+
+	ip, err := models.ParseIP(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+func (c Importer) Validate() error {
+	switch {
+	case c.Mode != ImporterModeOnce && c.Mode != ImporterModeWatch && c.Mode != ImporterModeDirectory:
+		return fmt.Errorf("config - importer mode must be one of %q, %q or %q", ImporterModeOnce, ImporterModeWatch,
+			ImporterModeDirectory)
+	case c.Mode == ImporterModeDirectory && strings.TrimSpace(c.WatchDir) == "":
+		return errors.New("config - importer watch dir is required in directory mode")
+	case c.Mode != ImporterModeDirectory && strings.TrimSpace(c.DumpFile) == "":
+		return errors.New("config - importer dump file is required")
+	case c.WatchInterval <= 0:
+		return errors.New("config - importer watch interval must be positive")
+	case c.TotalRoutines < 1:
+		return errors.New("config - importer total routines must be at least 1")
+	case c.BatchSize < 1:
+		return errors.New("config - importer batch size must be at least 1")
+	case c.ProgressInterval <= 0:
+		return errors.New("config - importer progress interval must be positive")
+	case c.StableFor <= 0:
+		return errors.New("config - importer stable for must be positive")
+	case !models.IsDatasetKind(c.Dataset):
+		return fmt.Errorf("config - importer dataset must be %q or %q", models.DatasetKindLocation,
+			models.DatasetKindASN)
+	case c.DryRun && c.Mode != ImporterModeOnce:
+		return fmt.Errorf("config - importer dry run is only supported in %q mode", ImporterModeOnce)
+	case c.DryRun && c.Dataset != models.DatasetKindLocation:
+		return fmt.Errorf("config - importer dry run is only supported for %q dump files", models.DatasetKindLocation)
+	case c.MaxInvalidPercent < 0 || c.MaxInvalidPercent > 100:
+		return errors.New("config - importer max invalid percent must be between 0 and 100")
+	case c.MinAcceptedLines < 0:
+		return errors.New("config - importer min accepted lines can't be negative")
+	case c.MaxDeltaPercent < 0:
+		return errors.New("config - importer max delta percent can't be negative")
+	}
+
+	return nil
+}
+
+func validatePort(name string, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("config - %s must be between 1 and 65535", name)
+	}
+
+	return nil
+}
+
+// setting is a single configuration value, described by the tags of its field.
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	def    string
+	secret bool
+	value  reflect.Value
+}
+
+// settings lists every setting of the configuration, section by section.
+func (c *Config) settings() []setting {
+	var result []setting
+
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sectionKey := sections.Type().Field(i).Tag.Get("yaml")
+
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Type().Field(j)
+			result = append(result, setting{
+				key:    sectionKey + "." + field.Tag.Get("yaml"),
+				env:    field.Tag.Get("env"),
+				flag:   field.Tag.Get("flag"),
+				def:    field.Tag.Get("default"),
+				secret: field.Tag.Get("secret") == "true",
+				value:  section.Field(j),
+			})
+		}
+	}
+
+	return result
+}
+
+// loadEnv sets the value from its environment variable, or from the file named by its _FILE variant if it's a
+// secret. It returns false if neither is set.
+func (s setting) loadEnv() (bool, error) {
+	if s.env == "" {
+		return false, nil
+	}
+
+	if s.secret {
+		if path, ok := os.LookupEnv(s.env + secretFileSuffix); ok {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return false, fmt.Errorf("config - failed to read %s: %w", s.env+secretFileSuffix, err)
+			}
+
+			return true, s.set(strings.TrimRight(string(content), "\r\n"))
+		}
+	}
+
+	raw, ok := os.LookupEnv(s.env)
+	if !ok {
+		return false, nil
+	}
+
+	if err := s.set(raw); err != nil {
+		return false, fmt.Errorf("config - invalid value for environment variable %s: %w", s.env, err)
+	}
+
+	return true, nil
+}
+
+// set parses raw according to the type of the setting. Lists are comma-separated.
+func (s setting) set(raw string) error {
+	switch s.value.Interface().(type) {
+	case string:
+		s.value.SetString(raw)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetFloat(f)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(d))
+	case []string:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported type %s", s.value.Type())
+	}
+
+	return nil
+}
+
+func (s setting) String() string {
+	if list, ok := s.value.Interface().([]string); ok {
+		return strings.Join(list, ",")
+	}
+
+	return fmt.Sprint(s.value.Interface())
+}
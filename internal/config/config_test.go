@@ -0,0 +1,531 @@
+//go:build !integration
+
+package config
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+func Test_Load(t *testing.T) {
+	dir := t.TempDir()
+
+	configFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+db:
+  user: file-user
+  host: file-host
+  port: 5433
+importer:
+  total_routines: 20
+  watch_interval: 5m
+`), 0o600))
+
+	passFile := filepath.Join(dir, "db_pass")
+	require.NoError(t, os.WriteFile(passFile, []byte("s3cr3t\n"), 0o600))
+
+	t.Setenv(EnvConfigFile, configFile)
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("DB_PASS_FILE", passFile)
+	t.Setenv("IMPORTER_TOTAL_ROUTINES", "30")
+	t.Setenv("GRPC_SERVER_ADDRESSES", "a:1, b:2")
+
+	cfg, err := Load("test", []string{"-total-routines", "40"})
+	require.NoError(t, err)
+
+	// Defaults
+	require.Equal(t, "geolocation", cfg.DB.Name)
+	require.Equal(t, 3*time.Second, cfg.HTTP.RequestTimeout)
+	// File
+	require.Equal(t, "file-user", cfg.DB.User)
+	require.Equal(t, 5433, cfg.DB.Port)
+	require.Equal(t, 5*time.Minute, cfg.Importer.WatchInterval)
+	// Environment overrides file
+	require.Equal(t, "env-host", cfg.DB.Host)
+	require.Equal(t, "s3cr3t", cfg.DB.Pass)
+	require.Equal(t, []string{"a:1", "b:2"}, cfg.GRPC.ServerAddresses)
+	// Flags override environment
+	require.Equal(t, 40, cfg.Importer.TotalRoutines)
+}
+
+func Test_Load_toml(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+[db]
+user = "file-user"
+port = 5433
+name = "geo"
+schema = "geolocation"
+
+[grpc]
+server_addresses = ["a:1", "b:2"]
+max_travel_speed = 900
+
+[importer]
+watch_interval = "5m"
+max_invalid_percent = 2.5
+dry_run = true
+`), 0o600))
+
+	t.Setenv("DB_HOST", "env-host")
+
+	cfg, err := Load("test", []string{"-config", configFile, "-db-port", "5434"})
+	require.NoError(t, err)
+
+	// Defaults
+	require.Equal(t, "localhost", cfg.GRPC.ServerHost)
+	// File
+	require.Equal(t, "file-user", cfg.DB.User)
+	require.Equal(t, "geo", cfg.DB.Name)
+	require.Equal(t, "geolocation", cfg.DB.Schema)
+	require.Equal(t, []string{"a:1", "b:2"}, cfg.GRPC.ServerAddresses)
+	require.Equal(t, 900.0, cfg.GRPC.MaxTravelSpeed)
+	require.Equal(t, 5*time.Minute, cfg.Importer.WatchInterval)
+	require.Equal(t, 2.5, cfg.Importer.MaxInvalidPercent)
+	require.True(t, cfg.Importer.DryRun)
+	// Environment and flags override file
+	require.Equal(t, "env-host", cfg.DB.Host)
+	require.Equal(t, 5434, cfg.DB.Port)
+}
+
+func Test_Load_fileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+	}{
+		{name: "unknown yaml key", filename: "config.yaml", content: "db:\n  username: root\n"},
+		{name: "unknown yaml section", filename: "config.yaml", content: "database:\n  user: root\n"},
+		{name: "invalid yaml value", filename: "config.yaml", content: "db:\n  port: abc\n"},
+		{name: "unknown toml key", filename: "config.toml", content: "[db]\nusername = \"root\"\n"},
+		{name: "unknown toml table", filename: "config.toml", content: "[database]\nuser = \"root\"\n"},
+		{name: "toml key outside a table", filename: "config.toml", content: "user = \"root\"\n"},
+		{name: "invalid toml value", filename: "config.toml", content: "[db]\nport = \"abc\"\n"},
+		{name: "invalid toml duration", filename: "config.toml", content: "[http]\nread_timeout = \"soon\"\n"},
+		{name: "invalid toml", filename: "config.toml", content: "[db\n"},
+		{name: "toml db schema without db name", filename: "config.toml", content: "[db]\nschema = \"geo\"\n"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			configFile := filepath.Join(t.TempDir(), test.filename)
+			require.NoError(t, os.WriteFile(configFile, []byte(test.content), 0o600))
+
+			_, err := Load("test", []string{"-config", configFile})
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_Load_boolFlags(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		expectedDryRun   bool
+		expectedDumpFile string
+	}{
+		{
+			name:             "bare bool flag followed by another flag",
+			args:             []string{"-dry-run", "-dump-file", "data_dump.csv"},
+			expectedDryRun:   true,
+			expectedDumpFile: "data_dump.csv",
+		},
+		{
+			name:             "bool flag with a value",
+			args:             []string{"-dry-run=false", "-dump-file", "data_dump.csv"},
+			expectedDumpFile: "data_dump.csv",
+		},
+		{
+			name:             "bare bool flag last",
+			args:             []string{"-dump-file", "data_dump.csv", "-dry-run"},
+			expectedDryRun:   true,
+			expectedDumpFile: "data_dump.csv",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := Load("test", test.args)
+			require.NoError(t, err)
+
+			require.Equal(t, test.expectedDryRun, cfg.Importer.DryRun)
+			require.Equal(t, test.expectedDumpFile, cfg.Importer.DumpFile)
+		})
+	}
+}
+
+func Test_Load_dryRun(t *testing.T) {
+	// The dry run example of the README
+	cfg, err := Load("importer", []string{"-dry-run", "-dump-file", "vendor.csv", "-max-invalid-percent", "5"})
+	require.NoError(t, err)
+
+	require.True(t, cfg.Importer.DryRun)
+	require.Equal(t, "vendor.csv", cfg.Importer.DumpFile)
+	require.Equal(t, float64(5), cfg.Importer.MaxInvalidPercent)
+	require.NoError(t, cfg.Importer.Validate())
+}
+
+func Test_Load_dbSchema(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("db:\n  schema: geolocation\n"), 0o600))
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		args        []string
+		expectedErr bool
+	}{
+		{name: "defaults"},
+		{name: "db schema with db name", env: map[string]string{"DB_SCHEMA": "geo", "DB_NAME": "geolocation"}},
+		{name: "db schema with a dsn", env: map[string]string{"DB_SCHEMA": "geo", "DB_DSN": "postgres://localhost/geo"}},
+		{name: "db schema in the file", env: map[string]string{EnvConfigFile: configFile}, expectedErr: true},
+		{
+			name: "db schema in the file, db name as a flag",
+			env:  map[string]string{EnvConfigFile: configFile},
+			args: []string{"-db-name", "geolocation"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load("test", test.args)
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_Load_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{
+			name: "invalid environment variable",
+			env:  map[string]string{"DB_PORT": "abc"},
+		},
+		{
+			name: "invalid flag",
+			args: []string{"-http-read-timeout", "soon"},
+		},
+		{
+			name: "invalid bool flag",
+			args: []string{"-dry-run=maybe"},
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-unknown"},
+		},
+		{
+			name: "missing secret file",
+			env:  map[string]string{"DB_PASS_FILE": "/does/not/exist"},
+		},
+		{
+			name: "db schema without db name",
+			env:  map[string]string{"DB_SCHEMA": "geolocation"},
+		},
+		{
+			name: "db schema without db name, as a flag",
+			args: []string{"-db-schema", "geolocation"},
+		},
+		{
+			name: "unsupported configuration file",
+			env:  map[string]string{EnvConfigFile: "config.json"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load("test", test.args)
+			require.Error(t, err)
+		})
+	}
+}
+
+// defaults loads the default configuration.
+func defaults(t *testing.T) *Config {
+	t.Helper()
+
+	cfg, err := Load("test", nil)
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func Test_DB_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(c *DB)
+		expectedErr bool
+	}{
+		{name: "defaults lack a user", modify: func(c *DB) {}, expectedErr: true},
+		{name: "valid", modify: func(c *DB) { c.User = "root" }},
+		{name: "the DSN replaces the connection settings", modify: func(c *DB) {
+			c.DSN = "postgres://root@localhost/geolocation"
+			c.Port = 0
+		}},
+		{name: "missing host", modify: func(c *DB) {
+			c.User = "root"
+			c.Host = " "
+		}, expectedErr: true},
+		{name: "missing name", modify: func(c *DB) {
+			c.User = "root"
+			c.Name = ""
+		}, expectedErr: true},
+		{name: "invalid ssl mode", modify: func(c *DB) {
+			c.User = "root"
+			c.SSLMode = "sometimes"
+		}, expectedErr: true},
+		{name: "invalid port", modify: func(c *DB) {
+			c.User = "root"
+			c.Port = 70000
+		}, expectedErr: true},
+		{name: "more idle than open connections", modify: func(c *DB) {
+			c.User = "root"
+			c.MaxOpenConns = 5
+			c.MaxIdleConns = 10
+		}, expectedErr: true},
+		{name: "negative connection lifetime", modify: func(c *DB) {
+			c.User = "root"
+			c.ConnMaxLifetime = -time.Second
+		}, expectedErr: true},
+		{name: "no connect timeout", modify: func(c *DB) {
+			c.User = "root"
+			c.ConnectTimeout = 0
+		}, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := defaults(t).DB
+			test.modify(&cfg)
+
+			err := cfg.Validate()
+			require.Equal(t, test.expectedErr, err != nil, err)
+		})
+	}
+}
+
+func Test_GRPC_ValidateServer(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(c *GRPC)
+		expectedErr bool
+	}{
+		{name: "defaults", modify: func(c *GRPC) {}},
+		{name: "no max travel speed", modify: func(c *GRPC) { c.MaxTravelSpeed = 0 }, expectedErr: true},
+		{name: "invalid port", modify: func(c *GRPC) { c.ServerPort = 70000 }, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := defaults(t).GRPC
+			test.modify(&cfg)
+
+			err := cfg.ValidateServer()
+			require.Equal(t, test.expectedErr, err != nil, err)
+		})
+	}
+}
+
+func Test_GRPC_ValidateClient(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(c *GRPC)
+		expectedErr bool
+	}{
+		{name: "defaults", modify: func(c *GRPC) {}},
+		{name: "addresses replace the host and port", modify: func(c *GRPC) {
+			c.ServerAddresses = []string{"10.0.0.1:8080"}
+			c.ServerHost = ""
+			c.ServerPort = 0
+		}},
+		{name: "missing host", modify: func(c *GRPC) { c.ServerHost = " " }, expectedErr: true},
+		{name: "invalid port", modify: func(c *GRPC) { c.ServerPort = 0 }, expectedErr: true},
+		{name: "negative call timeout", modify: func(c *GRPC) { c.CallTimeout = -time.Second }, expectedErr: true},
+		{name: "no attempts", modify: func(c *GRPC) { c.MaxAttempts = 0 }, expectedErr: true},
+		{name: "no startup timeout", modify: func(c *GRPC) { c.StartupTimeout = 0 }, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := defaults(t).GRPC
+			test.modify(&cfg)
+
+			err := cfg.ValidateClient()
+			require.Equal(t, test.expectedErr, err != nil, err)
+		})
+	}
+}
+
+func Test_HTTP_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(c *HTTP)
+		expectedErr bool
+	}{
+		{name: "defaults", modify: func(c *HTTP) {}},
+		{name: "invalid server port", modify: func(c *HTTP) { c.ServerPort = 0 }, expectedErr: true},
+		{name: "invalid admin port", modify: func(c *HTTP) { c.AdminPort = 70000 }, expectedErr: true},
+		{name: "admin port same as the server port", modify: func(c *HTTP) { c.AdminPort = c.ServerPort },
+			expectedErr: true},
+		{name: "negative idle timeout", modify: func(c *HTTP) { c.IdleTimeout = -time.Second }, expectedErr: true},
+		{name: "negative resolve timeout", modify: func(c *HTTP) { c.ResolveTimeout = -time.Second },
+			expectedErr: true},
+		{name: "trusted proxies", modify: func(c *HTTP) {
+			c.TrustedProxies = []string{"10.0.0.0/8", "::ffff:192.168.0.1"}
+		}},
+		{name: "invalid trusted proxy network", modify: func(c *HTTP) { c.TrustedProxies = []string{"10.0.0.0/33"} },
+			expectedErr: true},
+		{name: "invalid trusted proxy IP", modify: func(c *HTTP) { c.TrustedProxies = []string{"10.0.0"} },
+			expectedErr: true},
+		{name: "negative resolve cache ttl", modify: func(c *HTTP) { c.ResolveCacheTTL = -time.Second },
+			expectedErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := defaults(t).HTTP
+			test.modify(&cfg)
+
+			err := cfg.Validate()
+			require.Equal(t, test.expectedErr, err != nil, err)
+		})
+	}
+}
+
+func Test_HTTP_TrustedProxyNetworks(t *testing.T) {
+	cfg := HTTP{TrustedProxies: []string{"10.0.0.0/8", "::ffff:192.168.0.1"}}
+
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.0.1/32")},
+		cfg.TrustedProxyNetworks())
+}
+
+func Test_Importer_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(c *Importer)
+		expectedErr bool
+	}{
+		{name: "defaults lack a dump file", modify: func(c *Importer) {}, expectedErr: true},
+		{name: "valid", modify: func(c *Importer) { c.DumpFile = "data_dump.csv" }},
+		{name: "invalid mode", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.Mode = "sometimes"
+		}, expectedErr: true},
+		{name: "directory mode requires a watch dir", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.Mode = ImporterModeDirectory
+		}, expectedErr: true},
+		{name: "directory mode doesn't require a dump file", modify: func(c *Importer) {
+			c.Mode = ImporterModeDirectory
+			c.WatchDir = "/data/drop"
+		}},
+		{name: "dry runs validate a single dump file", modify: func(c *Importer) {
+			c.Mode = ImporterModeDirectory
+			c.WatchDir = "/data/drop"
+			c.DryRun = true
+		}, expectedErr: true},
+		{name: "dry run", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.DryRun = true
+		}},
+		{name: "dry runs only validate location dump files", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.DryRun = true
+			c.Dataset = models.DatasetKindASN
+		}, expectedErr: true},
+		{name: "asn dataset", modify: func(c *Importer) {
+			c.DumpFile = "asn_dump.csv"
+			c.Dataset = models.DatasetKindASN
+		}},
+		{name: "invalid dataset", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.Dataset = "weather"
+		}, expectedErr: true},
+		{name: "no routines", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.TotalRoutines = 0
+		}, expectedErr: true},
+		{name: "no batch size", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.BatchSize = 0
+		}, expectedErr: true},
+		{name: "max invalid percent above 100", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.MaxInvalidPercent = 101
+		}, expectedErr: true},
+		{name: "negative min accepted lines", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.MinAcceptedLines = -1
+		}, expectedErr: true},
+		{name: "negative max delta percent", modify: func(c *Importer) {
+			c.DumpFile = "data_dump.csv"
+			c.MaxDeltaPercent = -1
+		}, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := defaults(t).Importer
+			test.modify(&cfg)
+
+			err := cfg.Validate()
+			require.Equal(t, test.expectedErr, err != nil, err)
+		})
+	}
+}
+
+func Test_String(t *testing.T) {
+	cfg, err := Load("test", []string{"-db-user", "root", "-db-pass", "s3cr3t", "-admin-token", "t0k3n"})
+	require.NoError(t, err)
+
+	s := cfg.String()
+
+	require.Contains(t, s, "db.user: root\n")
+	require.Contains(t, s, "db.pass: [REDACTED]\n")
+	require.Contains(t, s, "grpc.admin_token: [REDACTED]\n")
+	require.NotContains(t, s, "s3cr3t")
+	require.NotContains(t, s, "t0k3n")
+}
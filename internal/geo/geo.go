@@ -0,0 +1,103 @@
+// Package geo has the geometry of coordinates on the surface of the Earth, used by the spatial queries of locations.
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean radius of the Earth, in km.
+const EarthRadiusKm = 6371.0088
+
+// kmPerDegree is the length of a degree of latitude, in km.
+const kmPerDegree = 2 * math.Pi * EarthRadiusKm / 360
+
+// Point is a pair of coordinates, in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Valid tells if the coordinates are within their ranges: -90 to 90 for the latitude, -180 to 180 for the longitude.
+func (p Point) Valid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+}
+
+// Box is the area between two latitudes and two longitudes, in degrees. A box crossing the antimeridian has a
+// MinLongitude greater than its MaxLongitude, e.g. 170 to -170.
+type Box struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// Valid tells if the corners of the box are valid points, the minimum latitude not above the maximum one.
+func (b Box) Valid() bool {
+	return Point{b.MinLatitude, b.MinLongitude}.Valid() && Point{b.MaxLatitude, b.MaxLongitude}.Valid() &&
+		b.MinLatitude <= b.MaxLatitude
+}
+
+// Contains tells if p is inside the box, or on its edges.
+func (b Box) Contains(p Point) bool {
+	if p.Latitude < b.MinLatitude || p.Latitude > b.MaxLatitude {
+		return false
+	}
+
+	if b.MinLongitude > b.MaxLongitude {
+		return p.Longitude >= b.MinLongitude || p.Longitude <= b.MaxLongitude
+	}
+
+	return p.Longitude >= b.MinLongitude && p.Longitude <= b.MaxLongitude
+}
+
+// Distance is the great-circle distance between two points, in km, by the Haversine formula.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// BoxAround returns a box containing every point within radiusKm of center. Boxes reaching a pole span all
+// longitudes.
+func BoxAround(center Point, radiusKm float64) Box {
+	dLat := radiusKm / kmPerDegree
+
+	box := Box{
+		MinLatitude:  math.Max(-90, center.Latitude-dLat),
+		MaxLatitude:  math.Min(90, center.Latitude+dLat),
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+
+	if box.MinLatitude == -90 || box.MaxLatitude == 90 {
+		return box
+	}
+
+	// Parallels are shorter away from the equator, so a degree of longitude is shorter than one of latitude. The
+	// box is as wide as the circle at its widest parallel
+	dLon := math.Asin(math.Min(1, math.Sin(radians(dLat))/math.Cos(radians(center.Latitude)))) * 180 / math.Pi
+
+	// Boxes crossing the antimeridian end up with a minimum longitude greater than the maximum one
+	box.MinLongitude = wrapLongitude(center.Longitude - dLon)
+	box.MaxLongitude = wrapLongitude(center.Longitude + dLon)
+
+	return box
+}
+
+// wrapLongitude brings a longitude beyond the antimeridian back to the -180 to 180 range.
+func wrapLongitude(longitude float64) float64 {
+	switch {
+	case longitude < -180:
+		return longitude + 360
+	case longitude > 180:
+		return longitude - 360
+	default:
+		return longitude
+	}
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
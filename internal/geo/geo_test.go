@@ -0,0 +1,130 @@
+//go:build !integration
+
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	lisbon    = Point{Latitude: 38.7223, Longitude: -9.1393}
+	porto     = Point{Latitude: 41.1579, Longitude: -8.6291}
+	saoPaulo  = Point{Latitude: -23.5505, Longitude: -46.6333}
+	suva      = Point{Latitude: -18.1416, Longitude: 178.4419}
+	northPole = Point{Latitude: 90}
+)
+
+func Test_Distance(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     Point
+		expected float64
+	}{
+		{name: "same point", a: lisbon, b: lisbon, expected: 0},
+		{name: "nearby cities", a: lisbon, b: porto, expected: 274},
+		{name: "across the ocean", a: lisbon, b: saoPaulo, expected: 7949},
+		{name: "pole to equator", a: northPole, b: Point{}, expected: 10008},
+		{name: "antipodes", a: Point{}, b: Point{Longitude: 180}, expected: 20015},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.InDelta(t, test.expected, Distance(test.a, test.b), 1)
+			assert.InDelta(t, test.expected, Distance(test.b, test.a), 1)
+		})
+	}
+}
+
+func Test_BoxAround(t *testing.T) {
+	tests := []struct {
+		name     string
+		center   Point
+		radiusKm float64
+		expected Box
+	}{
+		{
+			name:     "equator",
+			center:   Point{},
+			radiusKm: 111.195,
+			expected: Box{MinLatitude: -1, MaxLatitude: 1, MinLongitude: -1, MaxLongitude: 1},
+		},
+		{
+			name:     "crossing the antimeridian",
+			center:   Point{Longitude: 179.5},
+			radiusKm: 111.195,
+			expected: Box{MinLatitude: -1, MaxLatitude: 1, MinLongitude: 178.5, MaxLongitude: -179.5},
+		},
+		{
+			name:     "reaching a pole",
+			center:   Point{Latitude: 89.5},
+			radiusKm: 111.195,
+			expected: Box{MinLatitude: 88.5, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			box := BoxAround(test.center, test.radiusKm)
+
+			assert.InDelta(t, test.expected.MinLatitude, box.MinLatitude, 0.001)
+			assert.InDelta(t, test.expected.MaxLatitude, box.MaxLatitude, 0.001)
+			assert.InDelta(t, test.expected.MinLongitude, box.MinLongitude, 0.001)
+			assert.InDelta(t, test.expected.MaxLongitude, box.MaxLongitude, 0.001)
+		})
+	}
+}
+
+func Test_BoxAround_containsCircle(t *testing.T) {
+	// Points at the radius in every direction, away from the equator, are inside the box
+	center := Point{Latitude: 60, Longitude: 10}
+	box := BoxAround(center, 500)
+
+	for lat := 50.0; lat <= 70; lat += 0.25 {
+		for lon := -20.0; lon <= 40; lon += 0.25 {
+			p := Point{Latitude: lat, Longitude: lon}
+			if Distance(center, p) <= 500 {
+				assert.True(t, box.Contains(p), "%v is within 500 km of %v", p, center)
+			}
+		}
+	}
+}
+
+func Test_Box_Contains(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      Box
+		point    Point
+		expected bool
+	}{
+		{name: "inside", box: Box{-10, 10, -10, 10}, point: Point{5, 5}, expected: true},
+		{name: "on the edge", box: Box{-10, 10, -10, 10}, point: Point{10, -10}, expected: true},
+		{name: "outside", box: Box{-10, 10, -10, 10}, point: Point{5, 11}, expected: false},
+		{name: "across the antimeridian", box: Box{-20, -10, 170, -170}, point: suva, expected: true},
+		{name: "outside across the antimeridian", box: Box{-20, -10, 170, -170}, point: Point{-15, 0}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.box.Contains(test.point))
+		})
+	}
+}
+
+func Test_Box_Valid(t *testing.T) {
+	assert.True(t, Box{-90, 90, -180, 180}.Valid())
+	assert.True(t, Box{-20, -10, 170, -170}.Valid())
+	assert.False(t, Box{10, -10, 0, 1}.Valid())
+	assert.False(t, Box{-91, 0, 0, 1}.Valid())
+	assert.False(t, Box{0, 1, 0, 181}.Valid())
+}
@@ -0,0 +1,146 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// GeohashPrecision is the number of characters of the geohashes stored with locations, cells of a few centimeters.
+const GeohashPrecision = 12
+
+// geohashAlphabet is the base 32 alphabet of geohashes, sorted so that prefixes of a geohash sort before it.
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes p with the given number of characters. Each character splits the cell of the previous ones in 32,
+// alternating the bits between the longitude and the latitude, so points sharing a prefix are in the same cell.
+func Geohash(p Point, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	hash := make([]byte, 0, precision)
+	ch, bit, even := 0, 0, true
+	for len(hash) < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if p.Longitude >= mid {
+				ch = ch<<1 | 1
+				minLon = mid
+			} else {
+				ch <<= 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if p.Latitude >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			ch, bit = 0, 0
+		}
+	}
+
+	return string(hash)
+}
+
+// CoveringGeohashes returns the geohashes of the cells covering b, at the highest precision needing no more than
+// maxCells cells, sorted. A single empty geohash, covering the whole Earth, is returned if not even the cells of a
+// single character fit in maxCells.
+func CoveringGeohashes(b Box, maxCells int) []string {
+	boxes := []Box{b}
+	if b.MinLongitude > b.MaxLongitude {
+		// Covering each side of the antimeridian separately
+		boxes = []Box{
+			{MinLatitude: b.MinLatitude, MaxLatitude: b.MaxLatitude, MinLongitude: b.MinLongitude, MaxLongitude: 180},
+			{MinLatitude: b.MinLatitude, MaxLatitude: b.MaxLatitude, MinLongitude: -180, MaxLongitude: b.MaxLongitude},
+		}
+	}
+
+	// More characters always need more cells, so the highest precision is the last one fitting
+	precision := 0
+	for p := 1; p <= GeohashPrecision; p++ {
+		cells := 0
+		for _, box := range boxes {
+			cells += newCellRange(box, p).count()
+		}
+
+		if cells > maxCells {
+			break
+		}
+		precision = p
+	}
+
+	if precision == 0 {
+		return []string{""}
+	}
+
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, box := range boxes {
+		r := newCellRange(box, precision)
+		for lat := r.minLat; lat <= r.maxLat; lat++ {
+			for lon := r.minLon; lon <= r.maxLon; lon++ {
+				// The center of the cell is well inside it, away from any rounding on its edges
+				center := Point{
+					Latitude:  -90 + (float64(lat)+0.5)*r.height,
+					Longitude: -180 + (float64(lon)+0.5)*r.width,
+				}
+
+				if hash := Geohash(center, precision); !seen[hash] {
+					seen[hash] = true
+					hashes = append(hashes, hash)
+				}
+			}
+		}
+	}
+
+	sort.Strings(hashes)
+
+	return hashes
+}
+
+// cellRange is the grid of geohash cells of a precision overlapping a box, by their row and column in the grid of all
+// the cells of the Earth.
+type cellRange struct {
+	width, height                  float64
+	minLat, maxLat, minLon, maxLon int
+}
+
+func newCellRange(b Box, precision int) cellRange {
+	// The bits of the geohash alternate between the longitude and the latitude, starting with the longitude
+	bits := 5 * precision
+	lonCells := 1 << ((bits + 1) / 2)
+	latCells := 1 << (bits / 2)
+
+	r := cellRange{width: 360 / float64(lonCells), height: 180 / float64(latCells)}
+	r.minLat = cellIndex(b.MinLatitude+90, r.height, latCells)
+	r.maxLat = cellIndex(b.MaxLatitude+90, r.height, latCells)
+	r.minLon = cellIndex(b.MinLongitude+180, r.width, lonCells)
+	r.maxLon = cellIndex(b.MaxLongitude+180, r.width, lonCells)
+
+	return r
+}
+
+func (r cellRange) count() int {
+	return (r.maxLat - r.minLat + 1) * (r.maxLon - r.minLon + 1)
+}
+
+// cellIndex returns the cell of size containing offset, the last of n cells containing the end of the range.
+func cellIndex(offset, size float64, n int) int {
+	i := int(math.Floor(offset / size))
+	switch {
+	case i < 0:
+		return 0
+	case i >= n:
+		return n - 1
+	default:
+		return i
+	}
+}
@@ -0,0 +1,106 @@
+//go:build !integration
+
+package geo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Geohash(t *testing.T) {
+	tests := []struct {
+		name      string
+		point     Point
+		precision int
+		expected  string
+	}{
+		{
+			name:      "full precision",
+			point:     Point{Latitude: 57.64911, Longitude: 10.40744},
+			precision: 11,
+			expected:  "u4pruydqqvj",
+		},
+		{name: "origin", point: Point{}, precision: 4, expected: "s000"},
+		{name: "north pole", point: northPole, precision: 2, expected: "up"},
+		{name: "south west corner", point: Point{Latitude: -90, Longitude: -180}, precision: 3, expected: "000"},
+		{name: "no precision", point: lisbon, precision: 0, expected: ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, Geohash(test.point, test.precision))
+		})
+	}
+}
+
+func Test_CoveringGeohashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		box      Box
+		maxCells int
+		expected []string
+	}{
+		{
+			name:     "single cell",
+			box:      Box{MinLatitude: 0.1, MaxLatitude: 0.2, MinLongitude: 0.1, MaxLongitude: 0.2},
+			maxCells: 1,
+			expected: []string{"s00"},
+		},
+		{
+			name:     "cells around the origin",
+			box:      Box{MinLatitude: -1, MaxLatitude: 1, MinLongitude: -1, MaxLongitude: 1},
+			maxCells: 4,
+			expected: []string{"7zz", "ebp", "kpb", "s00"},
+		},
+		{
+			name:     "both sides of the antimeridian",
+			box:      Box{MinLatitude: 1, MaxLatitude: 2, MinLongitude: 179, MaxLongitude: -179},
+			maxCells: 2,
+			expected: []string{"80", "xb"},
+		},
+		{
+			name:     "whole earth",
+			box:      Box{MinLatitude: -90, MaxLatitude: 90, MinLongitude: -180, MaxLongitude: 180},
+			maxCells: 16,
+			expected: []string{""},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, CoveringGeohashes(test.box, test.maxCells))
+		})
+	}
+}
+
+func Test_CoveringGeohashes_coversBox(t *testing.T) {
+	// Every point of the box has the prefix of one of the cells
+	box := BoxAround(lisbon, 50)
+	hashes := CoveringGeohashes(box, 32)
+	require.LessOrEqual(t, len(hashes), 32)
+
+	for lat := box.MinLatitude; lat <= box.MaxLatitude; lat += 0.01 {
+		for lon := box.MinLongitude; lon <= box.MaxLongitude; lon += 0.01 {
+			hash := Geohash(Point{Latitude: lat, Longitude: lon}, GeohashPrecision)
+
+			covered := false
+			for _, prefix := range hashes {
+				if strings.HasPrefix(hash, prefix) {
+					covered = true
+					break
+				}
+			}
+
+			assert.True(t, covered, "%s isn't covered by %v", hash, hashes)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrValidationInvalidNetwork        = errors.New("invalid network")
+	ErrValidationInvalidASN            = errors.New("invalid autonomous system number")
+	ErrValidationInvalidOrganization   = errors.New("invalid organization")
+	ErrValidationInvalidConnectionType = errors.New("invalid connection type")
+)
+
+// Connection types of an ASNBlock, telling how the IPs of the network connect to the internet.
+const (
+	ConnectionTypeHosting     = "hosting"
+	ConnectionTypeResidential = "residential"
+	ConnectionTypeMobile      = "mobile"
+)
+
+// ASNBlock is a network of IPs and the autonomous system it belongs to.
+type ASNBlock struct {
+	// Network is the range of IPs, in CIDR notation
+	Network      string `csv:"network" json:"network"`
+	ASN          uint32 `csv:"asn" json:"asn"`
+	Organization string `csv:"organization" json:"organization"`
+	// ConnectionType is one of the ConnectionType* values, or empty if unknown
+	ConnectionType string `csv:"connection_type" json:"connection_type,omitempty"`
+}
+
+func (b ASNBlock) Validate() error {
+	if _, err := ParseNetwork(b.Network); err != nil {
+		return err
+	}
+
+	// AS 0 is reserved, and never routed
+	if b.ASN == 0 {
+		return ErrValidationInvalidASN
+	}
+
+	if strings.TrimSpace(b.Organization) == "" {
+		return ErrValidationInvalidOrganization
+	}
+
+	switch b.ConnectionType {
+	case "", ConnectionTypeHosting, ConnectionTypeResidential, ConnectionTypeMobile:
+		return nil
+	default:
+		return ErrValidationInvalidConnectionType
+	}
+}
@@ -0,0 +1,52 @@
+//go:build !integration
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ASNBlock_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       ASNBlock
+		expectedErr error
+	}{
+		{
+			name:  "success",
+			input: ASNBlock{Network: "192.168.0.0/16", ASN: 64496, Organization: "Unit Tests"},
+		},
+		{
+			name:        "network without prefix length should return error",
+			input:       ASNBlock{Network: "192.168.0.1", ASN: 64496, Organization: "Unit Tests"},
+			expectedErr: ErrValidationInvalidNetwork,
+		},
+		{
+			name:        "reserved ASN should return error",
+			input:       ASNBlock{Network: "192.168.0.0/16", Organization: "Unit Tests"},
+			expectedErr: ErrValidationInvalidASN,
+		},
+		{
+			name:        "missing organization should return error",
+			input:       ASNBlock{Network: "192.168.0.0/16", ASN: 64496},
+			expectedErr: ErrValidationInvalidOrganization,
+		},
+		{
+			name: "unknown connection type should return error",
+			input: ASNBlock{Network: "192.168.0.0/16", ASN: 64496, Organization: "Unit Tests",
+				ConnectionType: "satellite"},
+			expectedErr: ErrValidationInvalidConnectionType,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expectedErr, test.input.Validate())
+		})
+	}
+}
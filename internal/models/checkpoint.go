@@ -0,0 +1,32 @@
+package models
+
+// Checkpoint records a batch of consecutive lines of a dump file that was committed to a dataset. Batches are
+// numbered from 0, each covering BatchSize lines (the last one can be shorter), so their boundaries are the same
+// every time a file is imported with the same batch size.
+type Checkpoint struct {
+	DatasetID int64
+	Batch     int64
+	BatchSize int
+	// EndLine is the number of the last line of the batch, not counting the header (the first line is 1)
+	EndLine uint64
+	// EndOffset is the byte offset of the file right after the last line of the batch
+	EndOffset int64
+	Lines     LineCounts
+	// Errors is the number of invalid lines of the batch per reason
+	Errors map[string]uint64
+}
+
+// AddDuplicates counts n of the accepted lines of the batch as invalid, since their IPs were already imported.
+func (c *Checkpoint) AddDuplicates(n uint64) {
+	if n == 0 {
+		return
+	}
+
+	if c.Errors == nil {
+		c.Errors = make(map[string]uint64)
+	}
+
+	c.Lines.Accepted -= n
+	c.Lines.Invalid += n
+	c.Errors[ErrorReasonDuplicateIP] += n
+}
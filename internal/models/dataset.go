@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Dataset statuses. A dataset is staging while its file is imported, then either becomes active (replacing the
+// previously active dataset, which is retired) or failed.
+const (
+	DatasetStatusStaging = "staging"
+	DatasetStatusActive  = "active"
+	DatasetStatusRetired = "retired"
+	DatasetStatusFailed  = "failed"
+)
+
+// Dataset kinds, by the data of their dump files. Each kind has its own active dataset.
+const (
+	// DatasetKindLocation datasets hold the location of IPs (see Geolocation)
+	DatasetKindLocation = "location"
+	// DatasetKindASN datasets hold the owners of IP networks (see ASNBlock)
+	DatasetKindASN = "asn"
+)
+
+// IsDatasetKind tells if kind is one of the DatasetKind* values.
+func IsDatasetKind(kind string) bool {
+	return kind == DatasetKindLocation || kind == DatasetKindASN
+}
+
+// Dataset is a version of the geolocation or ASN data, created by importing a dump file.
+type Dataset struct {
+	ID          int64
+	Kind        string
+	SourceFile  string
+	Checksum    string
+	Status      string
+	Lines       LineCounts
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	ActivatedAt *time.Time
+	RetiredAt   *time.Time
+}
+
+// LineCounts summarizes how the lines of a dump file were processed.
+type LineCounts struct {
+	Total    uint64 `json:"total"`
+	Accepted uint64 `json:"accepted"`
+	Invalid  uint64 `json:"invalid"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import "time"
+
+// Import statuses
+const (
+	ImportStatusRunning   = "running"
+	ImportStatusSucceeded = "succeeded"
+	ImportStatusFailed    = "failed"
+	ImportStatusCancelled = "cancelled"
+)
+
+// Reasons for a line to be invalid, used as keys of Import.Errors. Lines repeating an IP that isn't imported
+// from them are duplicate_ip, or conflicting_ip if conflicting lines of an IP are all rejected.
+const (
+	ErrorReasonInvalidCSV         = "invalid_csv"
+	ErrorReasonInvalidIP          = "invalid_ip"
+	ErrorReasonInvalidCountryCode = "invalid_country_code"
+	ErrorReasonInvalidCountry     = "invalid_country"
+	ErrorReasonInvalidCity        = "invalid_city"
+	ErrorReasonInvalidAccuracy    = "invalid_accuracy_radius"
+	ErrorReasonDuplicateIP        = "duplicate_ip"
+	ErrorReasonConflictingIP      = "conflicting_ip"
+	ErrorReasonPersistence        = "persistence"
+
+	// Reasons for a line of an ASN dump file to be invalid. Lines repeating a network are duplicate_ip
+	ErrorReasonInvalidNetwork        = "invalid_network"
+	ErrorReasonInvalidASN            = "invalid_asn"
+	ErrorReasonInvalidOrganization   = "invalid_organization"
+	ErrorReasonInvalidConnectionType = "invalid_connection_type"
+)
+
+// Import is a run of the importer over a dump file, and its progress.
+type Import struct {
+	ID         int64             `json:"id"`
+	DatasetID  int64             `json:"dataset_id,omitempty"`
+	SourceFile string            `json:"source_file"`
+	FileSize   int64             `json:"file_size"`
+	BytesRead  int64             `json:"bytes_read"`
+	Status     string            `json:"status"`
+	Lines      LineCounts        `json:"lines"`
+	Errors     map[string]uint64 `json:"errors,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	// Conflicts are the first IPs found on several lines with conflicting data, out of TotalConflicts
+	Conflicts      []Conflict `json:"conflicts,omitempty"`
+	TotalConflicts uint64     `json:"total_conflicts,omitempty"`
+	// ReadStartedAt is when the file started being read in batches, once it was checksummed and scanned for
+	// duplicates. Resumed imports start reading from ResumedBytes, with ResumedLines already imported
+	ReadStartedAt *time.Time `json:"read_started_at,omitempty"`
+	ResumedBytes  int64      `json:"resumed_bytes,omitempty"`
+	ResumedLines  uint64     `json:"resumed_lines,omitempty"`
+}
+
+// Conflict is an IP found on several lines of a dump file with different data.
+type Conflict struct {
+	IpAddress string `json:"ip_address"`
+	// Lines are the numbers of the first lines with the IP, not counting the header (the first line is 1)
+	Lines []uint64 `json:"lines"`
+	// KeptLine is the number of the line that was imported, 0 if none was
+	KeptLine uint64 `json:"kept_line,omitempty"`
+}
+
+// Elapsed is how long the import ran for, or has been running for if it's still running.
+func (i Import) Elapsed(now time.Time) time.Duration {
+	if i.FinishedAt != nil {
+		return i.FinishedAt.Sub(i.StartedAt)
+	}
+
+	return now.Sub(i.StartedAt)
+}
+
+// readElapsed is how long the file has been read in batches, or was if the import is finished. Imports recorded
+// before that was tracked are timed from their start.
+func (i Import) readElapsed(now time.Time) time.Duration {
+	started := i.StartedAt
+	if i.ReadStartedAt != nil {
+		started = *i.ReadStartedAt
+	}
+
+	if i.FinishedAt != nil {
+		return i.FinishedAt.Sub(started)
+	}
+
+	return now.Sub(started)
+}
+
+// Throughput is the number of lines processed per second, not counting the lines a resumed import skipped.
+func (i Import) Throughput(now time.Time) float64 {
+	elapsed := i.readElapsed(now).Seconds()
+	if elapsed <= 0 || i.Lines.Total < i.ResumedLines {
+		return 0
+	}
+
+	return float64(i.Lines.Total-i.ResumedLines) / elapsed
+}
+
+// ETA estimates how long a running import will take to finish, based on how fast the file has been read so far.
+// It returns false if there's no estimate (the import isn't running or nothing was read yet).
+func (i Import) ETA(now time.Time) (time.Duration, bool) {
+	elapsed := i.readElapsed(now)
+	read := i.BytesRead - i.ResumedBytes
+	if i.Status != ImportStatusRunning || read <= 0 || elapsed <= 0 {
+		return 0, false
+	}
+
+	remaining := i.FileSize - i.BytesRead
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	bytesPerSecond := float64(read) / elapsed.Seconds()
+
+	return time.Duration(float64(remaining) / bytesPerSecond * float64(time.Second)), true
+}
@@ -0,0 +1,83 @@
+//go:build !integration
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Import_progress(t *testing.T) {
+	startedAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	readStartedAt := startedAt.Add(20 * time.Second)
+	now := readStartedAt.Add(10 * time.Second)
+
+	tests := []struct {
+		name               string
+		imp                Import
+		expectedThroughput float64
+		expectedETA        time.Duration
+		expectedHasETA     bool
+	}{
+		{
+			name: "timed from the start of imports without a read start",
+			imp: Import{
+				Status: ImportStatusRunning, FileSize: 400, BytesRead: 300, Lines: LineCounts{Total: 150},
+				StartedAt: readStartedAt,
+			},
+			expectedThroughput: 15,
+			expectedETA:        10 * time.Second / 3,
+			expectedHasETA:     true,
+		},
+		{
+			name: "the checksum and duplicates prepass isn't timed",
+			imp: Import{
+				Status: ImportStatusRunning, FileSize: 400, BytesRead: 100, Lines: LineCounts{Total: 50},
+				StartedAt: startedAt, ReadStartedAt: &readStartedAt,
+			},
+			expectedThroughput: 5,
+			expectedETA:        30 * time.Second,
+			expectedHasETA:     true,
+		},
+		{
+			name: "resumed imports only count what they read",
+			imp: Import{
+				Status: ImportStatusRunning, FileSize: 400, BytesRead: 300, Lines: LineCounts{Total: 150},
+				StartedAt: startedAt, ReadStartedAt: &readStartedAt, ResumedBytes: 200, ResumedLines: 100,
+			},
+			expectedThroughput: 5,
+			expectedETA:        10 * time.Second,
+			expectedHasETA:     true,
+		},
+		{
+			name: "nothing read since resuming",
+			imp: Import{
+				Status: ImportStatusRunning, FileSize: 400, BytesRead: 200, Lines: LineCounts{Total: 100},
+				StartedAt: startedAt, ReadStartedAt: &readStartedAt, ResumedBytes: 200, ResumedLines: 100,
+			},
+		},
+		{
+			name: "no estimate for finished imports",
+			imp: Import{
+				Status: ImportStatusSucceeded, FileSize: 400, BytesRead: 400, Lines: LineCounts{Total: 200},
+				StartedAt: startedAt, ReadStartedAt: &readStartedAt, FinishedAt: &now,
+			},
+			expectedThroughput: 20,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expectedThroughput, test.imp.Throughput(now))
+
+			eta, ok := test.imp.ETA(now)
+			assert.Equal(t, test.expectedHasETA, ok)
+			assert.Equal(t, test.expectedETA, eta)
+		})
+	}
+}
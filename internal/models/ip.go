@@ -0,0 +1,38 @@
+package models
+
+import (
+	"net/netip"
+)
+
+// ParseIP parses an IPv4 or IPv6 address into its canonical form, the one stored and looked up: IPv4-mapped IPv6
+// addresses (::ffff:1.2.3.4) are unmapped to their IPv4 address, and IPv6 addresses are compared by value, however
+// they're written. Addresses with a zone (fe80::1%eth0) are rejected, as zones only make sense on the host.
+func ParseIP(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, ErrValidationInvalidIP
+	}
+
+	return addr.Unmap(), nil
+}
+
+// ParseNetwork parses a network in CIDR notation into its canonical form, the one stored and looked up: networks of
+// IPv4-mapped IPv6 addresses (::ffff:1.2.3.0/120) are unmapped to their IPv4 network, and host bits are cleared
+// (1.2.3.4/24 is 1.2.3.0/24).
+func ParseNetwork(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, ErrValidationInvalidNetwork
+	}
+
+	if addr := prefix.Addr(); addr.Is4In6() {
+		// The first 96 bits are the ::ffff: prefix, so shorter networks mix IPv4 and IPv6 addresses
+		if prefix.Bits() < 96 {
+			return netip.Prefix{}, ErrValidationInvalidNetwork
+		}
+
+		prefix = netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+	}
+
+	return prefix.Masked(), nil
+}
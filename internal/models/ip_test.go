@@ -0,0 +1,87 @@
+//go:build !integration
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseIP(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr error
+	}{
+		{name: "IPv4", input: "1.2.3.4", expected: "1.2.3.4"},
+		{name: "unspecified IPv4", input: "0.0.0.0", expected: "0.0.0.0"},
+		{name: "IPv4-mapped IPv6", input: "::ffff:1.2.3.4", expected: "1.2.3.4"},
+		{name: "IPv4-mapped IPv6 in upper case", input: "::FFFF:1.2.3.4", expected: "1.2.3.4"},
+		{name: "IPv4-mapped IPv6 in hexadecimal", input: "::ffff:102:304", expected: "1.2.3.4"},
+		{name: "IPv6", input: "2001:db8::1", expected: "2001:db8::1"},
+		{name: "IPv6 in upper case", input: "2001:DB8::1", expected: "2001:db8::1"},
+		{name: "IPv6 with leading zeros", input: "2001:0db8:0000::0001", expected: "2001:db8::1"},
+		{name: "IPv6 without compression", input: "2001:db8:0:0:0:0:0:1", expected: "2001:db8::1"},
+		{name: "unspecified IPv6", input: "::", expected: "::"},
+		{name: "IPv4-compatible IPv6 isn't unmapped", input: "::1.2.3.4", expected: "::102:304"},
+		{name: "IPv6 with a zone should return error", input: "fe80::1%eth0", expectedErr: ErrValidationInvalidIP},
+		{name: "IPv4 with leading zeros should return error", input: "01.2.3.4", expectedErr: ErrValidationInvalidIP},
+		{name: "short IPv4 should return error", input: "1.2.3", expectedErr: ErrValidationInvalidIP},
+		{name: "network should return error", input: "1.2.3.4/32", expectedErr: ErrValidationInvalidIP},
+		{name: "spaces should return error", input: " 1.2.3.4", expectedErr: ErrValidationInvalidIP},
+		{name: "empty should return error", input: "", expectedErr: ErrValidationInvalidIP},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ip, err := ParseIP(test.input)
+			assert.Equal(t, test.expectedErr, err)
+			if test.expectedErr == nil {
+				assert.Equal(t, test.expected, ip.String())
+			}
+		})
+	}
+}
+
+func Test_ParseNetwork(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		expectedErr error
+	}{
+		{name: "IPv4", input: "1.2.3.0/24", expected: "1.2.3.0/24"},
+		{name: "IPv4 with host bits", input: "1.2.3.4/24", expected: "1.2.3.0/24"},
+		{name: "IPv4-mapped IPv6", input: "::ffff:1.2.3.0/120", expected: "1.2.3.0/24"},
+		{name: "IPv4-mapped IPv6 host", input: "::FFFF:1.2.3.4/128", expected: "1.2.3.4/32"},
+		{name: "all of IPv4, mapped", input: "::ffff:0.0.0.0/96", expected: "0.0.0.0/0"},
+		{name: "IPv6", input: "2001:DB8::/32", expected: "2001:db8::/32"},
+		{name: "IPv6 with host bits", input: "2001:db8::1/32", expected: "2001:db8::/32"},
+		{
+			name:        "IPv4-mapped IPv6 beyond IPv4 should return error",
+			input:       "::ffff:0.0.0.0/95",
+			expectedErr: ErrValidationInvalidNetwork,
+		},
+		{name: "IPv6 with a zone should return error", input: "fe80::%eth0/64", expectedErr: ErrValidationInvalidNetwork},
+		{name: "prefix too long should return error", input: "1.2.3.0/33", expectedErr: ErrValidationInvalidNetwork},
+		{name: "IP should return error", input: "1.2.3.4", expectedErr: ErrValidationInvalidNetwork},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			network, err := ParseNetwork(test.input)
+			assert.Equal(t, test.expectedErr, err)
+			if test.expectedErr == nil {
+				assert.Equal(t, test.expected, network.String())
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package models
 
 import (
 	"errors"
-	"net"
 	"strings"
 )
 
@@ -11,6 +10,7 @@ var (
 	ErrValidationInvalidCountryCode = errors.New("invalid country code")
 	ErrValidationInvalidCountry     = errors.New("invalid country")
 	ErrValidationInvalidCity        = errors.New("invalid city")
+	ErrValidationInvalidAccuracy    = errors.New("invalid accuracy radius")
 )
 
 type Geolocation struct {
@@ -21,13 +21,36 @@ type Geolocation struct {
 	Latitude     float64 `csv:"latitude" json:"latitude"`
 	Longitude    float64 `csv:"longitude" json:"longitude"`
 	MysteryValue string  `csv:"mystery_value" json:"mystery_value,omitempty"`
+	// Region is the subdivision of the country, e.g. a state or province
+	Region     string `csv:"region" json:"region,omitempty"`
+	PostalCode string `csv:"postal_code" json:"postal_code,omitempty"`
+	// Timezone is an IANA time zone name, e.g. America/Sao_Paulo
+	Timezone string `csv:"timezone" json:"timezone,omitempty"`
+	// AccuracyRadius is how far from the coordinates, in km, the IP can be
+	AccuracyRadius int    `csv:"accuracy_radius" json:"accuracy_radius,omitempty"`
+	Continent      string `csv:"continent" json:"continent,omitempty"`
+	// Attributes are the values of any other columns of the dump file, by column name
+	Attributes map[string]string `csv:"-" json:"attributes,omitempty"`
+}
+
+// LocationFilter selects the locations listed by country code and, optionally, city.
+type LocationFilter struct {
+	CountryCode string
+	// City is ignored if empty
+	City string
+}
+
+// NearbyLocation is a location found by a spatial query.
+type NearbyLocation struct {
+	Geolocation
+	// DistanceKm is how far the location is from the point searched around, 0 on searches by bounding box
+	DistanceKm float64 `json:"distance_km,omitempty"`
 }
 
 func (g Geolocation) Validate() error {
 	// Checking if the IP is valid
-	ip := net.ParseIP(g.IpAddress)
-	if ip == nil {
-		return ErrValidationInvalidIP
+	if _, err := ParseIP(g.IpAddress); err != nil {
+		return err
 	}
 
 	if strings.TrimSpace(g.CountryCode) == "" {
@@ -42,5 +65,9 @@ func (g Geolocation) Validate() error {
 		return ErrValidationInvalidCity
 	}
 
+	if g.AccuracyRadius < 0 {
+		return ErrValidationInvalidAccuracy
+	}
+
 	return nil
 }
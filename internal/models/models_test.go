@@ -65,9 +65,19 @@ func Test_Validate(t *testing.T) {
 			},
 			expectedErr: ErrValidationInvalidCity,
 		},
+		{
+			name: "negative accuracy radius should return error",
+			input: func() *Geolocation {
+				g := completeGeolocation()
+				g.AccuracyRadius = -1
+				return g
+			},
+			expectedErr: ErrValidationInvalidAccuracy,
+		},
 	}
 
 	for _, test := range tests {
+		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
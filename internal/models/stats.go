@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Stats summarizes the current locations.
+type Stats struct {
+	// DatasetID is the version of the locations, the ID of the active location dataset (0 if there's none), which
+	// was imported from SourceFile and activated at ActivatedAt
+	DatasetID   int64      `json:"dataset_id"`
+	SourceFile  string     `json:"source_file,omitempty"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+
+	TotalLocations uint64 `json:"total_locations"`
+	IPv4Locations  uint64 `json:"ipv4_locations"`
+	IPv6Locations  uint64 `json:"ipv6_locations"`
+
+	// Countries are sorted from the most to the least locations
+	Countries []CountryStats `json:"countries"`
+	// Prefixes are the /8 prefixes of the IPv4 locations, sorted
+	Prefixes []PrefixStats `json:"prefixes"`
+}
+
+// CountryStats is the number of locations of a country, and of its top cities.
+type CountryStats struct {
+	CountryCode string `json:"country_code"`
+	Country     string `json:"country"`
+	Locations   uint64 `json:"locations"`
+	// TopCities are the cities with the most locations, sorted from the most to the least
+	TopCities []CityStats `json:"top_cities"`
+}
+
+// CityStats is the number of locations of a city.
+type CityStats struct {
+	City      string `json:"city"`
+	Locations uint64 `json:"locations"`
+}
+
+// PrefixStats is the number of locations of a network prefix, in CIDR notation.
+type PrefixStats struct {
+	Prefix    string `json:"prefix"`
+	Locations uint64 `json:"locations"`
+}
@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// geolocationColumns are the columns of a dump file mapped to fields of models.Geolocation, by their csv tags.
+var geolocationColumns = func() map[string]bool {
+	columns := make(map[string]bool)
+
+	t := reflect.TypeOf(models.Geolocation{})
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("csv"); tag != "" && tag != "-" {
+			columns[tag] = true
+		}
+	}
+
+	return columns
+}()
+
+// attributeColumn is a column of a dump file that isn't mapped to a field of models.Geolocation, stored as an
+// attribute instead.
+type attributeColumn struct {
+	index int
+	name  string
+}
+
+// attributeColumnsByHeader caches the attribute columns of each header, since every line is parsed along with it.
+var attributeColumnsByHeader sync.Map
+
+// attributeColumns returns the attribute columns of a header.
+func attributeColumns(header string) ([]attributeColumn, error) {
+	if columns, ok := attributeColumnsByHeader.Load(header); ok {
+		return columns.([]attributeColumn), nil
+	}
+
+	names, err := csv.NewReader(strings.NewReader(header)).Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []attributeColumn
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !geolocationColumns[name] {
+			columns = append(columns, attributeColumn{index: i, name: name})
+		}
+	}
+
+	attributeColumnsByHeader.Store(header, columns)
+
+	return columns, nil
+}
+
+// lineAttributes returns the values of the attribute columns of a line, leaving out empty ones. It returns nil if
+// there are none.
+func lineAttributes(header, line string) (map[string]string, error) {
+	columns, err := attributeColumns(header)
+	if err != nil || len(columns) == 0 {
+		return nil, err
+	}
+
+	values, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var attributes map[string]string
+	for _, column := range columns {
+		if column.index >= len(values) || strings.TrimSpace(values[column.index]) == "" {
+			continue
+		}
+
+		if attributes == nil {
+			attributes = make(map[string]string, len(columns))
+		}
+		attributes[column.name] = values[column.index]
+	}
+
+	return attributes, nil
+}
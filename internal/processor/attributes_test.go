@@ -0,0 +1,60 @@
+//go:build !integration
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+func Test_csvLineToStruct_attributes(t *testing.T) {
+	tests := []struct {
+		name               string
+		header             string
+		line               string
+		expectedLocation   models.Geolocation
+		expectedAttributes map[string]string
+	}{
+		{
+			name:   "no extra columns",
+			header: "ip_address,country_code,country,city,latitude,longitude,mystery_value",
+			line:   "1.1.1.1,BR,Brazil,Brasilia,-15.7,-47.9,42",
+			expectedLocation: models.Geolocation{IpAddress: "1.1.1.1", CountryCode: "BR", Country: "Brazil",
+				City: "Brasilia", Latitude: -15.7, Longitude: -47.9, MysteryValue: "42"},
+		},
+		{
+			name:   "extra columns are attributes, empty ones left out",
+			header: "asn,ip_address,country_code,country,city,latitude,longitude,isp,mystery_value,domain",
+			line:   `AS123,1.1.1.1,BR,Brazil,Brasilia,-15.7,-47.9,"Unit, Tests",42,`,
+			expectedLocation: models.Geolocation{IpAddress: "1.1.1.1", CountryCode: "BR", Country: "Brazil",
+				City: "Brasilia", Latitude: -15.7, Longitude: -47.9, MysteryValue: "42"},
+			expectedAttributes: map[string]string{"asn": "AS123", "isp": "Unit, Tests"},
+		},
+		{
+			name: "location details are fields, not attributes",
+			header: "ip_address,country_code,country,city,latitude,longitude,mystery_value,region,postal_code,timezone," +
+				"accuracy_radius,continent",
+			line: "1.1.1.1,BR,Brazil,Brasilia,-15.7,-47.9,42,Distrito Federal,70000-000,America/Sao_Paulo,50,SA",
+			expectedLocation: models.Geolocation{IpAddress: "1.1.1.1", CountryCode: "BR", Country: "Brazil",
+				City: "Brasilia", Latitude: -15.7, Longitude: -47.9, MysteryValue: "42", Region: "Distrito Federal",
+				PostalCode: "70000-000", Timezone: "America/Sao_Paulo", AccuracyRadius: 50, Continent: "SA"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			g, err := csvLineToStruct(test.header, test.line)
+			require.NoError(t, err)
+
+			require.Equal(t, test.expectedAttributes, g.Attributes)
+			g.Attributes = nil
+			require.Equal(t, test.expectedLocation, g)
+		})
+	}
+}
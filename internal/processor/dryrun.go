@@ -0,0 +1,213 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// samplesPerReason is how many invalid lines a DryRunReport keeps per reason.
+const samplesPerReason = 5
+
+// DryRunReport describes what importing a dump file would do, without importing it.
+type DryRunReport struct {
+	SourceFile string
+	Lines      models.LineCounts
+	// Errors is the number of invalid lines per reason (one of the models.ErrorReason* values)
+	Errors map[string]uint64
+	// Samples are the first invalid lines per reason
+	Samples map[string][]SampleLine
+	// Countries is the number of valid lines per country code
+	Countries map[string]uint64
+	// Bounds is the bounding box of the coordinates of the valid lines, nil if there are none
+	Bounds *BoundingBox
+	// Conflicts are the first IPs found on several lines with conflicting data, out of TotalConflicts
+	Conflicts      []models.Conflict
+	TotalConflicts uint64
+}
+
+// SampleLine is an invalid line of a dump file.
+type SampleLine struct {
+	// Number is the number of the line, not counting the header (the first line is 1)
+	Number uint64
+	Text   string
+}
+
+// BoundingBox is the smallest area, in degrees, containing a set of coordinates.
+type BoundingBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// extend grows the bounding box to include the given coordinates.
+func (b *BoundingBox) extend(latitude, longitude float64) {
+	if latitude < b.MinLatitude {
+		b.MinLatitude = latitude
+	}
+	if latitude > b.MaxLatitude {
+		b.MaxLatitude = latitude
+	}
+	if longitude < b.MinLongitude {
+		b.MinLongitude = longitude
+	}
+	if longitude > b.MaxLongitude {
+		b.MaxLongitude = longitude
+	}
+}
+
+// DryRun reads and validates dumpFile the same way ExecuteFileImport does, without touching the database.
+// Lines with the same IP are resolved by the duplicate policy, as they would be when importing the file.
+//
+// Only the batch size and duplicate policy options are relevant to a dry run.
+func DryRun(ctx context.Context, dumpFile string, opts ...Option) (*DryRunReport, error) {
+	fp := NewFileProcessor(nil, opts...)
+
+	dups, err := findDuplicates(ctx, dumpFile, fp.duplicatePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("dry run failed: %w", err)
+	}
+
+	report := &DryRunReport{
+		SourceFile:     dumpFile,
+		Errors:         make(map[string]uint64),
+		Samples:        make(map[string][]SampleLine),
+		Countries:      make(map[string]uint64),
+		Conflicts:      dups.conflicts,
+		TotalConflicts: dups.totalConflicts,
+	}
+
+	// Reading the file in the background, while its batches are validated in order
+	batches := make(chan batch)
+	processErr := make(chan error, 1)
+	go func() {
+		processErr <- fp.processFile(ctx, dumpFile, newResumePoint(nil, fp.batchSize), batches)
+	}()
+
+	for b := range batches {
+		for i, line := range b.lines {
+			report.Lines.Total++
+
+			g, reason := parseLine(fp.header, line)
+			if reason == "" {
+				reason = dups.reason(b.firstLine + uint64(i))
+			}
+
+			if reason != "" {
+				report.Lines.Invalid++
+				report.Errors[reason]++
+				if len(report.Samples[reason]) < samplesPerReason {
+					report.Samples[reason] = append(report.Samples[reason],
+						SampleLine{Number: b.firstLine + uint64(i), Text: line})
+				}
+				continue
+			}
+
+			report.Lines.Accepted++
+			report.Countries[g.CountryCode]++
+			if report.Bounds == nil {
+				report.Bounds = &BoundingBox{
+					MinLatitude: g.Latitude, MaxLatitude: g.Latitude,
+					MinLongitude: g.Longitude, MaxLongitude: g.Longitude,
+				}
+			}
+			report.Bounds.extend(g.Latitude, g.Longitude)
+		}
+	}
+
+	if err := <-processErr; err != nil {
+		return nil, fmt.Errorf("dry run failed: %w", err)
+	}
+
+	return report, nil
+}
+
+// Print writes the report to w in a human-readable form.
+func (r *DryRunReport) Print(w io.Writer) error {
+	pw := &printer{w: w}
+
+	pw.printf("Dry run of %s\n", r.SourceFile)
+	pw.printf("Lines: %d total, %d accepted, %d invalid\n", r.Lines.Total, r.Lines.Accepted, r.Lines.Invalid)
+
+	if len(r.Errors) > 0 {
+		pw.printf("\nInvalid lines per reason:\n")
+		for _, reason := range sortedByCount(r.Errors) {
+			pw.printf("  %s: %d\n", reason, r.Errors[reason])
+			for _, sample := range r.Samples[reason] {
+				pw.printf("    line %d: %s\n", sample.Number, sample.Text)
+			}
+		}
+	}
+
+	if len(r.Countries) > 0 {
+		pw.printf("\nValid lines per country:\n")
+		for _, country := range sortedByCount(r.Countries) {
+			pw.printf("  %s: %d\n", country, r.Countries[country])
+		}
+	}
+
+	if r.Bounds != nil {
+		pw.printf("\nBounding box: latitude %g to %g, longitude %g to %g\n", r.Bounds.MinLatitude,
+			r.Bounds.MaxLatitude, r.Bounds.MinLongitude, r.Bounds.MaxLongitude)
+	}
+
+	if r.TotalConflicts > 0 {
+		pw.printf("\nIPs with conflicting data: %d\n", r.TotalConflicts)
+		for _, c := range r.Conflicts {
+			kept := "none"
+			if c.KeptLine > 0 {
+				kept = fmt.Sprintf("line %d", c.KeptLine)
+			}
+
+			pw.printf("  %s: lines %s, kept %s\n", c.IpAddress, joinLines(c.Lines), kept)
+		}
+	}
+
+	return pw.err
+}
+
+// printer writes formatted text, keeping the first error.
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// joinLines formats a list of line numbers.
+func joinLines(lines []uint64) string {
+	formatted := make([]string, 0, len(lines))
+	for _, line := range lines {
+		formatted = append(formatted, strconv.FormatUint(line, 10))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// sortedByCount returns the keys of counts from the highest to the lowest count, ties sorted by key.
+func sortedByCount(counts map[string]uint64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
@@ -0,0 +1,61 @@
+//go:build !integration
+
+package processor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+func Test_DryRun(t *testing.T) {
+	contents := "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+		"1.1.1.1,BR,Brazil,Brasilia,-15.7,-47.9,1\n" +
+		"2001:db8::1,PT,Portugal,Lisbon,38.7,-9.1,1\n" +
+		"2001:DB8:0::1,PT,Portugal,Porto,41.1,-8.6,1\n" +
+		",BR,Brazil,Brasilia,0,0,1\n" +
+		"1.1.1.2,BR,Brazil,Sao Paulo,-23.5,-46.6,1\n" +
+		"1.1.1.3,BR,Brazil\n"
+
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	require.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+	// Small batches, so lines are numbered across batches
+	report, err := DryRun(context.Background(), dumpFile, WithBatchSize(2))
+	require.NoError(t, err)
+
+	assert.Equal(t, models.LineCounts{Total: 6, Accepted: 3, Invalid: 3}, report.Lines)
+	assert.Equal(t, map[string]uint64{
+		models.ErrorReasonDuplicateIP: 1,
+		models.ErrorReasonInvalidIP:   1,
+		models.ErrorReasonInvalidCSV:  1,
+	}, report.Errors)
+	assert.Equal(t, []SampleLine{{Number: 3, Text: "2001:DB8:0::1,PT,Portugal,Porto,41.1,-8.6,1"}},
+		report.Samples[models.ErrorReasonDuplicateIP])
+	assert.Equal(t, []SampleLine{{Number: 6, Text: "1.1.1.3,BR,Brazil"}}, report.Samples[models.ErrorReasonInvalidCSV])
+	assert.Equal(t, map[string]uint64{"BR": 2, "PT": 1}, report.Countries)
+	assert.Equal(t, &BoundingBox{MinLatitude: -23.5, MaxLatitude: 38.7, MinLongitude: -47.9, MaxLongitude: -9.1},
+		report.Bounds)
+
+	assert.Equal(t, []models.Conflict{{IpAddress: "2001:db8::1", Lines: []uint64{2, 3}, KeptLine: 2}}, report.Conflicts)
+	assert.Equal(t, uint64(1), report.TotalConflicts)
+
+	var out bytes.Buffer
+	require.NoError(t, report.Print(&out))
+	assert.Contains(t, out.String(), "Lines: 6 total, 3 accepted, 3 invalid")
+	assert.Contains(t, out.String(), "line 4: ,BR,Brazil,Brasilia,0,0,1")
+	assert.Contains(t, out.String(), "2001:db8::1: lines 2, 3, kept line 2")
+}
+
+func Test_DryRun_missingFile(t *testing.T) {
+	_, err := DryRun(context.Background(), "missing_file.csv")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,407 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// Policies resolving the conflicts between lines of a dump file with the same IP and different data. Lines with
+// the same IP and data are always imported once, from the first of them.
+const (
+	// DuplicatePolicyFirstWins imports the first line of the IP
+	DuplicatePolicyFirstWins = "first-wins"
+	// DuplicatePolicyLastWins imports the last line of the IP
+	DuplicatePolicyLastWins = "last-wins"
+	// DuplicatePolicyMostComplete imports the line of the IP with the most fields set, the first of them on a tie
+	DuplicatePolicyMostComplete = "most-complete"
+	// DuplicatePolicyRejectAll imports none of the lines of the IP
+	DuplicatePolicyRejectAll = "reject-all"
+)
+
+// IsDuplicatePolicy tells if policy is one of the DuplicatePolicy* values.
+func IsDuplicatePolicy(policy string) bool {
+	switch policy {
+	case DuplicatePolicyFirstWins, DuplicatePolicyLastWins, DuplicatePolicyMostComplete, DuplicatePolicyRejectAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateDuplicatePolicy returns an error if policy isn't one of the DuplicatePolicy* values.
+func ValidateDuplicatePolicy(policy string) error {
+	if !IsDuplicatePolicy(policy) {
+		return fmt.Errorf("duplicate policy must be one of %q, %q, %q or %q", DuplicatePolicyFirstWins,
+			DuplicatePolicyLastWins, DuplicatePolicyMostComplete, DuplicatePolicyRejectAll)
+	}
+
+	return nil
+}
+
+const (
+	// partitionBytes is roughly how many bytes of the file are compared in memory at once when looking for
+	// duplicates
+	partitionBytes = 64 << 20
+	// maxConflicts is how many conflicts are listed, out of all the conflicts found
+	maxConflicts = 100
+	// maxConflictLines is how many line numbers are listed per conflict
+	maxConflictLines = 10
+)
+
+// duplicates are the lines of a dump file that aren't imported because their IP is also on other lines.
+type duplicates struct {
+	// skipped are the numbers of the lines that aren't imported, and rejected those among them rejected by
+	// DuplicatePolicyRejectAll
+	skipped  bitset
+	rejected bitset
+
+	// conflicts are the first conflicts in the file, by the number of their first line
+	conflicts      []models.Conflict
+	totalConflicts uint64
+}
+
+// reason returns why a line isn't imported (one of the models.ErrorReason* values), or "" if it's imported.
+// A nil duplicates imports every line.
+func (d *duplicates) reason(line uint64) string {
+	switch {
+	case d == nil || !d.skipped.has(line):
+		return ""
+	case d.rejected.has(line):
+		return models.ErrorReasonConflictingIP
+	default:
+		return models.ErrorReasonDuplicateIP
+	}
+}
+
+// findDuplicates looks for the valid lines of a dump file with the same IP, deciding which of them is imported
+// according to policy.
+//
+// Memory use is bounded regardless of the size of the file: the valid lines are spread over temporary files by IP,
+// each small enough to be compared in memory, and only a bit per line is kept to tell the lines that are skipped.
+func findDuplicates(ctx context.Context, filename, policy string) (*duplicates, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return findDuplicatesIn(ctx, filename, policy, int(info.Size()/partitionBytes)+1)
+}
+
+// findDuplicatesIn is findDuplicates with the lines spread over n partitions.
+func findDuplicatesIn(ctx context.Context, filename, policy string, n int) (*duplicates, error) {
+	dir, err := os.MkdirTemp("", "geolocation-duplicates-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	parts, err := newPartitions(dir, n)
+	if err != nil {
+		return nil, err
+	}
+	defer parts.close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Reading the file in the background, while its valid lines are spread over the partitions in order
+	reader := NewFileProcessor(nil)
+	batches := make(chan batch)
+	processErr := make(chan error, 1)
+	go func() {
+		processErr <- reader.processFile(ctx, filename, newResumePoint(nil, reader.batchSize), batches)
+	}()
+
+	var writeErr error
+	for b := range batches {
+		if writeErr != nil {
+			// Draining the channel, so the file processing isn't blocked
+			continue
+		}
+
+		for i, line := range b.lines {
+			g, reason := parseLine(reader.header, line)
+			if reason != "" {
+				continue
+			}
+
+			if writeErr = parts.add(newDuplicateEntry(b.firstLine+uint64(i), g)); writeErr != nil {
+				cancel()
+				break
+			}
+		}
+	}
+
+	if err := <-processErr; err != nil {
+		return nil, err
+	}
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
+	if err := parts.flush(); err != nil {
+		return nil, err
+	}
+
+	d := &duplicates{}
+	for _, file := range parts.files {
+		if err := d.resolvePartition(file.Name(), policy); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// resolvePartition decides which lines of each IP of a partition are imported.
+func (d *duplicates) resolvePartition(filename, policy string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) { _ = file.Close() }(file)
+
+	// Entries are written in the order of the file, so the lines of each IP are sorted
+	byIP := make(map[string][]duplicateEntry)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		e, err := parseDuplicateEntry(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", filename, err)
+		}
+
+		byIP[e.ip] = append(byIP[e.ip], e)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var conflicts []models.Conflict
+	for ip, entries := range byIP {
+		if len(entries) == 1 {
+			continue
+		}
+
+		kept, conflict := resolveDuplicates(entries, policy)
+		for i, e := range entries {
+			if i == kept {
+				continue
+			}
+
+			d.skipped.set(e.line)
+			if kept < 0 {
+				d.rejected.set(e.line)
+			}
+		}
+
+		if !conflict {
+			continue
+		}
+
+		d.totalConflicts++
+
+		c := models.Conflict{IpAddress: ip}
+		for i := 0; i < len(entries) && i < maxConflictLines; i++ {
+			c.Lines = append(c.Lines, entries[i].line)
+		}
+		if kept >= 0 {
+			c.KeptLine = entries[kept].line
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	// Keeping the first conflicts of the file
+	d.conflicts = append(d.conflicts, conflicts...)
+	sort.Slice(d.conflicts, func(i, j int) bool { return d.conflicts[i].Lines[0] < d.conflicts[j].Lines[0] })
+	if len(d.conflicts) > maxConflicts {
+		d.conflicts = d.conflicts[:maxConflicts]
+	}
+
+	return nil
+}
+
+// resolveDuplicates returns the index of the entry of an IP that's imported, -1 if none is, and whether the
+// entries conflict, i.e. their data isn't the same.
+func resolveDuplicates(entries []duplicateEntry, policy string) (int, bool) {
+	conflict := false
+	for _, e := range entries[1:] {
+		if e.hash != entries[0].hash {
+			conflict = true
+			break
+		}
+	}
+
+	if !conflict {
+		return 0, false
+	}
+
+	switch policy {
+	case DuplicatePolicyLastWins:
+		return len(entries) - 1, true
+	case DuplicatePolicyMostComplete:
+		kept := 0
+		for i, e := range entries {
+			if e.completeness > entries[kept].completeness {
+				kept = i
+			}
+		}
+
+		return kept, true
+	case DuplicatePolicyRejectAll:
+		return -1, true
+	default:
+		return 0, true
+	}
+}
+
+// duplicateEntry is a valid line of a dump file, as compared to the other lines with the same IP.
+type duplicateEntry struct {
+	ip   string
+	line uint64
+	// completeness is the number of fields set
+	completeness int
+	// hash identifies the data of the line, other than the IP
+	hash uint64
+}
+
+func newDuplicateEntry(line uint64, g models.Geolocation) duplicateEntry {
+	e := duplicateEntry{
+		// parseLine leaves the IP in canonical form
+		ip:   g.IpAddress,
+		line: line,
+	}
+
+	for _, field := range []string{g.CountryCode, g.Country, g.City, g.MysteryValue, g.Region, g.PostalCode, g.Timezone,
+		g.Continent} {
+		if strings.TrimSpace(field) != "" {
+			e.completeness++
+		}
+	}
+	if g.Latitude != 0 || g.Longitude != 0 {
+		e.completeness++
+	}
+	if g.AccuracyRadius != 0 {
+		e.completeness++
+	}
+	// Empty attributes are left out when parsing the line
+	e.completeness += len(g.Attributes)
+
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s\x00%x\x00%x\x00%s\x00%s\x00%s\x00%d\x00%s", g.CountryCode,
+		g.Country, g.City, g.MysteryValue, math.Float64bits(g.Latitude), math.Float64bits(g.Longitude), g.Region,
+		g.PostalCode, g.Timezone, g.AccuracyRadius, g.Continent)
+
+	names := make([]string, 0, len(g.Attributes))
+	for name := range g.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(hash, "\x00%s=%s", name, g.Attributes[name])
+	}
+
+	e.hash = hash.Sum64()
+
+	return e
+}
+
+func (e duplicateEntry) String() string {
+	return fmt.Sprintf("%s %d %d %d", e.ip, e.line, e.completeness, e.hash)
+}
+
+func parseDuplicateEntry(s string) (duplicateEntry, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 4 {
+		return duplicateEntry{}, fmt.Errorf("invalid entry %q", s)
+	}
+
+	e := duplicateEntry{ip: fields[0]}
+
+	var err error
+	if e.line, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+		return duplicateEntry{}, err
+	}
+	if e.completeness, err = strconv.Atoi(fields[2]); err != nil {
+		return duplicateEntry{}, err
+	}
+	if e.hash, err = strconv.ParseUint(fields[3], 10, 64); err != nil {
+		return duplicateEntry{}, err
+	}
+
+	return e, nil
+}
+
+// partitions spreads entries over temporary files by IP, so all the entries of an IP are in the same file.
+type partitions struct {
+	files   []*os.File
+	writers []*bufio.Writer
+}
+
+func newPartitions(dir string, n int) (*partitions, error) {
+	p := &partitions{}
+	for i := 0; i < n; i++ {
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("partition-%d", i)))
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+
+		p.files = append(p.files, file)
+		p.writers = append(p.writers, bufio.NewWriter(file))
+	}
+
+	return p, nil
+}
+
+func (p *partitions) add(e duplicateEntry) error {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(e.ip))
+
+	_, err := fmt.Fprintln(p.writers[hash.Sum32()%uint32(len(p.writers))], e)
+
+	return err
+}
+
+func (p *partitions) flush() error {
+	for _, w := range p.writers {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *partitions) close() {
+	for _, file := range p.files {
+		_ = file.Close()
+	}
+}
+
+// bitset is a set of numbers, growing as they're added.
+type bitset []uint64
+
+func (b *bitset) set(n uint64) {
+	i := n / 64
+	for uint64(len(*b)) <= i {
+		*b = append(*b, 0)
+	}
+
+	(*b)[i] |= 1 << (n % 64)
+}
+
+func (b bitset) has(n uint64) bool {
+	i := n / 64
+
+	return i < uint64(len(b)) && b[i]&(1<<(n%64)) != 0
+}
@@ -0,0 +1,116 @@
+//go:build !integration
+
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+func Test_findDuplicates(t *testing.T) {
+	contents := csvHeader + "\n" +
+		"1.1.1.1,BR,Brazil,Brasilia,0,0,\n" +
+		"1.1.1.2,PT,Portugal,Lisbon,0,0,1\n" +
+		"1.1.1.1,BR,Brazil,Sao Paulo,-23.5,-46.6,\n" +
+		"1.1.1.2,PT,Portugal,Lisbon,0,0,1\n" +
+		",BR,Brazil,Brasilia,0,0,1\n" +
+		"1.1.1.1,BR,Brazil,Rio de Janeiro,0,0,\n" +
+		"1.1.1.3,BR,Brazil,Brasilia,0,0,1\n"
+
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	require.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+	// The lines of 1.1.1.2 are the same, so the second one is a duplicate whatever the policy
+	tests := []struct {
+		policy           string
+		expectedKept     uint64
+		expectedSkipped  map[uint64]string
+		expectedConflict models.Conflict
+	}{
+		{
+			policy:       DuplicatePolicyFirstWins,
+			expectedKept: 1,
+			expectedSkipped: map[uint64]string{
+				3: models.ErrorReasonDuplicateIP,
+				4: models.ErrorReasonDuplicateIP,
+				6: models.ErrorReasonDuplicateIP,
+			},
+		},
+		{
+			policy:       DuplicatePolicyLastWins,
+			expectedKept: 6,
+			expectedSkipped: map[uint64]string{
+				1: models.ErrorReasonDuplicateIP,
+				3: models.ErrorReasonDuplicateIP,
+				4: models.ErrorReasonDuplicateIP,
+			},
+		},
+		{
+			policy:       DuplicatePolicyMostComplete,
+			expectedKept: 3,
+			expectedSkipped: map[uint64]string{
+				1: models.ErrorReasonDuplicateIP,
+				4: models.ErrorReasonDuplicateIP,
+				6: models.ErrorReasonDuplicateIP,
+			},
+		},
+		{
+			policy: DuplicatePolicyRejectAll,
+			expectedSkipped: map[uint64]string{
+				1: models.ErrorReasonConflictingIP,
+				3: models.ErrorReasonConflictingIP,
+				4: models.ErrorReasonDuplicateIP,
+				6: models.ErrorReasonConflictingIP,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.policy, func(t *testing.T) {
+			t.Parallel()
+
+			// The result doesn't depend on how the lines are partitioned
+			for _, partitions := range []int{1, 3} {
+				d, err := findDuplicatesIn(context.Background(), dumpFile, test.policy, partitions)
+				require.NoError(t, err)
+
+				skipped := make(map[uint64]string)
+				for line := uint64(1); line <= 7; line++ {
+					if reason := d.reason(line); reason != "" {
+						skipped[line] = reason
+					}
+				}
+
+				assert.Equal(t, test.expectedSkipped, skipped)
+
+				assert.Equal(t, uint64(1), d.totalConflicts)
+				assert.Equal(t, []models.Conflict{{IpAddress: "1.1.1.1", Lines: []uint64{1, 3, 6},
+					KeptLine: test.expectedKept}}, d.conflicts)
+			}
+		})
+	}
+}
+
+func Test_findDuplicates_missingFile(t *testing.T) {
+	_, err := findDuplicates(context.Background(), "missing_file.csv", DuplicatePolicyFirstWins)
+
+	assert.Error(t, err)
+}
+
+func Test_ValidateDuplicatePolicy(t *testing.T) {
+	for _, policy := range []string{DuplicatePolicyFirstWins, DuplicatePolicyLastWins, DuplicatePolicyMostComplete,
+		DuplicatePolicyRejectAll} {
+		assert.NoError(t, ValidateDuplicatePolicy(policy))
+	}
+
+	assert.Error(t, ValidateDuplicatePolicy("random"))
+	assert.Error(t, ValidateDuplicatePolicy(""))
+}
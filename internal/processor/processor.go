@@ -3,167 +3,636 @@ package processor
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime/debug"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/gocarina/gocsv"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/tiagocesar/geolocation/internal/models"
 )
 
 type geolocationPersister interface {
-	AddLocationInfo(ctx context.Context, locationInfo models.Geolocation) error
+	CreateDataset(ctx context.Context, kind, sourceFile, checksum string) (int64, error)
+	ActiveDatasetLines(ctx context.Context, kind string) (uint64, error)
+	ResumableDataset(ctx context.Context, kind, checksum string) (int64, []models.Checkpoint, error)
+	SaveBatch(ctx context.Context, datasetID int64, locations []models.Geolocation,
+		checkpoint models.Checkpoint) (models.Checkpoint, error)
+	SaveASNBatch(ctx context.Context, datasetID int64, blocks []models.ASNBlock,
+		checkpoint models.Checkpoint) (models.Checkpoint, error)
+	ActivateDataset(ctx context.Context, id int64, lines models.LineCounts) error
+	FailDataset(ctx context.Context, id int64, lines models.LineCounts) error
+
+	LockImports(ctx context.Context) (unlock func(), ok bool, err error)
+	CreateImport(ctx context.Context, imp models.Import) (int64, error)
+	SaveImportProgress(ctx context.Context, imp models.Import) error
 }
 
+// batch is a run of consecutive lines of the file, persisted in a single transaction.
+type batch struct {
+	number    int64
+	firstLine uint64
+	lines     []string
+	// endOffset is the byte offset of the file right after the last line
+	endOffset int64
+}
+
+// Result is the outcome of an import. Imports that fail also return how far they got.
+type Result struct {
+	ImportID  int64
+	DatasetID int64
+	Lines     models.LineCounts
+	// Errors is the number of invalid lines per reason (one of the models.ErrorReason* values)
+	Errors map[string]uint64
+	// Conflicts are the first IPs found on several lines with conflicting data, out of TotalConflicts
+	Conflicts      []models.Conflict
+	TotalConflicts uint64
+	Elapsed        time.Duration
+}
+
+// fileProcessor imports a single dump file. It's not meant to be reused, as it keeps the progress of the import.
 type fileProcessor struct {
-	wg            sync.WaitGroup
-	data          chan models.Geolocation
-	TotalLines    uint64
-	AcceptedLines uint64
-	InvalidLines  uint64
+	// kind is the kind of the imported datasets, one of the models.DatasetKind* values
+	kind string
+	// header is set by processFile before the first batch is sent
+	header    string
+	datasetID int64
+	batchSize int
+
+	duplicatePolicy string
+	// duplicates are the lines skipped because their IP is also on other lines
+	duplicates *duplicates
+
+	thresholds Thresholds
+	// currentLines is the number of rows of the current dataset, the base of the row count delta
+	currentLines uint64
+
+	progress         *progress
+	progressInterval time.Duration
+	onRegistered     func(id int64)
 
 	repository geolocationPersister
 }
 
-func NewFileProcessor(repository geolocationPersister) *fileProcessor {
-	return &fileProcessor{
-		data:       make(chan models.Geolocation),
-		repository: repository,
+// Option configures a fileProcessor created via NewFileProcessor.
+type Option func(*fileProcessor)
+
+// WithProgressInterval sets how often the progress of an import is saved while it runs.
+func WithProgressInterval(interval time.Duration) Option {
+	return func(fp *fileProcessor) {
+		fp.progressInterval = interval
+	}
+}
+
+// WithBatchSize sets how many lines are persisted per transaction, each recording a checkpoint the import can
+// resume from. Resumed imports keep the batch size they started with.
+func WithBatchSize(size int) Option {
+	return func(fp *fileProcessor) {
+		fp.batchSize = size
 	}
 }
 
-func (fp *fileProcessor) ExecuteFileImport(ctx context.Context, dumpFile string, totalRoutines int) {
+// WithDuplicatePolicy sets how conflicts between lines with the same IP and different data are resolved, one of the
+// DuplicatePolicy* values (DuplicatePolicyFirstWins by default).
+func WithDuplicatePolicy(policy string) Option {
+	return func(fp *fileProcessor) {
+		fp.duplicatePolicy = policy
+	}
+}
+
+// WithDataset sets the kind of the imported files, one of the models.DatasetKind* values
+// (models.DatasetKindLocation by default). Each kind has its own active dataset.
+func WithDataset(kind string) Option {
+	return func(fp *fileProcessor) {
+		fp.kind = kind
+	}
+}
+
+// WithThresholds sets the quality gates of the imported files (DefaultThresholds by default). Imports crossing them
+// are stopped and their dataset discarded.
+func WithThresholds(thresholds Thresholds) Option {
+	return func(fp *fileProcessor) {
+		fp.thresholds = thresholds
+	}
+}
+
+func NewFileProcessor(repository geolocationPersister, opts ...Option) *fileProcessor {
+	fp := &fileProcessor{
+		kind:             models.DatasetKindLocation,
+		batchSize:        1000,
+		duplicatePolicy:  DuplicatePolicyFirstWins,
+		thresholds:       DefaultThresholds(),
+		progress:         newProgress(),
+		progressInterval: time.Second,
+		repository:       repository,
+	}
+
+	for _, opt := range opts {
+		opt(fp)
+	}
+
+	return fp
+}
+
+// ExecuteFileImport imports dumpFile as a new dataset, persisting its valid lines with totalRoutines goroutines.
+// The dataset replaces the current one only if the import succeeds. It returns an error if the file couldn't be
+// read, ctx was cancelled or the file crossed the quality gates (see WithThresholds), which stop the import as soon
+// as they're crossed; otherwise invalid lines are only counted.
+//
+// Lines are persisted in batches, each committed with a checkpoint. If the import is interrupted (ctx is cancelled,
+// the process dies or lines can't be persisted), importing the same file again resumes the same dataset, skipping
+// the batches already committed. Importing another file instead discards the interrupted dataset.
+//
+// The import is tracked (see models.Import), with its progress saved every progress interval while it runs. Only one
+// import runs at a time, across all processes sharing the database: it returns ErrImportRunning, without registering
+// the import, if another one is running.
+func (fp *fileProcessor) ExecuteFileImport(ctx context.Context, dumpFile string, totalRoutines int) (Result, error) {
+	unlock, ok, err := fp.repository.LockImports(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("file importer failed to lock imports: %w", err)
+	}
+	if !ok {
+		return Result{}, ErrImportRunning
+	}
+	defer unlock()
+
 	startTime := time.Now()
 
-	fp.wg.Add(1)
+	imp := models.Import{
+		SourceFile: dumpFile,
+		Status:     models.ImportStatusRunning,
+		StartedAt:  startTime,
+	}
+	if info, err := os.Stat(dumpFile); err == nil {
+		imp.FileSize = info.Size()
+	}
 
-	// Running the goroutines that will persist valid lines
-	for i := 0; i < totalRoutines; i++ {
-		fp.wg.Add(1)
+	imp.ID, err = fp.repository.CreateImport(ctx, imp)
+	if err != nil {
+		return Result{}, fmt.Errorf("file importer failed to register import: %w", err)
+	}
+
+	if fp.onRegistered != nil {
+		fp.onRegistered(imp.ID)
+	}
 
-		go func() {
-			defer fp.wg.Done()
+	err = fp.importFile(ctx, &imp, totalRoutines)
+
+	// Saving the outcome of the import, with a fresh context so it's saved even if ctx was cancelled
+	fp.snapshot(&imp)
+	finishedAt := time.Now()
+	imp.FinishedAt = &finishedAt
+
+	switch {
+	case err == nil:
+		imp.Status = models.ImportStatusSucceeded
+	case ctx.Err() != nil:
+		imp.Status = models.ImportStatusCancelled
+		imp.Error = err.Error()
+	default:
+		imp.Status = models.ImportStatusFailed
+		imp.Error = err.Error()
+	}
 
-			defer func() {
-				if r := recover(); r != nil {
-					stack := debug.Stack()
-					log.Println("recovered from panic", r)
-					log.Println(string(stack))
-				}
-			}()
+	if saveErr := fp.repository.SaveImportProgress(context.Background(), imp); saveErr != nil {
+		log.Printf("Failed to save import %d: %v\n", imp.ID, saveErr)
+	}
 
-			fp.persistGeoData(ctx)
-		}()
+	result := Result{
+		ImportID:       imp.ID,
+		DatasetID:      imp.DatasetID,
+		Lines:          imp.Lines,
+		Errors:         imp.Errors,
+		Conflicts:      imp.Conflicts,
+		TotalConflicts: imp.TotalConflicts,
+		Elapsed:        imp.Elapsed(finishedAt),
 	}
 
-	// Processing the file
-	go func(filename string) {
-		defer func() {
-			if r := recover(); r != nil {
-				stack := debug.Stack()
-				log.Println("recovered from panic", r)
-				log.Println(string(stack))
-			}
-		}()
+	if err != nil {
+		return result, err
+	}
+
+	log.Printf("File importer is done = Dataset: %d, total lines: %d, accepted lines: %d, invalid lines: %d, "+
+		"elapsed time: %s\n", result.DatasetID, result.Lines.Total, result.Lines.Accepted, result.Lines.Invalid,
+		result.Elapsed)
+
+	return result, nil
+}
+
+// importFile imports dumpFile into a new dataset, or resumes the dataset of an interrupted import of the same file,
+// activating it if the import succeeds.
+func (fp *fileProcessor) importFile(ctx context.Context, imp *models.Import, totalRoutines int) error {
+	checksum, err := FileChecksum(imp.SourceFile)
+	if err != nil {
+		return fmt.Errorf("file importer failed: %w", err)
+	}
+
+	// Finding the lines with the same IP up front, so the same line of each IP is imported whatever the order
+	// batches are persisted in. Networks repeated in ASN files are only skipped by the repository
+	if fp.kind == models.DatasetKindLocation {
+		fp.duplicates, err = findDuplicates(ctx, imp.SourceFile, fp.duplicatePolicy)
+		if err != nil {
+			return fmt.Errorf("file importer failed to look for duplicate IPs: %w", err)
+		}
 
-		err := fp.processFile(filename)
+		imp.Conflicts, imp.TotalConflicts = fp.duplicates.conflicts, fp.duplicates.totalConflicts
+		if imp.TotalConflicts > 0 {
+			log.Printf("Found %d IPs with conflicting data, resolved as %s\n", imp.TotalConflicts, fp.duplicatePolicy)
+		}
+	}
+
+	datasetID, checkpoints, err := fp.repository.ResumableDataset(ctx, fp.kind, checksum)
+	if err != nil {
+		return fmt.Errorf("file importer failed to look for an interrupted import: %w", err)
+	}
+
+	resume := newResumePoint(checkpoints, fp.batchSize)
+
+	if datasetID != 0 {
+		log.Printf("Resuming dataset %d from line %d, %d lines already imported\n", datasetID, resume.nextLine,
+			resume.lines.Total)
+	} else {
+		datasetID, err = fp.repository.CreateDataset(ctx, fp.kind, imp.SourceFile, checksum)
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("file importer failed to create dataset: %w", err)
 		}
-	}(dumpFile)
+	}
+
+	fp.currentLines, err = fp.repository.ActiveDatasetLines(ctx, fp.kind)
+	if err != nil {
+		return fmt.Errorf("file importer failed to count the rows of the current dataset: %w", err)
+	}
 
-	fp.wg.Wait()
+	fp.datasetID = datasetID
+	fp.batchSize = resume.batchSize
+	fp.progress.addLines(resume.lines)
+	fp.progress.addErrors(resume.errors)
+	imp.DatasetID = fp.datasetID
 
-	log.Printf("File importer is done = Total lines: %d, accepted lines: %d, invalid lines: %d, elapsed time: %s\n",
-		fp.TotalLines, fp.AcceptedLines, fp.InvalidLines, time.Since(startTime))
+	// Timing the import from here, so its throughput and ETA only account for reading the file in batches
+	readStartedAt := time.Now()
+	imp.ReadStartedAt = &readStartedAt
+	imp.ResumedBytes, imp.ResumedLines = resume.offset, resume.lines.Total
+
+	// Saving the progress periodically while the file is imported
+	stopReporting := fp.reportProgress(ctx, *imp)
+	defer stopReporting()
+
+	// The file is read by one goroutine and its batches persisted by totalRoutines others. The first error (e.g. the
+	// file can't be read, a batch can't be persisted or the quality gates are crossed) stops all of them
+	batches := make(chan batch)
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() (err error) {
+		defer recoverPanic(&err)
+
+		if err := fp.processFile(gctx, imp.SourceFile, resume, batches); err != nil {
+			return fmt.Errorf("reading %s: %w", imp.SourceFile, err)
+		}
+
+		return nil
+	})
+
+	for i := 0; i < totalRoutines; i++ {
+		g.Go(func() (err error) {
+			defer recoverPanic(&err)
+
+			return fp.persistGeoData(gctx, batches)
+		})
+	}
+
+	err = g.Wait()
+
+	// Interrupted imports keep their dataset, to be resumed, while files crossing the quality gates are discarded
+	lines := fp.progress.Lines()
+	resumable := true
+	switch {
+	case errors.Is(err, ErrThresholdExceeded):
+		err = fmt.Errorf("file importer failed: %w", err)
+		resumable = false
+	case ctx.Err() != nil:
+		err = fmt.Errorf("file importer cancelled: %w", ctx.Err())
+	case err != nil:
+		err = fmt.Errorf("file importer failed: %w", err)
+	default:
+		if gateErr := fp.thresholds.Check(lines, fp.currentLines); gateErr != nil {
+			err = fmt.Errorf("file importer failed: %w", gateErr)
+			resumable = false
+		}
+	}
+
+	switch {
+	case err != nil && resumable:
+		log.Printf("Dataset %d kept, importing the same file again resumes it\n", fp.datasetID)
+		return err
+	case err != nil:
+		// Using a fresh context, so staged data is cleaned up even if ctx was cancelled
+		if failErr := fp.repository.FailDataset(context.Background(), fp.datasetID, lines); failErr != nil {
+			log.Printf("Failed to discard dataset %d: %v\n", fp.datasetID, failErr)
+		}
+
+		return err
+	}
+
+	if err := fp.repository.ActivateDataset(ctx, fp.datasetID, lines); err != nil {
+		return fmt.Errorf("file importer failed to activate dataset: %w", err)
+	}
+
+	return nil
+}
+
+// reportProgress saves the progress of imp every progress interval, until the returned function is called.
+func (fp *fileProcessor) reportProgress(ctx context.Context, imp models.Import) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(fp.progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fp.snapshot(&imp)
+				if err := fp.repository.SaveImportProgress(ctx, imp); err != nil {
+					log.Printf("Failed to save progress of import %d: %v\n", imp.ID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// snapshot copies the current progress counters to imp.
+func (fp *fileProcessor) snapshot(imp *models.Import) {
+	imp.Lines = fp.progress.Lines()
+	imp.BytesRead = fp.progress.BytesRead()
+	imp.Errors = fp.progress.Errors()
 }
 
-// processFile opens the file specified in the DUMP_FILE environment var, checks if it's valid against the defined
-// csv schema (defined by the header) and sends each line in the CSV for async processing.
+// recoverPanic turns a panic of the calling goroutine into an error, set to err. It must be deferred.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		log.Println("recovered from panic", r)
+		log.Println(string(debug.Stack()))
+
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
+
+// FileChecksum computes the SHA-256 checksum of a file, identifying its contents. Datasets record the checksum of
+// the file they were imported from.
+func FileChecksum(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) { _ = file.Close() }(file)
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// processFile reads filename and sends its lines to out, in batches of consecutive lines, closing out once done. The
+// first line is the header, which defines the csv schema.
 //
-// The actual contents of each line (after being converted to a models.Geolocation struct) is validated before
-// persisting it.
-func (fp *fileProcessor) processFile(filename string) error {
+// Reading starts from the resume point, skipping the batches already committed. It stops early if ctx is cancelled.
+func (fp *fileProcessor) processFile(ctx context.Context, filename string, resume resumePoint,
+	out chan<- batch) error {
+
+	defer close(out)
+
 	file, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
 	defer func(file *os.File) { _ = file.Close() }(file)
 
-	header := ""
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if header == "" {
-			// First line is the header.
-			header = scanner.Text()
-			continue
-		}
+	// offset is the byte offset of the file right after the last line scanned
+	var offset int64
+	newScanner := func(r io.Reader) *bufio.Scanner {
+		scanner := bufio.NewScanner(r)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			advance, token, err := bufio.ScanLines(data, atEOF)
+			offset += int64(advance)
+			return advance, token, err
+		})
+
+		return scanner
+	}
 
-		fp.TotalLines++
+	// First line is the header. The scanner reads ahead, so lines are read again from where the header ends, or
+	// from the resume point, counting the bytes read from there
+	scanner := newScanner(file)
+	if !scanner.Scan() {
+		return scanner.Err()
+	}
+	fp.header = scanner.Text()
 
-		// Once the header is known we can continue to the proper lines in the CSV
-		g, err := csvLineToStruct(header, scanner.Text())
-		if err != nil {
-			fp.incrementInvalidCount()
-			continue
+	if resume.offset > 0 {
+		offset = resume.offset
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	fp.progress.skipBytes(offset)
+	scanner = newScanner(fp.progress.countBytes(file))
+
+	send := func(b batch) error {
+		if resume.committed[b.number] {
+			return nil
 		}
 
-		fp.data <- g
+		select {
+		case out <- b:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
-	close(fp.data)
+	current := batch{number: resume.nextBatch, firstLine: resume.nextLine}
+	for scanner.Scan() {
+		current.lines = append(current.lines, scanner.Text())
+		current.endOffset = offset
+
+		if len(current.lines) == fp.batchSize {
+			if err := send(current); err != nil {
+				return err
+			}
+
+			current = batch{number: current.number + 1, firstLine: current.firstLine + uint64(fp.batchSize)}
+		}
+	}
 
 	if err := scanner.Err(); err != nil {
 		return err
 	}
 
-	fp.wg.Done()
+	if len(current.lines) > 0 {
+		return send(current)
+	}
 
 	return nil
 }
 
-// persistGeoData validates and persists geolocation data, feeding InvalidLines via an atomic operation
-func (fp *fileProcessor) persistGeoData(ctx context.Context) {
-	for g := range fp.data {
-		// Checking if the data is valid
-		if err := g.Validate(); err != nil {
-			// Incrementing one on the list of total errors
-			fp.incrementInvalidCount()
-			continue
+// persistGeoData validates and persists batches of lines until the batches channel is closed. It returns as soon
+// as ctx is done, or with the first error persisting a batch or crossing the quality gates.
+func (fp *fileProcessor) persistGeoData(ctx context.Context, batches <-chan batch) error {
+	for b := range batches {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		err := fp.repository.AddLocationInfo(ctx, g)
-		if err != nil {
-			log.Println(err)
-			fp.incrementInvalidCount()
-			continue
+		if err := fp.persistBatch(ctx, b); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
 
-		fp.incrementAcceptedCount()
+// persistBatch validates the lines of a batch and saves the valid ones along with the batch checkpoint, counting
+// the lines once the batch is committed. It returns an error wrapping ErrThresholdExceeded if the lines counted so
+// far cross the quality gates.
+func (fp *fileProcessor) persistBatch(ctx context.Context, b batch) error {
+	checkpoint := models.Checkpoint{
+		Batch:     b.number,
+		BatchSize: fp.batchSize,
+		EndLine:   b.firstLine + uint64(len(b.lines)) - 1,
+		EndOffset: b.endOffset,
+		Errors:    make(map[string]uint64),
 	}
+
+	var err error
+	switch fp.kind {
+	case models.DatasetKindASN:
+		blocks := validLines(b, &checkpoint, func(_ uint64, line string) (models.ASNBlock, string) {
+			return parseASNLine(fp.header, line)
+		})
+		checkpoint, err = fp.repository.SaveASNBatch(ctx, fp.datasetID, blocks, checkpoint)
+	default:
+		locations := validLines(b, &checkpoint, func(number uint64, line string) (models.Geolocation, string) {
+			g, reason := parseLine(fp.header, line)
+			if reason == "" {
+				reason = fp.duplicates.reason(number)
+			}
+
+			return g, reason
+		})
+		checkpoint, err = fp.repository.SaveBatch(ctx, fp.datasetID, locations, checkpoint)
+	}
+	if err != nil {
+		return fmt.Errorf("persisting batch %d (lines %d to %d): %w", b.number, b.firstLine,
+			b.firstLine+uint64(len(b.lines))-1, err)
+	}
+
+	fp.progress.addLines(checkpoint.Lines)
+	fp.progress.addErrors(checkpoint.Errors)
+
+	return fp.thresholds.checkPartial(fp.progress.Lines(), fp.currentLines)
 }
 
-func (fp *fileProcessor) incrementAcceptedCount() {
-	atomic.AddUint64(&fp.AcceptedLines, 1)
+// validLines parses the lines of a batch with parse, returning the valid ones and counting all of them in checkpoint.
+// parse is given the number and text of each line, returning the reason why it's invalid if it is (one of the
+// models.ErrorReason* values).
+func validLines[T any](b batch, checkpoint *models.Checkpoint, parse func(number uint64, line string) (T, string)) []T {
+	valid := make([]T, 0, len(b.lines))
+	for i, line := range b.lines {
+		checkpoint.Lines.Total++
+
+		v, reason := parse(b.firstLine+uint64(i), line)
+		if reason != "" {
+			checkpoint.Lines.Invalid++
+			checkpoint.Errors[reason]++
+			continue
+		}
+
+		checkpoint.Lines.Accepted++
+		valid = append(valid, v)
+	}
+
+	return valid
 }
 
-func (fp *fileProcessor) incrementInvalidCount() {
-	atomic.AddUint64(&fp.InvalidLines, 1)
+// parseLine converts a line to a models.Geolocation and validates it, with its IP in canonical form. If the line is
+// invalid, it returns the reason why (one of the models.ErrorReason* values).
+func parseLine(header, line string) (models.Geolocation, string) {
+	g, err := csvLineToStruct(header, line)
+	if err != nil {
+		return models.Geolocation{}, models.ErrorReasonInvalidCSV
+	}
+
+	// Checking if the data is valid
+	if err := g.Validate(); err != nil {
+		return models.Geolocation{}, errorReason(err)
+	}
+
+	// The same IP can be written in many ways, e.g. ::ffff:1.1.1.1 or 2001:DB8::1, but is stored and looked up in one
+	ip, _ := models.ParseIP(g.IpAddress)
+	g.IpAddress = ip.String()
+
+	return g, ""
 }
 
 // csvLineToStruct converts each CSV line to a models.Geolocation struct, given the header of the CSV file
-// and the line contents.
+// and the line contents. Columns that aren't fields of models.Geolocation are kept as attributes.
 func csvLineToStruct(header, line string) (models.Geolocation, error) {
 	var g []models.Geolocation
 	if err := gocsv.UnmarshalString(fmt.Sprintf("%s\n%s", header, line), &g); err != nil {
 		return models.Geolocation{}, err
 	}
 
+	attributes, err := lineAttributes(header, line)
+	if err != nil {
+		return models.Geolocation{}, err
+	}
+
 	// gocsv returns an array even if there's only one value
+	g[0].Attributes = attributes
+
 	return g[0], nil
 }
+
+// parseASNLine converts a line of an ASN dump file to a models.ASNBlock and validates it, with its network in
+// canonical form. If the line is invalid, it returns the reason why (one of the models.ErrorReason* values).
+func parseASNLine(header, line string) (models.ASNBlock, string) {
+	var blocks []models.ASNBlock
+	if err := gocsv.UnmarshalString(fmt.Sprintf("%s\n%s", header, line), &blocks); err != nil {
+		return models.ASNBlock{}, models.ErrorReasonInvalidCSV
+	}
+
+	block := blocks[0]
+	if err := block.Validate(); err != nil {
+		return models.ASNBlock{}, errorReason(err)
+	}
+
+	// The database rejects networks with host bits set, e.g. 1.1.1.1/24 instead of 1.1.1.0/24
+	network, _ := models.ParseNetwork(block.Network)
+	block.Network = network.String()
+
+	return block, ""
+}
@@ -4,6 +4,12 @@ package processor
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,17 +18,126 @@ import (
 )
 
 type mockRepository struct {
+	mu                          sync.Mutex
 	AddLocationInfoInvokedCount int
 	AddLocationInfoFn           func(ctx context.Context, locationInfo models.Geolocation) error
+
+	// ResumeDatasetID and Checkpoints are returned by ResumableDataset
+	ResumeDatasetID int64
+	Checkpoints     []models.Checkpoint
+	SavedBatches    []models.Checkpoint
+	// CurrentLines is returned by ActiveDatasetLines
+	CurrentLines uint64
+
+	// DatasetKind is the kind of the dataset created, and ASNBlocks the blocks saved via SaveASNBatch
+	DatasetKind string
+	ASNBlocks   []models.ASNBlock
+
+	ActivatedDataset int64
+	FailedDataset    int64
+	DatasetLines     models.LineCounts
+
+	// ImportsLocked has LockImports fail, as if another process was importing
+	ImportsLocked bool
+
+	importMu    sync.Mutex
+	SavedImport models.Import
+}
+
+func (m *mockRepository) CreateDataset(_ context.Context, kind, _, _ string) (int64, error) {
+	m.DatasetKind = kind
+	return 1, nil
+}
+
+func (m *mockRepository) ActiveDatasetLines(context.Context, string) (uint64, error) {
+	return m.CurrentLines, nil
+}
+
+func (m *mockRepository) ResumableDataset(context.Context, string, string) (int64, []models.Checkpoint, error) {
+	return m.ResumeDatasetID, m.Checkpoints, nil
+}
+
+// errDuplicateIP is returned by AddLocationInfoFn to have a location counted as a duplicate.
+var errDuplicateIP = errors.New("duplicate IP address")
+
+// SaveBatch saves each location via AddLocationInfoFn, which can return errDuplicateIP for duplicates.
+func (m *mockRepository) SaveBatch(ctx context.Context, datasetID int64, locations []models.Geolocation,
+	checkpoint models.Checkpoint) (models.Checkpoint, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var duplicates uint64
+	for _, l := range locations {
+		m.AddLocationInfoInvokedCount++
+
+		err := m.AddLocationInfoFn(ctx, l)
+		switch {
+		case errors.Is(err, errDuplicateIP):
+			duplicates++
+		case err != nil:
+			return models.Checkpoint{}, err
+		}
+	}
+
+	checkpoint.DatasetID = datasetID
+	checkpoint.AddDuplicates(duplicates)
+	m.SavedBatches = append(m.SavedBatches, checkpoint)
+
+	return checkpoint, nil
 }
 
-func (m *mockRepository) AddLocationInfo(ctx context.Context, locationInfo models.Geolocation) error {
-	m.AddLocationInfoInvokedCount++
+func (m *mockRepository) SaveASNBatch(_ context.Context, datasetID int64, blocks []models.ASNBlock,
+	checkpoint models.Checkpoint) (models.Checkpoint, error) {
 
-	return m.AddLocationInfoFn(ctx, locationInfo)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ASNBlocks = append(m.ASNBlocks, blocks...)
+	checkpoint.DatasetID = datasetID
+	m.SavedBatches = append(m.SavedBatches, checkpoint)
+
+	return checkpoint, nil
+}
+
+func (m *mockRepository) ActivateDataset(_ context.Context, id int64, lines models.LineCounts) error {
+	m.ActivatedDataset = id
+	m.DatasetLines = lines
+	return nil
+}
+
+func (m *mockRepository) FailDataset(_ context.Context, id int64, lines models.LineCounts) error {
+	m.FailedDataset = id
+	m.DatasetLines = lines
+	return nil
 }
 
-func Test_persistGeoData(t *testing.T) {
+func (m *mockRepository) LockImports(context.Context) (func(), bool, error) {
+	return func() {}, !m.ImportsLocked, nil
+}
+
+func (m *mockRepository) CreateImport(context.Context, models.Import) (int64, error) {
+	return 1, nil
+}
+
+func (m *mockRepository) SaveImportProgress(_ context.Context, imp models.Import) error {
+	m.importMu.Lock()
+	defer m.importMu.Unlock()
+
+	m.SavedImport = imp
+	return nil
+}
+
+func (m *mockRepository) savedImport() models.Import {
+	m.importMu.Lock()
+	defer m.importMu.Unlock()
+
+	return m.SavedImport
+}
+
+const csvHeader = "ip_address,country_code,country,city,latitude,longitude,mystery_value"
+
+func Test_persistBatch(t *testing.T) {
 	t.Run("Successful persistence - asserts AddLocationInfo was called exactly once", func(t *testing.T) {
 		t.Parallel()
 
@@ -32,12 +147,21 @@ func Test_persistGeoData(t *testing.T) {
 			},
 		}
 		fp := NewFileProcessor(&repository)
+		fp.header = csvHeader
 
-		go fp.persistGeoData(context.Background())
-
-		fp.data <- mockGeolocation()
+		err := fp.persistBatch(context.Background(), batch{firstLine: 1, lines: []string{mockLine()}, endOffset: 40})
 
-		assert.True(t, repository.AddLocationInfoInvokedCount == 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, repository.AddLocationInfoInvokedCount)
+		assert.Equal(t, uint64(1), fp.progress.Lines().Accepted)
+		assert.Equal(t, []models.Checkpoint{{
+			DatasetID: 0,
+			BatchSize: 1000,
+			EndLine:   1,
+			EndOffset: 40,
+			Lines:     models.LineCounts{Total: 1, Accepted: 1},
+			Errors:    map[string]uint64{},
+		}}, repository.SavedBatches)
 	})
 
 	t.Run("invalid data - atomic invalid count should increment", func(t *testing.T) {
@@ -45,18 +169,23 @@ func Test_persistGeoData(t *testing.T) {
 
 		repository := &mockRepository{}
 		fp := NewFileProcessor(repository)
+		fp.header = csvHeader
 
-		assert.True(t, fp.InvalidLines == 0)
-
-		go fp.persistGeoData(context.Background())
+		assert.True(t, fp.progress.Lines().Invalid == 0)
 
-		fp.data <- models.Geolocation{}
+		err := fp.persistBatch(context.Background(), batch{firstLine: 1, lines: []string{",BR,Brazil,Brasilia,0,0,1"}})
 
+		assert.NoError(t, err)
 		assert.True(t, repository.AddLocationInfoInvokedCount == 0)
-		assert.True(t, fp.InvalidLines == 1)
+		assert.True(t, fp.progress.Lines().Invalid == 1)
 	})
 }
 
+func mockLine() string {
+	g := mockGeolocation()
+	return g.IpAddress + "," + g.CountryCode + "," + g.Country + "," + g.City + ",0,0," + g.MysteryValue
+}
+
 func mockGeolocation() models.Geolocation {
 	return models.Geolocation{
 		IpAddress:    "192.168.0.1",
@@ -68,3 +197,511 @@ func mockGeolocation() models.Geolocation {
 		MysteryValue: "Home sweet home",
 	}
 }
+
+func Test_ExecuteFileImport_datasets(t *testing.T) {
+	tests := []struct {
+		name              string
+		contents          string
+		expectedErr       bool
+		expectedActivated int64
+		expectedFailed    int64
+		expectedLines     models.LineCounts
+	}{
+		{
+			name: "successful import activates the dataset",
+			contents: "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+				"1.1.1.1,BR,Brazil,Brasilia,0,0,1\n" +
+				",BR,Brazil,Brasilia,0,0,1\n",
+			expectedActivated: 1,
+			expectedLines:     models.LineCounts{Total: 2, Accepted: 1, Invalid: 1},
+		},
+		{
+			name: "import with no valid lines fails the dataset",
+			contents: "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+				",BR,Brazil,Brasilia,0,0,1\n",
+			expectedErr:    true,
+			expectedFailed: 1,
+			expectedLines:  models.LineCounts{Total: 1, Invalid: 1},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+			assert.NoError(t, os.WriteFile(dumpFile, []byte(test.contents), 0o600))
+
+			repository := &mockRepository{
+				AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+					return nil
+				},
+			}
+
+			_, err := NewFileProcessor(repository).ExecuteFileImport(context.Background(), dumpFile, 1)
+
+			assert.Equal(t, test.expectedErr, err != nil)
+			assert.Equal(t, test.expectedActivated, repository.ActivatedDataset)
+			assert.Equal(t, test.expectedFailed, repository.FailedDataset)
+			assert.Equal(t, test.expectedLines, repository.DatasetLines)
+		})
+	}
+}
+
+func Test_ExecuteFileImport_missingFile(t *testing.T) {
+	repository := &mockRepository{}
+	fp := NewFileProcessor(repository)
+
+	_, err := fp.ExecuteFileImport(context.Background(), "missing_file.csv", 2)
+
+	assert.Error(t, err)
+
+	imp := repository.savedImport()
+	assert.Equal(t, models.ImportStatusFailed, imp.Status)
+	assert.NotEmpty(t, imp.Error)
+	assert.NotNil(t, imp.FinishedAt)
+}
+
+func Test_ExecuteFileImport_tracking(t *testing.T) {
+	contents := "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+		"1.1.1.1,BR,Brazil,Brasilia,0,0,1\n" +
+		"1.1.1.1,BR,Brazil,Brasilia,0,0,1\n" +
+		",BR,Brazil,Brasilia,0,0,1\n" +
+		"1.1.1.2,,Brazil,Brasilia,0,0,1\n" +
+		"1.1.1.3,BR,Brazil\n"
+
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	assert.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+
+	repository := &mockRepository{
+		AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if seen[locationInfo.IpAddress] {
+				return errDuplicateIP
+			}
+			seen[locationInfo.IpAddress] = true
+			return nil
+		},
+	}
+
+	result, err := NewFileProcessor(repository).ExecuteFileImport(context.Background(), dumpFile, 1)
+	assert.NoError(t, err)
+
+	imp := repository.savedImport()
+	assert.Equal(t, Result{
+		ImportID:  imp.ID,
+		DatasetID: imp.DatasetID,
+		Lines:     imp.Lines,
+		Errors:    imp.Errors,
+		Elapsed:   imp.FinishedAt.Sub(imp.StartedAt),
+	}, result)
+	assert.Equal(t, int64(1), imp.ID)
+	assert.Equal(t, int64(1), imp.DatasetID)
+	assert.Equal(t, models.ImportStatusSucceeded, imp.Status)
+	assert.Equal(t, int64(len(contents)), imp.FileSize)
+	assert.Equal(t, int64(len(contents)), imp.BytesRead)
+	assert.Equal(t, models.LineCounts{Total: 5, Accepted: 1, Invalid: 4}, imp.Lines)
+	assert.Equal(t, map[string]uint64{
+		models.ErrorReasonInvalidCSV:         1,
+		models.ErrorReasonInvalidIP:          1,
+		models.ErrorReasonInvalidCountryCode: 1,
+		models.ErrorReasonDuplicateIP:        1,
+	}, imp.Errors)
+	assert.NotNil(t, imp.FinishedAt)
+}
+
+func Test_ExecuteFileImport_cancelled(t *testing.T) {
+	contents := "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+		"1.1.1.1,BR,Brazil,Brasilia,0,0,1\n"
+
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	assert.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	repository := &mockRepository{
+		AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+			cancel()
+			return ctx.Err()
+		},
+	}
+
+	_, err := NewFileProcessor(repository).ExecuteFileImport(ctx, dumpFile, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	imp := repository.savedImport()
+	assert.Equal(t, models.ImportStatusCancelled, imp.Status)
+	// The dataset is kept, to be resumed
+	assert.Equal(t, int64(0), repository.FailedDataset)
+	assert.Equal(t, int64(0), repository.ActivatedDataset)
+}
+
+func Test_ExecuteFileImport_duplicates(t *testing.T) {
+	// Many batches of conflicting lines, persisted concurrently
+	lines := []string{csvHeader}
+	for i := 1; i <= 50; i++ {
+		lines = append(lines, fmt.Sprintf("1.1.1.%d,BR,Brazil,Brasilia,0,0,1", i))
+	}
+	for i := 1; i <= 50; i++ {
+		lines = append(lines, fmt.Sprintf("1.1.1.%d,BR,Brazil,Sao Paulo,0,0,1", i))
+	}
+
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	assert.NoError(t, os.WriteFile(dumpFile, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+	var mu sync.Mutex
+	cities := make(map[string]int)
+	repository := &mockRepository{
+		AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			cities[locationInfo.City]++
+			return nil
+		},
+	}
+
+	fp := NewFileProcessor(repository, WithBatchSize(2), WithDuplicatePolicy(DuplicatePolicyLastWins))
+	result, err := fp.ExecuteFileImport(context.Background(), dumpFile, 8)
+	assert.NoError(t, err)
+
+	// The last line of each IP is imported, whatever the order batches were persisted in
+	assert.Equal(t, map[string]int{"Sao Paulo": 50}, cities)
+	assert.Equal(t, models.LineCounts{Total: 100, Accepted: 50, Invalid: 50}, result.Lines)
+	assert.Equal(t, map[string]uint64{models.ErrorReasonDuplicateIP: 50}, result.Errors)
+	assert.Equal(t, uint64(50), result.TotalConflicts)
+	assert.Equal(t, models.Conflict{IpAddress: "1.1.1.1", Lines: []uint64{1, 51}, KeptLine: 51}, result.Conflicts[0])
+
+	imp := repository.savedImport()
+	assert.Equal(t, result.Conflicts, imp.Conflicts)
+	assert.Equal(t, uint64(50), imp.TotalConflicts)
+}
+
+func Test_ExecuteFileImport_addressForms(t *testing.T) {
+	contents := csvHeader + "\n" +
+		"1.1.1.1,PT,Portugal,Lisbon,38.7,-9.1,1\n" +
+		"::ffff:1.1.1.1,PT,Portugal,Porto,41.1,-8.6,1\n" +
+		"::FFFF:1.1.1.2,PT,Portugal,Faro,37.0,-7.9,1\n" +
+		"2001:0DB8:0000::0001,PT,Portugal,Braga,41.5,-8.4,1\n" +
+		"fe80::1%eth0,PT,Portugal,Coimbra,40.2,-8.4,1\n"
+
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	assert.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+	var mu sync.Mutex
+	ips := make(map[string]string)
+	repository := &mockRepository{
+		AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			ips[locationInfo.IpAddress] = locationInfo.City
+			return nil
+		},
+	}
+
+	fp := NewFileProcessor(repository, WithDuplicatePolicy(DuplicatePolicyFirstWins))
+	result, err := fp.ExecuteFileImport(context.Background(), dumpFile, 2)
+	assert.NoError(t, err)
+
+	// IPs are stored in canonical form, so an IPv4 address and its IPv4-mapped IPv6 form are the same IP
+	assert.Equal(t, map[string]string{"1.1.1.1": "Lisbon", "1.1.1.2": "Faro", "2001:db8::1": "Braga"}, ips)
+	assert.Equal(t, models.LineCounts{Total: 5, Accepted: 3, Invalid: 2}, result.Lines)
+	assert.Equal(t, map[string]uint64{models.ErrorReasonInvalidIP: 1, models.ErrorReasonDuplicateIP: 1},
+		result.Errors)
+	assert.Equal(t, []models.Conflict{{IpAddress: "1.1.1.1", Lines: []uint64{1, 2}, KeptLine: 1}}, result.Conflicts)
+}
+
+func Test_ExecuteFileImport_asn(t *testing.T) {
+	contents := "network,asn,organization,connection_type\n" +
+		"1.1.1.0/24,13335,Cloudflare,hosting\n" +
+		"2001:db8::1/32,64496,Example,residential\n" +
+		"1.1.1.0/33,13335,Cloudflare,hosting\n" +
+		"1.0.0.0/24,0,Reserved,\n" +
+		"1.2.0.0/16,64497,Example,satellite\n" +
+		"1.3.0.0/16,64498,Example,\n" +
+		"::ffff:1.4.0.0/112,64499,Example,\n" +
+		"::ffff:0.0.0.0/95,64499,Example,\n"
+
+	dumpFile := filepath.Join(t.TempDir(), "asn.csv")
+	assert.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+	repository := &mockRepository{}
+	fp := NewFileProcessor(repository, WithDataset(models.DatasetKindASN), WithBatchSize(2))
+	result, err := fp.ExecuteFileImport(context.Background(), dumpFile, 2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, models.DatasetKindASN, repository.DatasetKind)
+	assert.Equal(t, models.LineCounts{Total: 8, Accepted: 4, Invalid: 4}, result.Lines)
+	assert.Equal(t, map[string]uint64{
+		models.ErrorReasonInvalidNetwork:        2,
+		models.ErrorReasonInvalidASN:            1,
+		models.ErrorReasonInvalidConnectionType: 1,
+	}, result.Errors)
+
+	// Networks are stored in canonical form
+	assert.ElementsMatch(t, []models.ASNBlock{
+		{Network: "1.1.1.0/24", ASN: 13335, Organization: "Cloudflare", ConnectionType: models.ConnectionTypeHosting},
+		{Network: "2001:db8::/32", ASN: 64496, Organization: "Example",
+			ConnectionType: models.ConnectionTypeResidential},
+		{Network: "1.3.0.0/16", ASN: 64498, Organization: "Example"},
+		{Network: "1.4.0.0/16", ASN: 64499, Organization: "Example"},
+	}, repository.ASNBlocks)
+	assert.Equal(t, int64(1), repository.ActivatedDataset)
+	assert.Equal(t, 0, repository.AddLocationInfoInvokedCount)
+}
+
+func Test_ExecuteFileImport_failures(t *testing.T) {
+	lines := []string{csvHeader}
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, fmt.Sprintf("1.1.1.%d,BR,Brazil,Brasilia,0,0,1", i))
+	}
+	contents := strings.Join(lines, "\n") + "\n"
+
+	errPersistence := errors.New("connection reset")
+
+	tests := []struct {
+		name           string
+		persistFn      func(cancel context.CancelFunc, locationInfo models.Geolocation) error
+		expectedErr    error
+		expectedStatus string
+	}{
+		{
+			name: "persistence error",
+			persistFn: func(_ context.CancelFunc, locationInfo models.Geolocation) error {
+				if locationInfo.IpAddress == "1.1.1.50" {
+					return errPersistence
+				}
+				return nil
+			},
+			expectedErr:    errPersistence,
+			expectedStatus: models.ImportStatusFailed,
+		},
+		{
+			name: "panic persisting a batch",
+			persistFn: func(_ context.CancelFunc, locationInfo models.Geolocation) error {
+				if locationInfo.IpAddress == "1.1.1.50" {
+					panic("unexpected")
+				}
+				return nil
+			},
+			expectedStatus: models.ImportStatusFailed,
+		},
+		{
+			name: "cancelled while importing",
+			persistFn: func(cancel context.CancelFunc, locationInfo models.Geolocation) error {
+				if locationInfo.IpAddress == "1.1.1.50" {
+					cancel()
+				}
+				return nil
+			},
+			expectedErr:    context.Canceled,
+			expectedStatus: models.ImportStatusCancelled,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+			assert.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			repository := &mockRepository{
+				AddLocationInfoFn: func(_ context.Context, locationInfo models.Geolocation) error {
+					return test.persistFn(cancel, locationInfo)
+				},
+			}
+
+			// Many goroutines and small batches, so the failure happens while other batches are in flight
+			result, err := NewFileProcessor(repository, WithBatchSize(2)).ExecuteFileImport(ctx, dumpFile, 8)
+			assert.Error(t, err)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+			}
+
+			imp := repository.savedImport()
+			assert.Equal(t, test.expectedStatus, imp.Status)
+			assert.Equal(t, imp.Lines, result.Lines)
+			assert.Less(t, result.Lines.Total, uint64(100))
+			// The dataset is kept, to be resumed
+			assert.Equal(t, int64(0), repository.FailedDataset)
+			assert.Equal(t, int64(0), repository.ActivatedDataset)
+		})
+	}
+}
+
+func Test_ExecuteFileImport_thresholds(t *testing.T) {
+	t.Run("crossing a gate while importing stops the import", func(t *testing.T) {
+		t.Parallel()
+
+		// Only the first line is valid, the invalid percentage is checked once enough lines were read
+		lines := []string{csvHeader, "1.1.1.1,BR,Brazil,Brasilia,0,0,1"}
+		for i := 0; i < 2*minLinesChecked; i++ {
+			lines = append(lines, ",BR,Brazil,Brasilia,0,0,1")
+		}
+
+		dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+		assert.NoError(t, os.WriteFile(dumpFile, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+		repository := &mockRepository{
+			AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+				return nil
+			},
+		}
+
+		fp := NewFileProcessor(repository, WithBatchSize(100), WithThresholds(Thresholds{MaxInvalidPercent: 10}))
+		_, err := fp.ExecuteFileImport(context.Background(), dumpFile, 1)
+		assert.ErrorIs(t, err, ErrThresholdExceeded)
+
+		// The file isn't read to the end
+		assert.Less(t, len(repository.SavedBatches), 2*minLinesChecked/100)
+
+		imp := repository.savedImport()
+		assert.Equal(t, models.ImportStatusFailed, imp.Status)
+		// The dataset is discarded instead of being kept to be resumed
+		assert.Equal(t, int64(1), repository.FailedDataset)
+		assert.Equal(t, int64(0), repository.ActivatedDataset)
+	})
+
+	t.Run("crossing a gate once imported fails the import", func(t *testing.T) {
+		t.Parallel()
+
+		contents := csvHeader + "\n" +
+			"1.1.1.1,BR,Brazil,Brasilia,0,0,1\n" +
+			"1.1.1.2,BR,Brazil,Brasilia,0,0,1\n"
+
+		dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+		assert.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+		repository := &mockRepository{
+			AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+				return nil
+			},
+			CurrentLines: 10,
+		}
+
+		fp := NewFileProcessor(repository, WithThresholds(Thresholds{MaxInvalidPercent: 100, MaxDeltaPercent: 50}))
+		_, err := fp.ExecuteFileImport(context.Background(), dumpFile, 1)
+		assert.ErrorIs(t, err, ErrThresholdExceeded)
+
+		assert.Equal(t, models.ImportStatusFailed, repository.savedImport().Status)
+		assert.Equal(t, int64(1), repository.FailedDataset)
+		assert.Equal(t, int64(0), repository.ActivatedDataset)
+		assert.Equal(t, models.LineCounts{Total: 2, Accepted: 2}, repository.DatasetLines)
+	})
+}
+
+func Test_ExecuteFileImport_resume(t *testing.T) {
+	lines := []string{
+		csvHeader,
+		"1.1.1.1,BR,Brazil,Brasilia,0,0,1",
+		"1.1.1.2,BR,Brazil,Brasilia,0,0,1",
+		"1.1.1.3,BR,Brazil,Brasilia,0,0,1",
+		",BR,Brazil,Brasilia,0,0,1",
+		"1.1.1.5,BR,Brazil,Brasilia,0,0,1",
+	}
+	contents := strings.Join(lines, "\n") + "\n"
+
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	assert.NoError(t, os.WriteFile(dumpFile, []byte(contents), 0o600))
+
+	// offsetAfter is the byte offset right after the given data line
+	offsetAfter := func(line int) int64 {
+		return int64(len(strings.Join(lines[:line+1], "\n")) + 1)
+	}
+
+	var imported []string
+	repository := &mockRepository{
+		AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+			imported = append(imported, locationInfo.IpAddress)
+			return nil
+		},
+		// Batches of 2 lines: the first and last batches were committed before the import was interrupted
+		ResumeDatasetID: 7,
+		Checkpoints: []models.Checkpoint{
+			{Batch: 2, BatchSize: 2, EndLine: 5, EndOffset: offsetAfter(5), Lines: models.LineCounts{Total: 1, Accepted: 1}},
+			{Batch: 0, BatchSize: 2, EndLine: 2, EndOffset: offsetAfter(2), Lines: models.LineCounts{Total: 2, Accepted: 2}},
+		},
+	}
+
+	// The batch size of the checkpoints is kept
+	_, err := NewFileProcessor(repository, WithBatchSize(1000)).ExecuteFileImport(context.Background(), dumpFile, 1)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"1.1.1.3"}, imported)
+	assert.Equal(t, []models.Checkpoint{{
+		DatasetID: 7,
+		Batch:     1,
+		BatchSize: 2,
+		EndLine:   4,
+		EndOffset: offsetAfter(4),
+		Lines:     models.LineCounts{Total: 2, Accepted: 1, Invalid: 1},
+		Errors:    map[string]uint64{models.ErrorReasonInvalidIP: 1},
+	}}, repository.SavedBatches)
+
+	assert.Equal(t, int64(7), repository.ActivatedDataset)
+	assert.Equal(t, models.LineCounts{Total: 5, Accepted: 4, Invalid: 1}, repository.DatasetLines)
+
+	// Reading resumed after the first batch, with the lines of both committed batches already imported
+	imp := repository.savedImport()
+	assert.Equal(t, int64(len(contents)), imp.BytesRead)
+	assert.Equal(t, offsetAfter(2), imp.ResumedBytes)
+	assert.Equal(t, uint64(3), imp.ResumedLines)
+	assert.NotNil(t, imp.ReadStartedAt)
+}
+
+func Test_newResumePoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		checkpoints []models.Checkpoint
+		expected    resumePoint
+	}{
+		{
+			name: "no checkpoints",
+			expected: resumePoint{
+				nextLine:  1,
+				batchSize: 10,
+				committed: map[int64]bool{},
+				errors:    map[string]uint64{},
+			},
+		},
+		{
+			name: "resumes after the contiguous committed batches, skipping the later ones",
+			checkpoints: []models.Checkpoint{
+				{Batch: 3, BatchSize: 5, EndLine: 20, EndOffset: 200, Lines: models.LineCounts{Total: 5, Invalid: 5},
+					Errors: map[string]uint64{models.ErrorReasonInvalidCSV: 5}},
+				{Batch: 0, BatchSize: 5, EndLine: 5, EndOffset: 50, Lines: models.LineCounts{Total: 5, Accepted: 5}},
+				{Batch: 1, BatchSize: 5, EndLine: 10, EndOffset: 100, Lines: models.LineCounts{Total: 5, Accepted: 5}},
+			},
+			expected: resumePoint{
+				offset:    100,
+				nextLine:  11,
+				nextBatch: 2,
+				batchSize: 5,
+				committed: map[int64]bool{3: true},
+				lines:     models.LineCounts{Total: 15, Accepted: 10, Invalid: 5},
+				errors:    map[string]uint64{models.ErrorReasonInvalidCSV: 5},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, newResumePoint(test.checkpoints, 10))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package processor
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// progress tracks how much of the file was read, how many lines were imported and why lines were rejected, while
+// an import runs. It's safe for concurrent use.
+type progress struct {
+	bytesRead int64
+	total     uint64
+	accepted  uint64
+	invalid   uint64
+
+	mu     sync.Mutex
+	errors map[string]uint64
+}
+
+func newProgress() *progress {
+	return &progress{
+		errors: make(map[string]uint64),
+	}
+}
+
+// countBytes wraps r, counting the bytes read from it.
+func (p *progress) countBytes(r io.Reader) io.Reader {
+	return &countingReader{reader: r, count: &p.bytesRead}
+}
+
+func (p *progress) BytesRead() int64 {
+	return atomic.LoadInt64(&p.bytesRead)
+}
+
+// skipBytes records that reading starts n bytes into the file, which count as read.
+func (p *progress) skipBytes(n int64) {
+	atomic.StoreInt64(&p.bytesRead, n)
+}
+
+// addLines adds to the line counts.
+func (p *progress) addLines(lines models.LineCounts) {
+	atomic.AddUint64(&p.total, lines.Total)
+	atomic.AddUint64(&p.accepted, lines.Accepted)
+	atomic.AddUint64(&p.invalid, lines.Invalid)
+}
+
+func (p *progress) Lines() models.LineCounts {
+	return models.LineCounts{
+		Total:    atomic.LoadUint64(&p.total),
+		Accepted: atomic.LoadUint64(&p.accepted),
+		Invalid:  atomic.LoadUint64(&p.invalid),
+	}
+}
+
+// addErrors adds the number of invalid lines per reason.
+func (p *progress) addErrors(errs map[string]uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for reason, count := range errs {
+		p.errors[reason] += count
+	}
+}
+
+// Errors returns a copy of the number of invalid lines per reason.
+func (p *progress) Errors() map[string]uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make(map[string]uint64, len(p.errors))
+	for reason, count := range p.errors {
+		result[reason] = count
+	}
+
+	return result
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  *int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	atomic.AddInt64(r.count, int64(n))
+
+	return n, err
+}
+
+// errorReason maps the error that made a line invalid to one of the models.ErrorReason* values.
+func errorReason(err error) string {
+	switch {
+	case errors.Is(err, models.ErrValidationInvalidIP):
+		return models.ErrorReasonInvalidIP
+	case errors.Is(err, models.ErrValidationInvalidCountryCode):
+		return models.ErrorReasonInvalidCountryCode
+	case errors.Is(err, models.ErrValidationInvalidCountry):
+		return models.ErrorReasonInvalidCountry
+	case errors.Is(err, models.ErrValidationInvalidCity):
+		return models.ErrorReasonInvalidCity
+	case errors.Is(err, models.ErrValidationInvalidAccuracy):
+		return models.ErrorReasonInvalidAccuracy
+	case errors.Is(err, models.ErrValidationInvalidNetwork):
+		return models.ErrorReasonInvalidNetwork
+	case errors.Is(err, models.ErrValidationInvalidASN):
+		return models.ErrorReasonInvalidASN
+	case errors.Is(err, models.ErrValidationInvalidOrganization):
+		return models.ErrorReasonInvalidOrganization
+	case errors.Is(err, models.ErrValidationInvalidConnectionType):
+		return models.ErrorReasonInvalidConnectionType
+	default:
+		return models.ErrorReasonPersistence
+	}
+}
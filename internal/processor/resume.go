@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"sort"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// resumePoint is where the import of a file into a dataset resumes from, based on the checkpoints of the batches
+// already committed.
+type resumePoint struct {
+	// offset is the byte offset to continue reading from, 0 to read from the start
+	offset int64
+	// nextLine is the number of the line at offset (the first line after the header is 1)
+	nextLine uint64
+	// nextBatch is the number of the batch starting at nextLine
+	nextBatch int64
+	batchSize int
+	// committed are the batches already committed after nextBatch, which are skipped
+	committed map[int64]bool
+
+	lines  models.LineCounts
+	errors map[string]uint64
+}
+
+// newResumePoint finds where to resume from given the checkpoints of a dataset. Batches are committed out of order,
+// so reading resumes right after the last batch of the contiguous run of committed batches starting at batch 0.
+// batchSize is used if there are no checkpoints; otherwise the batch size of the checkpoints is kept, so batch
+// boundaries don't change.
+func newResumePoint(checkpoints []models.Checkpoint, batchSize int) resumePoint {
+	rp := resumePoint{
+		nextLine:  1,
+		batchSize: batchSize,
+		committed: make(map[int64]bool, len(checkpoints)),
+		errors:    make(map[string]uint64),
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Batch < checkpoints[j].Batch })
+
+	for _, cp := range checkpoints {
+		rp.batchSize = cp.BatchSize
+
+		rp.lines.Total += cp.Lines.Total
+		rp.lines.Accepted += cp.Lines.Accepted
+		rp.lines.Invalid += cp.Lines.Invalid
+		for reason, count := range cp.Errors {
+			rp.errors[reason] += count
+		}
+
+		if cp.Batch == rp.nextBatch {
+			rp.offset = cp.EndOffset
+			rp.nextLine = cp.EndLine + 1
+			rp.nextBatch++
+			continue
+		}
+
+		rp.committed[cp.Batch] = true
+	}
+
+	return rp
+}
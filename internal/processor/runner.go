@@ -0,0 +1,176 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+var (
+	ErrImportRunning    = errors.New("an import is already running")
+	ErrImportNotRunning = errors.New("import isn't running")
+)
+
+// Runner runs imports in the background, on demand, one at a time. Imports also take the imports lock of the
+// database (see ExecuteFileImport), so they don't run alongside imports of other processes either.
+type Runner struct {
+	ctx           context.Context
+	repository    geolocationPersister
+	totalRoutines int
+	uploadDir     string
+	opts          []Option
+
+	mu      sync.Mutex
+	current *runningImport
+	wg      sync.WaitGroup
+}
+
+type runningImport struct {
+	id     int64
+	cancel context.CancelFunc
+}
+
+// NewRunner creates a Runner whose imports are persisted by totalRoutines goroutines, with the fileProcessor
+// configured by opts. Running imports are cancelled once ctx is done. Uploaded files are stored in uploadDir
+// (the default directory for temporary files if empty) while they're imported.
+func NewRunner(ctx context.Context, repository geolocationPersister, totalRoutines int, uploadDir string,
+	opts ...Option) *Runner {
+
+	return &Runner{
+		ctx:           ctx,
+		repository:    repository,
+		totalRoutines: totalRoutines,
+		uploadDir:     uploadDir,
+		opts:          opts,
+	}
+}
+
+// Start imports dumpFile in the background, returning the ID of the import once it's registered.
+// It returns ErrImportRunning if another import is still running, in this or another process.
+func (r *Runner) Start(dumpFile string) (int64, error) {
+	return r.start(dumpFile, func() {})
+}
+
+// Upload stores the contents of src in the upload directory and imports them in the background, returning the ID
+// of the import once it's registered. The stored file is removed once the import is done.
+// It returns ErrImportRunning if another import is still running, in this or another process.
+func (r *Runner) Upload(src io.Reader) (int64, error) {
+	// Failing fast, instead of storing the whole upload only to find out it can't be imported
+	if r.running() {
+		return 0, ErrImportRunning
+	}
+
+	file, err := os.CreateTemp(r.uploadDir, "upload-*.csv")
+	if err != nil {
+		return 0, fmt.Errorf("runner - failed to store upload: %w", err)
+	}
+
+	remove := func() {
+		if err := os.Remove(file.Name()); err != nil {
+			log.Printf("Failed to remove upload %s: %v\n", file.Name(), err)
+		}
+	}
+
+	_, err = io.Copy(file, src)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return 0, fmt.Errorf("runner - failed to store upload: %w", err)
+	}
+
+	return r.start(file.Name(), remove)
+}
+
+// Cancel cancels the running import with the given ID, returning ErrImportNotRunning if it isn't running.
+// The import stops in the background, with its status set to cancelled.
+func (r *Runner) Cancel(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current == nil || r.current.id != id {
+		return ErrImportNotRunning
+	}
+
+	r.current.cancel()
+
+	return nil
+}
+
+// Wait blocks until the running import, if any, is done.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) running() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current != nil
+}
+
+// start imports dumpFile in the background, calling done once the import is over (or right away, if it can't be
+// started).
+func (r *Runner) start(dumpFile string, done func()) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current != nil {
+		done()
+		return 0, ErrImportRunning
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	current := &runningImport{cancel: cancel}
+
+	registered := make(chan int64, 1)
+	result := make(chan error, 1)
+
+	opts := append(r.opts[:len(r.opts):len(r.opts)], func(fp *fileProcessor) {
+		fp.onRegistered = func(id int64) { registered <- id }
+	})
+	fp := NewFileProcessor(r.repository, opts...)
+
+	r.current = current
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+
+		_, err := fp.ExecuteFileImport(ctx, dumpFile, r.totalRoutines)
+		if err != nil {
+			log.Printf("Import of %s failed: %v\n", dumpFile, err)
+		}
+		result <- err
+
+		r.mu.Lock()
+		if r.current == current {
+			r.current = nil
+		}
+		r.mu.Unlock()
+
+		done()
+	}()
+
+	select {
+	case current.id = <-registered:
+		return current.id, nil
+	case err := <-result:
+		// Imports are registered before they run, so one that ended quickly may have been registered too: its
+		// outcome is then recorded, and it's returned like any other
+		select {
+		case current.id = <-registered:
+			return current.id, nil
+		default:
+		}
+
+		// The import ended before being registered, the goroutine clears it once the lock is released
+		return 0, err
+	}
+}
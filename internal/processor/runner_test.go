@@ -0,0 +1,106 @@
+//go:build !integration
+
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+const runnerDump = "ip_address,country_code,country,city,latitude,longitude,mystery_value\n" +
+	"1.1.1.1,BR,Brazil,Brasilia,0,0,1\n"
+
+func Test_Runner_oneAtATime(t *testing.T) {
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	require.NoError(t, os.WriteFile(dumpFile, []byte(runnerDump), 0o600))
+
+	// Imports block until they're cancelled
+	repository := &mockRepository{
+		AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	runner := NewRunner(context.Background(), repository, 1, t.TempDir())
+
+	id, err := runner.Start(dumpFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	_, err = runner.Start(dumpFile)
+	assert.ErrorIs(t, err, ErrImportRunning)
+
+	_, err = runner.Upload(strings.NewReader(runnerDump))
+	assert.ErrorIs(t, err, ErrImportRunning)
+
+	assert.ErrorIs(t, runner.Cancel(2), ErrImportNotRunning)
+	assert.NoError(t, runner.Cancel(id))
+
+	runner.Wait()
+
+	assert.Equal(t, models.ImportStatusCancelled, repository.savedImport().Status)
+	assert.ErrorIs(t, runner.Cancel(id), ErrImportNotRunning)
+}
+
+func Test_Runner_Start_lockedByAnotherProcess(t *testing.T) {
+	dumpFile := filepath.Join(t.TempDir(), "dump.csv")
+	require.NoError(t, os.WriteFile(dumpFile, []byte(runnerDump), 0o600))
+
+	repository := &mockRepository{ImportsLocked: true}
+	runner := NewRunner(context.Background(), repository, 1, t.TempDir())
+
+	_, err := runner.Start(dumpFile)
+	assert.ErrorIs(t, err, ErrImportRunning)
+
+	runner.Wait()
+
+	// The import isn't registered
+	assert.Empty(t, repository.savedImport().Status)
+}
+
+func Test_Runner_Upload(t *testing.T) {
+	uploadDir := t.TempDir()
+
+	repository := &mockRepository{
+		AddLocationInfoFn: func(ctx context.Context, locationInfo models.Geolocation) error {
+			return nil
+		},
+	}
+	runner := NewRunner(context.Background(), repository, 1, uploadDir)
+
+	_, err := runner.Upload(strings.NewReader(runnerDump))
+	require.NoError(t, err)
+
+	runner.Wait()
+
+	imp := repository.savedImport()
+	assert.Equal(t, models.ImportStatusSucceeded, imp.Status)
+	assert.Equal(t, uploadDir, filepath.Dir(imp.SourceFile))
+
+	// The uploaded file is removed once it's imported
+	entries, err := os.ReadDir(uploadDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func Test_Runner_Start_missingFile(t *testing.T) {
+	repository := &mockRepository{}
+	runner := NewRunner(context.Background(), repository, 1, "")
+
+	// The import is registered, then fails in the background
+	id, err := runner.Start("missing_file.csv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	runner.Wait()
+
+	assert.Equal(t, models.ImportStatusFailed, repository.savedImport().Status)
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// ErrThresholdExceeded is returned when the lines of a dump file don't meet the Thresholds.
+var ErrThresholdExceeded = errors.New("error threshold exceeded")
+
+// minLinesChecked is how many lines must be read before the invalid percentage is checked while a file is imported,
+// so a few invalid lines at the start of the file don't stop the import.
+const minLinesChecked = 1000
+
+// Thresholds are the quality gates of a dump file. Imports crossing them fail, leaving the current dataset in
+// place.
+type Thresholds struct {
+	// MaxInvalidPercent is the maximum percentage of invalid lines, 100 allowing any
+	MaxInvalidPercent float64
+	// MinAcceptedLines is the minimum number of valid lines. Files without valid lines never pass
+	MinAcceptedLines uint64
+	// MaxDeltaPercent is the maximum difference between the number of valid lines and the number of rows of the
+	// current dataset, as a percentage of the latter. 0 allows any difference
+	MaxDeltaPercent float64
+}
+
+// DefaultThresholds only rejects files without valid lines.
+func DefaultThresholds() Thresholds {
+	return Thresholds{MaxInvalidPercent: 100}
+}
+
+// Check returns an error wrapping ErrThresholdExceeded if the lines of a file exceed the thresholds. currentLines
+// is the number of rows of the current dataset, 0 if there's none (which skips the delta check).
+func (t Thresholds) Check(lines models.LineCounts, currentLines uint64) error {
+	switch {
+	case lines.Accepted == 0:
+		return fmt.Errorf("%w: no valid lines", ErrThresholdExceeded)
+	case lines.Accepted < t.MinAcceptedLines:
+		return fmt.Errorf("%w: %d valid lines, the minimum is %d", ErrThresholdExceeded, lines.Accepted,
+			t.MinAcceptedLines)
+	}
+
+	if err := t.checkInvalid(lines); err != nil {
+		return err
+	}
+
+	if t.MaxDeltaPercent > 0 && currentLines > 0 {
+		delta := (float64(lines.Accepted) - float64(currentLines)) * 100 / float64(currentLines)
+		if delta > t.MaxDeltaPercent || -delta > t.MaxDeltaPercent {
+			return fmt.Errorf("%w: %d valid lines differ by %.2f%% from the %d rows of the current dataset, "+
+				"the maximum is %.2f%%", ErrThresholdExceeded, lines.Accepted, delta, currentLines, t.MaxDeltaPercent)
+		}
+	}
+
+	return nil
+}
+
+// checkPartial checks the lines read so far while a file is imported, returning an error wrapping
+// ErrThresholdExceeded as soon as it's certain the file can't pass the thresholds: once enough lines were read to
+// tell the percentage of invalid lines, or once there are too many valid lines compared to the current dataset.
+func (t Thresholds) checkPartial(lines models.LineCounts, currentLines uint64) error {
+	if lines.Total >= minLinesChecked {
+		if err := t.checkInvalid(lines); err != nil {
+			return err
+		}
+	}
+
+	if t.MaxDeltaPercent > 0 && currentLines > 0 {
+		maxLines := float64(currentLines) * (1 + t.MaxDeltaPercent/100)
+		if float64(lines.Accepted) > maxLines {
+			return fmt.Errorf("%w: more than %.0f valid lines, %.2f%% above the %d rows of the current dataset",
+				ErrThresholdExceeded, maxLines, t.MaxDeltaPercent, currentLines)
+		}
+	}
+
+	return nil
+}
+
+func (t Thresholds) checkInvalid(lines models.LineCounts) error {
+	if lines.Total == 0 {
+		return nil
+	}
+
+	invalidPercent := float64(lines.Invalid) * 100 / float64(lines.Total)
+	if invalidPercent > t.MaxInvalidPercent {
+		return fmt.Errorf("%w: %.2f%% of the lines are invalid, the maximum is %.2f%%", ErrThresholdExceeded,
+			invalidPercent, t.MaxInvalidPercent)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+//go:build !integration
+
+package processor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+func Test_Thresholds_Check(t *testing.T) {
+	tests := []struct {
+		name         string
+		thresholds   Thresholds
+		lines        models.LineCounts
+		currentLines uint64
+		expectedErr  bool
+	}{
+		{
+			name:       "within thresholds",
+			thresholds: Thresholds{MaxInvalidPercent: 10, MinAcceptedLines: 9, MaxDeltaPercent: 10},
+			lines:      models.LineCounts{Total: 10, Accepted: 9, Invalid: 1},
+			// 9 is 10% less than 10
+			currentLines: 10,
+		},
+		{
+			name:        "too many invalid lines",
+			thresholds:  Thresholds{MaxInvalidPercent: 10},
+			lines:       models.LineCounts{Total: 10, Accepted: 8, Invalid: 2},
+			expectedErr: true,
+		},
+		{
+			name:        "no valid lines",
+			thresholds:  DefaultThresholds(),
+			lines:       models.LineCounts{Total: 10, Invalid: 10},
+			expectedErr: true,
+		},
+		{
+			name:        "too few valid lines",
+			thresholds:  Thresholds{MaxInvalidPercent: 100, MinAcceptedLines: 10},
+			lines:       models.LineCounts{Total: 10, Accepted: 9, Invalid: 1},
+			expectedErr: true,
+		},
+		{
+			name:         "too many rows less than the current dataset",
+			thresholds:   Thresholds{MaxInvalidPercent: 100, MaxDeltaPercent: 10},
+			lines:        models.LineCounts{Total: 10, Accepted: 8, Invalid: 2},
+			currentLines: 10,
+			expectedErr:  true,
+		},
+		{
+			name:         "too many rows more than the current dataset",
+			thresholds:   Thresholds{MaxInvalidPercent: 100, MaxDeltaPercent: 10},
+			lines:        models.LineCounts{Total: 12, Accepted: 12},
+			currentLines: 10,
+			expectedErr:  true,
+		},
+		{
+			name:       "no current dataset skips the delta",
+			thresholds: Thresholds{MaxInvalidPercent: 100, MaxDeltaPercent: 10},
+			lines:      models.LineCounts{Total: 12, Accepted: 12},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.thresholds.Check(test.lines, test.currentLines)
+
+			assert.Equal(t, test.expectedErr, errors.Is(err, ErrThresholdExceeded))
+		})
+	}
+}
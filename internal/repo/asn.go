@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+	"net/netip"
+	"time"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// SaveASNBatch stages the ASN blocks of a batch and records its checkpoint, in a single transaction. Blocks whose
+// network was already staged for the dataset are skipped, and counted as duplicates in the returned checkpoint.
+//
+// Saving a batch that was already committed fails, so each batch is persisted exactly once.
+func (r *repository) SaveASNBatch(ctx context.Context, datasetID int64, blocks []models.ASNBlock,
+	checkpoint models.Checkpoint) (models.Checkpoint, error) {
+
+	insertBlock := `INSERT INTO ` + tableASNInfo + `(dataset_id, network, asn, organization, connection_type)
+                    VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+                    ON CONFLICT (dataset_id, network) DO NOTHING`
+
+	return r.saveBatch(ctx, datasetID, checkpoint, insertBlock, len(blocks), func(i int) ([]interface{}, error) {
+		b := blocks[i]
+
+		return []interface{}{datasetID, b.Network, b.ASN, b.Organization, b.ConnectionType}, nil
+	})
+}
+
+// GetASNByIP gets the most specific network containing an IP as of the given time, or currently if asOf is zero.
+// The IP is expected in canonical form (see models.ParseIP), as IPv4 addresses are only contained by IPv4 networks.
+// err can be sql.ErrNoRows
+func (r *repository) GetASNByIP(ctx context.Context, ip netip.Addr, asOf time.Time) (*models.ASNBlock, error) {
+	q := `SELECT network, asn, organization, COALESCE(connection_type, '')
+            FROM ` + tableASNInfo + `
+           WHERE network >>= $1
+             AND valid_from IS NOT NULL
+             AND valid_to IS NULL
+           ORDER BY masklen(network) DESC
+           LIMIT 1`
+	args := []interface{}{ip.String()}
+
+	if !asOf.IsZero() {
+		q = `SELECT network, asn, organization, COALESCE(connection_type, '')
+               FROM ` + tableASNInfo + `
+              WHERE network >>= $1
+                AND valid_from <= $2
+                AND (valid_to IS NULL OR valid_to > $2)
+              ORDER BY masklen(network) DESC
+              LIMIT 1`
+		args = append(args, asOf)
+	}
+
+	var block models.ASNBlock
+	err := r.db.QueryRowContext(ctx, q, args...).Scan(&block.Network, &block.ASN, &block.Organization,
+		&block.ConnectionType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/tiagocesar/geolocation/internal/geo"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+const tableImportCheckpoints = "import_checkpoints"
+
+// ResumableDataset finds the latest staging dataset of a kind imported from a file with the given checksum, along
+// with the checkpoints of the batches committed to it. A zero ID is returned if there's none.
+func (r *repository) ResumableDataset(ctx context.Context, kind, checksum string) (int64, []models.Checkpoint, error) {
+	q := `SELECT id
+            FROM ` + tableDatasets + `
+           WHERE kind = $1
+             AND status = $2
+             AND checksum = $3
+           ORDER BY id DESC
+           LIMIT 1`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, q, kind, models.DatasetStatusStaging, checksum).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil, nil
+	case err != nil:
+		return 0, nil, err
+	}
+
+	q = `SELECT dataset_id, batch, batch_size, end_line, end_offset, total_lines, accepted_lines, invalid_lines, errors
+           FROM ` + tableImportCheckpoints + `
+          WHERE dataset_id = $1
+          ORDER BY batch`
+
+	rows, err := r.db.QueryContext(ctx, q, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var checkpoints []models.Checkpoint
+	for rows.Next() {
+		var cp models.Checkpoint
+		var errs []byte
+
+		err := rows.Scan(&cp.DatasetID, &cp.Batch, &cp.BatchSize, &cp.EndLine, &cp.EndOffset, &cp.Lines.Total,
+			&cp.Lines.Accepted, &cp.Lines.Invalid, &errs)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if err := json.Unmarshal(errs, &cp.Errors); err != nil {
+			return 0, nil, err
+		}
+
+		checkpoints = append(checkpoints, cp)
+	}
+
+	return id, checkpoints, rows.Err()
+}
+
+// SaveBatch stages the locations of a batch and records its checkpoint, in a single transaction. Locations whose IP
+// was already staged for the dataset are skipped, and counted as duplicates in the returned checkpoint.
+//
+// Saving a batch that was already committed fails, so each batch is persisted exactly once.
+func (r *repository) SaveBatch(ctx context.Context, datasetID int64, locations []models.Geolocation,
+	checkpoint models.Checkpoint) (models.Checkpoint, error) {
+
+	insertLocation := `INSERT INTO ` + tableLocationInfo + `(dataset_id, ip_address, country_code, country, city,
+                                                         latitude, longitude, mystery_value, attributes, region,
+                                                         postal_code, timezone, accuracy_radius, continent,
+                                                         geohash)
+                       VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''),
+                               NULLIF($12, ''), NULLIF($13, 0), NULLIF($14, ''), $15)
+                       ON CONFLICT (dataset_id, ip_address) DO NOTHING`
+
+	return r.saveBatch(ctx, datasetID, checkpoint, insertLocation, len(locations), func(i int) ([]interface{}, error) {
+		l := locations[i]
+
+		attributes, err := marshalAttributes(l.Attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		return []interface{}{datasetID, l.IpAddress, l.CountryCode, l.Country, l.City, l.Latitude, l.Longitude,
+			l.MysteryValue, attributes, l.Region, l.PostalCode, l.Timezone, l.AccuracyRadius, l.Continent,
+			geo.Geohash(geo.Point{Latitude: l.Latitude, Longitude: l.Longitude}, geo.GeohashPrecision)}, nil
+	})
+}
+
+// saveBatch runs insert for each of the n rows of a batch, with the arguments returned by args, and records the
+// checkpoint of the batch, in a single transaction. Rows that aren't inserted (i.e. insert does nothing on
+// conflict) are counted as duplicates in the returned checkpoint.
+func (r *repository) saveBatch(ctx context.Context, datasetID int64, checkpoint models.Checkpoint, insert string,
+	n int, args func(i int) ([]interface{}, error)) (models.Checkpoint, error) {
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, insert)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = stmt.Close() }()
+
+		var duplicates uint64
+		for i := 0; i < n; i++ {
+			rowArgs, err := args(i)
+			if err != nil {
+				return err
+			}
+
+			result, err := stmt.ExecContext(ctx, rowArgs...)
+			if err != nil {
+				return err
+			}
+
+			if inserted, err := result.RowsAffected(); err != nil {
+				return err
+			} else if inserted == 0 {
+				duplicates++
+			}
+		}
+
+		checkpoint.DatasetID = datasetID
+		checkpoint.AddDuplicates(duplicates)
+
+		errs, err := json.Marshal(checkpoint.Errors)
+		if err != nil {
+			return err
+		}
+
+		insertCheckpoint := `INSERT INTO ` + tableImportCheckpoints + `(dataset_id, batch, batch_size, end_line,
+                                                                    end_offset, total_lines, accepted_lines,
+                                                                    invalid_lines, errors)
+                             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+		_, err = tx.ExecContext(ctx, insertCheckpoint, datasetID, checkpoint.Batch, checkpoint.BatchSize,
+			checkpoint.EndLine, checkpoint.EndOffset, checkpoint.Lines.Total, checkpoint.Lines.Accepted,
+			checkpoint.Lines.Invalid, string(errs))
+
+		return err
+	})
+	if err != nil {
+		return models.Checkpoint{}, err
+	}
+
+	return checkpoint, nil
+}
@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+const tableImports = "imports"
+
+// importsLockID identifies the advisory lock held while importing; any constant shared by all processes works, as
+// long as it's not the one of migrations.
+const importsLockID = 7_412_390_052
+
+const importColumns = `id, COALESCE(dataset_id, 0), source_file, file_size, bytes_read, status, total_lines,
+                       accepted_lines, invalid_lines, errors, conflicts, total_conflicts, COALESCE(error, ''),
+                       started_at, updated_at, finished_at, read_started_at, resumed_bytes, resumed_lines`
+
+// LockImports takes the imports advisory lock, so only one import runs at a time across every process sharing the
+// database. ok is false if the lock is held by another import. Advisory locks belong to a session, so the lock is
+// held by a connection of its own until unlock is called, or released by Postgres if the process dies.
+func (r *repository) LockImports(ctx context.Context) (unlock func(), ok bool, err error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, importsLockID).Scan(&ok); err != nil || !ok {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	unlock = func() {
+		// Using a fresh context, so the lock is released even if the import was cancelled
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, importsLockID)
+		_ = conn.Close()
+	}
+
+	return unlock, true, nil
+}
+
+// CreateImport registers an import that's starting.
+func (r *repository) CreateImport(ctx context.Context, imp models.Import) (int64, error) {
+	q := `INSERT INTO ` + tableImports + `(source_file, file_size, status)
+          VALUES ($1, $2, $3)
+          RETURNING id`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, q, imp.SourceFile, imp.FileSize, imp.Status).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// SaveImportProgress updates the status and progress of an import.
+func (r *repository) SaveImportProgress(ctx context.Context, imp models.Import) error {
+	errs, err := json.Marshal(imp.Errors)
+	if err != nil {
+		return err
+	}
+
+	conflicts := imp.Conflicts
+	if conflicts == nil {
+		conflicts = []models.Conflict{}
+	}
+
+	conflictsJSON, err := json.Marshal(conflicts)
+	if err != nil {
+		return err
+	}
+
+	q := `UPDATE ` + tableImports + `
+             SET dataset_id = NULLIF($1, 0), bytes_read = $2, status = $3, total_lines = $4, accepted_lines = $5,
+                 invalid_lines = $6, errors = $7, conflicts = $8, total_conflicts = $9, error = NULLIF($10, ''),
+                 finished_at = $11, read_started_at = $12, resumed_bytes = $13, resumed_lines = $14, updated_at = now()
+           WHERE id = $15`
+
+	_, err = r.db.ExecContext(ctx, q, imp.DatasetID, imp.BytesRead, imp.Status, imp.Lines.Total, imp.Lines.Accepted,
+		imp.Lines.Invalid, string(errs), string(conflictsJSON), imp.TotalConflicts, imp.Error, imp.FinishedAt,
+		imp.ReadStartedAt, imp.ResumedBytes, imp.ResumedLines, imp.ID)
+
+	return err
+}
+
+// ListImports lists the latest imports, most recent first.
+func (r *repository) ListImports(ctx context.Context, limit int) ([]models.Import, error) {
+	q := `SELECT ` + importColumns + `
+            FROM ` + tableImports + `
+           ORDER BY started_at DESC, id DESC
+           LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []models.Import
+	for rows.Next() {
+		imp, err := scanImport(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *imp)
+	}
+
+	return result, rows.Err()
+}
+
+// GetImport gets an import by its ID. err can be sql.ErrNoRows
+func (r *repository) GetImport(ctx context.Context, id int64) (*models.Import, error) {
+	q := `SELECT ` + importColumns + `
+            FROM ` + tableImports + `
+           WHERE id = $1`
+
+	return scanImport(r.db.QueryRowContext(ctx, q, id))
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanImport(row scanner) (*models.Import, error) {
+	var imp models.Import
+	var errs, conflicts []byte
+	var finishedAt, readStartedAt sql.NullTime
+
+	err := row.Scan(&imp.ID, &imp.DatasetID, &imp.SourceFile, &imp.FileSize, &imp.BytesRead, &imp.Status,
+		&imp.Lines.Total, &imp.Lines.Accepted, &imp.Lines.Invalid, &errs, &conflicts, &imp.TotalConflicts, &imp.Error,
+		&imp.StartedAt, &imp.UpdatedAt, &finishedAt, &readStartedAt, &imp.ResumedBytes, &imp.ResumedLines)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(errs, &imp.Errors); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(conflicts, &imp.Conflicts); err != nil {
+		return nil, err
+	}
+
+	if finishedAt.Valid {
+		imp.FinishedAt = &finishedAt.Time
+	}
+
+	if readStartedAt.Valid {
+		imp.ReadStartedAt = &readStartedAt.Time
+	}
+
+	return &imp, nil
+}
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS location_info
+(
+    id            serial
+        primary key,
+    ip_address    inet             not null,
+    country_code  varchar(10)      not null,
+    country       varchar(50)      not null,
+    city          varchar(50)      not null,
+    latitude      double precision not null,
+    longitude     double precision not null,
+    mystery_value varchar(100)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS location_info_ip_address_uindex
+    ON location_info (ip_address);
@@ -0,0 +1,19 @@
+-- Only the rows currently valid are kept
+DELETE
+  FROM location_info
+ WHERE valid_from IS NULL
+    OR valid_to IS NOT NULL;
+
+DROP INDEX location_info_ip_address_validity_index;
+DROP INDEX location_info_current_ip_address_uindex;
+DROP INDEX location_info_dataset_ip_address_uindex;
+
+CREATE UNIQUE INDEX location_info_ip_address_uindex
+    ON location_info (ip_address);
+
+ALTER TABLE location_info
+    DROP COLUMN dataset_id,
+    DROP COLUMN valid_from,
+    DROP COLUMN valid_to;
+
+DROP TABLE datasets;
@@ -0,0 +1,51 @@
+-- Each import creates a dataset. Its rows are staged (valid_from is null) until the import succeeds, when the
+-- dataset is activated: rows of the previous dataset get their valid_to set and the new rows their valid_from.
+CREATE TABLE datasets
+(
+    id             serial
+        primary key,
+    source_file    text        not null,
+    checksum       varchar(64) not null,
+    status         varchar(20) not null,
+    total_lines    bigint      not null default 0,
+    accepted_lines bigint      not null default 0,
+    invalid_lines  bigint      not null default 0,
+    started_at     timestamptz not null default now(),
+    finished_at    timestamptz,
+    activated_at   timestamptz,
+    retired_at     timestamptz
+);
+
+CREATE INDEX datasets_checksum_index
+    ON datasets (checksum);
+
+ALTER TABLE location_info
+    ADD COLUMN dataset_id integer REFERENCES datasets (id),
+    ADD COLUMN valid_from timestamptz,
+    ADD COLUMN valid_to   timestamptz;
+
+-- Rows imported before datasets existed become the first, active dataset
+INSERT INTO datasets (source_file, checksum, status, accepted_lines, finished_at, activated_at)
+SELECT 'unknown', '', 'active', count(*), now(), now()
+  FROM location_info
+HAVING count(*) > 0;
+
+UPDATE location_info
+   SET dataset_id = (SELECT max(id) FROM datasets),
+       valid_from = now();
+
+ALTER TABLE location_info
+    ALTER COLUMN dataset_id SET NOT NULL;
+
+DROP INDEX location_info_ip_address_uindex;
+
+CREATE UNIQUE INDEX location_info_dataset_ip_address_uindex
+    ON location_info (dataset_id, ip_address);
+
+-- Only one row per IP can be valid at any time
+CREATE UNIQUE INDEX location_info_current_ip_address_uindex
+    ON location_info (ip_address)
+    WHERE valid_from IS NOT NULL AND valid_to IS NULL;
+
+CREATE INDEX location_info_ip_address_validity_index
+    ON location_info (ip_address, valid_from, valid_to);
@@ -0,0 +1,22 @@
+-- Tracks each run of the importer, with its progress updated while it runs
+CREATE TABLE imports
+(
+    id             serial
+        primary key,
+    dataset_id     integer REFERENCES datasets (id),
+    source_file    text        not null,
+    file_size      bigint      not null default 0,
+    bytes_read     bigint      not null default 0,
+    status         varchar(20) not null,
+    total_lines    bigint      not null default 0,
+    accepted_lines bigint      not null default 0,
+    invalid_lines  bigint      not null default 0,
+    errors         jsonb       not null default '{}',
+    error          text,
+    started_at     timestamptz not null default now(),
+    updated_at     timestamptz not null default now(),
+    finished_at    timestamptz
+);
+
+CREATE INDEX imports_started_at_index
+    ON imports (started_at);
@@ -0,0 +1,3 @@
+DROP INDEX datasets_status_checksum_index;
+
+DROP TABLE import_checkpoints;
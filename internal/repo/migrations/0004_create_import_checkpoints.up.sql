@@ -0,0 +1,19 @@
+-- Lines are persisted in batches of consecutive lines; each batch is committed together with its checkpoint, so an
+-- interrupted import of the same file can resume its staging dataset, skipping the batches already committed.
+CREATE TABLE import_checkpoints
+(
+    dataset_id     integer     not null REFERENCES datasets (id),
+    batch          bigint      not null,
+    batch_size     integer     not null,
+    end_line       bigint      not null,
+    end_offset     bigint      not null,
+    total_lines    bigint      not null,
+    accepted_lines bigint      not null,
+    invalid_lines  bigint      not null,
+    errors         jsonb       not null default '{}',
+    committed_at   timestamptz not null default now(),
+    primary key (dataset_id, batch)
+);
+
+CREATE INDEX datasets_status_checksum_index
+    ON datasets (status, checksum);
@@ -0,0 +1,3 @@
+ALTER TABLE imports
+    DROP COLUMN conflicts,
+    DROP COLUMN total_conflicts;
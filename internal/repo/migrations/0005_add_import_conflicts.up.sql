@@ -0,0 +1,4 @@
+-- The first IPs found on several lines of a dump file with conflicting data, out of total_conflicts
+ALTER TABLE imports
+    ADD COLUMN conflicts       jsonb  not null default '[]',
+    ADD COLUMN total_conflicts bigint not null default 0;
@@ -0,0 +1,2 @@
+ALTER TABLE location_info
+    DROP COLUMN attributes;
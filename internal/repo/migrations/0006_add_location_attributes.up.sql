@@ -0,0 +1,3 @@
+-- Values of the columns of the dump file that aren't mapped to their own column, by column name
+ALTER TABLE location_info
+    ADD COLUMN attributes jsonb not null default '{}';
@@ -0,0 +1,6 @@
+ALTER TABLE location_info
+    DROP COLUMN region,
+    DROP COLUMN postal_code,
+    DROP COLUMN timezone,
+    DROP COLUMN accuracy_radius,
+    DROP COLUMN continent;
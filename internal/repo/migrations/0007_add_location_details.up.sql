@@ -0,0 +1,7 @@
+-- Optional details of a location, null on rows imported from dump files without them
+ALTER TABLE location_info
+    ADD COLUMN region          varchar(100),
+    ADD COLUMN postal_code     varchar(20),
+    ADD COLUMN timezone        varchar(64),
+    ADD COLUMN accuracy_radius integer,
+    ADD COLUMN continent       varchar(50);
@@ -0,0 +1,19 @@
+DROP TABLE asn_info;
+
+-- Only location datasets are kept, their imports losing track of the others
+DELETE
+  FROM import_checkpoints
+ WHERE dataset_id IN (SELECT id FROM datasets WHERE kind <> 'location');
+
+UPDATE imports
+   SET dataset_id = NULL
+ WHERE dataset_id IN (SELECT id FROM datasets WHERE kind <> 'location');
+
+DELETE
+  FROM datasets
+ WHERE kind <> 'location';
+
+DROP INDEX datasets_kind_status_index;
+
+ALTER TABLE datasets
+    DROP COLUMN kind;
@@ -0,0 +1,32 @@
+-- Datasets hold either locations (location_info) or the owners of IP networks (asn_info), each kind with its own
+-- active dataset
+ALTER TABLE datasets
+    ADD COLUMN kind varchar(20) not null default 'location';
+
+CREATE INDEX datasets_kind_status_index
+    ON datasets (kind, status);
+
+CREATE TABLE asn_info
+(
+    id              serial
+        primary key,
+    dataset_id      integer      not null REFERENCES datasets (id),
+    network         cidr         not null,
+    asn             bigint       not null,
+    organization    varchar(200) not null,
+    connection_type varchar(20),
+    valid_from      timestamptz,
+    valid_to        timestamptz
+);
+
+CREATE UNIQUE INDEX asn_info_dataset_network_uindex
+    ON asn_info (dataset_id, network);
+
+-- Only one row per network can be valid at any time
+CREATE UNIQUE INDEX asn_info_current_network_uindex
+    ON asn_info (network)
+    WHERE valid_from IS NOT NULL AND valid_to IS NULL;
+
+-- Looking up the networks containing an IP
+CREATE INDEX asn_info_network_index
+    ON asn_info USING gist (network inet_ops);
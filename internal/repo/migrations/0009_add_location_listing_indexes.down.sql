@@ -0,0 +1,2 @@
+DROP INDEX location_info_current_country_city_ip_address_index;
+DROP INDEX location_info_current_country_ip_address_index;
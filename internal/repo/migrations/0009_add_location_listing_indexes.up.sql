@@ -0,0 +1,8 @@
+-- Listing the current locations of a country, or of a city of a country, sorted by IP
+CREATE INDEX location_info_current_country_ip_address_index
+    ON location_info (country_code, ip_address)
+    WHERE valid_from IS NOT NULL AND valid_to IS NULL;
+
+CREATE INDEX location_info_current_country_city_ip_address_index
+    ON location_info (country_code, city, ip_address)
+    WHERE valid_from IS NOT NULL AND valid_to IS NULL;
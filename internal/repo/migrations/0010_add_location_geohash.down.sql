@@ -0,0 +1,4 @@
+DROP INDEX location_info_current_geohash_index;
+
+ALTER TABLE location_info
+    DROP COLUMN geohash;
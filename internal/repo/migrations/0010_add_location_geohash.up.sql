@@ -0,0 +1,69 @@
+-- Geohash of the coordinates of each location, with 12 characters (see the geo package). Locations in the same area
+-- share a prefix, so the locations near a point are found by ranges of the index over it. The "C" collation sorts
+-- the geohashes byte by byte, so a prefix and the geohashes starting with it sort together
+ALTER TABLE location_info
+    ADD COLUMN geohash varchar(12) COLLATE "C";
+
+-- Filling the geohash of the existing rows, the same way the importer does
+CREATE FUNCTION pg_temp.geohash_encode(lat double precision, lon double precision, chars integer)
+    RETURNS text AS
+$$
+DECLARE
+    alphabet constant text := '0123456789bcdefghjkmnpqrstuvwxyz';
+    min_lat           double precision := -90;
+    max_lat           double precision := 90;
+    min_lon           double precision := -180;
+    max_lon           double precision := 180;
+    mid               double precision;
+    hash              text             := '';
+    ch                integer          := 0;
+    bits              integer          := 0;
+    even              boolean          := true;
+BEGIN
+    WHILE length(hash) < chars
+        LOOP
+            IF even THEN
+                mid := (min_lon + max_lon) / 2;
+                IF lon >= mid THEN
+                    ch := ch * 2 + 1;
+                    min_lon := mid;
+                ELSE
+                    ch := ch * 2;
+                    max_lon := mid;
+                END IF;
+            ELSE
+                mid := (min_lat + max_lat) / 2;
+                IF lat >= mid THEN
+                    ch := ch * 2 + 1;
+                    min_lat := mid;
+                ELSE
+                    ch := ch * 2;
+                    max_lat := mid;
+                END IF;
+            END IF;
+
+            even := NOT even;
+            bits := bits + 1;
+            IF bits = 5 THEN
+                hash := hash || substr(alphabet, ch + 1, 1);
+                ch := 0;
+                bits := 0;
+            END IF;
+        END LOOP;
+
+    RETURN hash;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+UPDATE location_info
+   SET geohash = pg_temp.geohash_encode(latitude, longitude, 12);
+
+DROP FUNCTION pg_temp.geohash_encode(double precision, double precision, integer);
+
+ALTER TABLE location_info
+    ALTER COLUMN geohash SET NOT NULL;
+
+-- Finding the current locations in an area
+CREATE INDEX location_info_current_geohash_index
+    ON location_info (geohash)
+    WHERE valid_from IS NOT NULL AND valid_to IS NULL;
@@ -0,0 +1,2 @@
+-- Unmapped addresses can't be told apart from the ones imported in IPv4 form, and lookups only find the latter, so
+-- there's nothing to revert
@@ -0,0 +1,30 @@
+-- IPs and networks are stored in canonical form (see models.ParseIP and models.ParseNetwork), but older imports kept
+-- them as written in the dump files. IPv4-mapped IPv6 addresses (::ffff:1.2.3.4) are a different inet than their
+-- IPv4 address, so they were never found by lookups of the IPv4 address.
+
+-- Where a dataset has both forms of an IP, the IPv4 one is kept
+DELETE
+  FROM location_info mapped
+ WHERE mapped.ip_address <<= '::ffff:0.0.0.0/96'
+   AND EXISTS(SELECT 1
+                FROM location_info plain
+               WHERE plain.dataset_id = mapped.dataset_id
+                 AND plain.ip_address = '0.0.0.0'::inet + (mapped.ip_address - '::ffff:0.0.0.0'::inet));
+
+UPDATE location_info
+   SET ip_address = '0.0.0.0'::inet + (ip_address - '::ffff:0.0.0.0'::inet)
+ WHERE ip_address <<= '::ffff:0.0.0.0/96';
+
+-- The same for the networks of the ASN datasets, ::ffff:1.2.3.0/120 being 1.2.3.0/24
+DELETE
+  FROM asn_info mapped
+ WHERE mapped.network <<= '::ffff:0.0.0.0/96'
+   AND EXISTS(SELECT 1
+                FROM asn_info plain
+               WHERE plain.dataset_id = mapped.dataset_id
+                 AND plain.network = set_masklen('0.0.0.0'::inet + (mapped.network - '::ffff:0.0.0.0'::inet),
+                                                 masklen(mapped.network) - 96)::cidr);
+
+UPDATE asn_info
+   SET network = set_masklen('0.0.0.0'::inet + (network - '::ffff:0.0.0.0'::inet), masklen(network) - 96)::cidr
+ WHERE network <<= '::ffff:0.0.0.0/96';
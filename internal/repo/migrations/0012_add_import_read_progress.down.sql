@@ -0,0 +1,4 @@
+ALTER TABLE imports
+    DROP COLUMN read_started_at,
+    DROP COLUMN resumed_bytes,
+    DROP COLUMN resumed_lines;
@@ -0,0 +1,6 @@
+-- When the file of an import started being read in batches, and the bytes and lines a resumed import skipped, so
+-- its throughput and ETA only count what it read
+ALTER TABLE imports
+    ADD COLUMN read_started_at timestamptz,
+    ADD COLUMN resumed_bytes   bigint not null default 0,
+    ADD COLUMN resumed_lines   bigint not null default 0;
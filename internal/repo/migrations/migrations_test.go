@@ -0,0 +1,82 @@
+//go:build !integration
+
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Load(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	// Versions start at 1 and have no gaps
+	for i, m := range migrations {
+		require.Equal(t, i+1, m.Version)
+	}
+}
+
+func Test_load(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       fstest.MapFS
+		expected    []Migration
+		expectedErr bool
+	}{
+		{
+			name: "migrations are sorted by version",
+			files: fstest.MapFS{
+				"0002_second.up.sql":   {Data: []byte("up 2")},
+				"0002_second.down.sql": {Data: []byte("down 2")},
+				"0001_first.up.sql":    {Data: []byte("up 1")},
+				"0001_first.down.sql":  {Data: []byte("down 1")},
+			},
+			expected: []Migration{
+				{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+				{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+			},
+		},
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"0001_first.up.sql": {Data: []byte("up 1")},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid file name",
+			files: fstest.MapFS{
+				"first.sql": {Data: []byte("up 1")},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "same version with different names",
+			files: fstest.MapFS{
+				"0001_first.up.sql":   {Data: []byte("up 1")},
+				"0001_other.down.sql": {Data: []byte("down 1")},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations, err := load(test.files)
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, migrations)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/tiagocesar/geolocation/internal/geo"
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// maxGeohashCells is how many geohash cells, at most, cover the area of a spatial query. Each cell is a range of the
+// geohash index; more cells fit the area more closely, reading fewer rows outside of it.
+const maxGeohashCells = 32
+
+// LocationsNear lists up to limit current locations within radiusKm of center, the nearest first (ties sorted by
+// IP).
+//
+// The locations are first narrowed down to the geohash cells covering the circle, through the geohash index, then
+// their distance is calculated by the Haversine formula.
+func (r *repository) LocationsNear(ctx context.Context, center geo.Point, radiusKm float64,
+	limit int) ([]models.NearbyLocation, error) {
+
+	// $1 and $2 are the coordinates of the center
+	distance := `2 * ` + earthRadiusKm + ` * asin(least(1, sqrt(
+                          power(sin(radians(latitude - $1) / 2), 2) +
+                          cos(radians($1)) * cos(radians(latitude)) * power(sin(radians(longitude - $2) / 2), 2))))`
+
+	args := []interface{}{center.Latitude, center.Longitude, radiusKm}
+	cells, args := geohashRanges(geo.CoveringGeohashes(geo.BoxAround(center, radiusKm), maxGeohashCells), args)
+
+	args = append(args, limit)
+	q := `SELECT ` + locationColumns + `, ` + distance + ` AS distance
+            FROM ` + tableLocationInfo + `
+           WHERE valid_from IS NOT NULL
+             AND valid_to IS NULL
+             AND (` + cells + `)
+             AND ` + distance + ` <= $3
+           ORDER BY distance, ip_address
+           LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []models.NearbyLocation
+	for rows.Next() {
+		var distance float64
+		location, err := scanLocation(rows, &distance)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, models.NearbyLocation{Geolocation: *location, DistanceKm: distance})
+	}
+
+	return result, rows.Err()
+}
+
+// LocationsWithin lists up to limit current locations inside box, sorted by IP.
+//
+// Like LocationsNear, the locations are first narrowed down to the geohash cells covering the box.
+func (r *repository) LocationsWithin(ctx context.Context, box geo.Box, limit int) ([]models.Geolocation, error) {
+	args := []interface{}{box.MinLatitude, box.MaxLatitude, box.MinLongitude, box.MaxLongitude}
+	cells, args := geohashRanges(geo.CoveringGeohashes(box, maxGeohashCells), args)
+
+	longitude := `longitude BETWEEN $3 AND $4`
+	if box.MinLongitude > box.MaxLongitude {
+		// Crossing the antimeridian
+		longitude = `(longitude >= $3 OR longitude <= $4)`
+	}
+
+	args = append(args, limit)
+	q := `SELECT ` + locationColumns + `
+            FROM ` + tableLocationInfo + `
+           WHERE valid_from IS NOT NULL
+             AND valid_to IS NULL
+             AND (` + cells + `)
+             AND latitude BETWEEN $1 AND $2
+             AND ` + longitude + `
+           ORDER BY ip_address
+           LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []models.Geolocation
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *location)
+	}
+
+	return result, rows.Err()
+}
+
+// earthRadiusKm is geo.EarthRadiusKm, for the queries.
+var earthRadiusKm = strconv.FormatFloat(geo.EarthRadiusKm, 'f', -1, 64)
+
+// geohashRanges returns the condition selecting the rows whose geohash starts with one of prefixes, as ranges of the
+// geohash index, along with args and the placeholders of the condition appended.
+func geohashRanges(prefixes []string, args []interface{}) (string, []interface{}) {
+	ranges := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		// Geohashes are sorted byte by byte, and '~' comes after every character of their alphabet
+		args = append(args, prefix, prefix+"~")
+		from, to := strconv.Itoa(len(args)-1), strconv.Itoa(len(args))
+		ranges = append(ranges, `(geohash >= $`+from+` AND geohash < $`+to+`)`)
+	}
+
+	return strings.Join(ranges, ` OR `), args
+}
@@ -3,29 +3,100 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/tiagocesar/geolocation/internal/models"
+	"github.com/tiagocesar/geolocation/internal/repo/migrations"
 )
 
-const tableLocationInfo = "public.location_info"
+const (
+	tableLocationInfo = "location_info"
+	tableASNInfo      = "asn_info"
+	tableDatasets     = "datasets"
+)
+
+// Config describes how to connect to the database, either through a full DSN or its individual parts.
+type Config struct {
+	// DSN is a full connection string (URL or key=value format). When set, the connection fields below are ignored.
+	DSN string
+
+	User     string
+	Password string
+	Host     string
+	Port     int
+	Database string
+	// Schema is set as the search_path of every connection.
+	Schema string
+	// SSLMode is one of the sslmode values supported by Postgres (disable, require, verify-ca, verify-full).
+	SSLMode     string
+	SSLRootCert string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectTimeout is how long to keep retrying, with exponential backoff, until the database is ready.
+	ConnectTimeout time.Duration
+}
+
+// ConnectionString returns the DSN if set, or a connection URL built from the individual connection fields,
+// with credentials properly escaped.
+func (c Config) ConnectionString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+
+	query := url.Values{}
+	if c.SSLMode != "" {
+		query.Set("sslmode", c.SSLMode)
+	}
+	if c.SSLRootCert != "" {
+		query.Set("sslrootcert", c.SSLRootCert)
+	}
+	if c.Schema != "" {
+		query.Set("search_path", c.Schema)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
 
 type repository struct {
 	db *sql.DB
 }
 
-func NewRepository(user, pass, host, port, schema string) (*repository, error) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, pass, host, port, schema)
-
-	db, err := sql.Open("postgres", connStr)
+// NewRepository opens the connection pool described by cfg, waiting for the database to be ready for up to
+// cfg.ConnectTimeout.
+func NewRepository(ctx context.Context, cfg Config) (*repository, error) {
+	db, err := sql.Open("postgres", cfg.ConnectionString())
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.Ping()
-	if err != nil {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := ping(ctx, db, cfg.ConnectTimeout); err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
@@ -34,30 +105,353 @@ func NewRepository(user, pass, host, port, schema string) (*repository, error) {
 	}, nil
 }
 
-func (r *repository) AddLocationInfo(ctx context.Context, locationInfo models.Geolocation) error {
-	q := `INSERT INTO ` + tableLocationInfo + `(ip_address, country_code, country, city, latitude, longitude, mystery_value)
-          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+// ping checks the connection to the database, retrying with exponential backoff until timeout is reached.
+func ping(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := 100 * time.Millisecond
+	const maxBackoff = 5 * time.Second
+
+	for {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("Database not ready, retrying in %s: %v\n", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not ready after %s: %w", timeout, err)
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Migrator returns a migrator for the database schema.
+func (r *repository) Migrator() (*migrations.Migrator, error) {
+	return migrations.NewMigrator(r.db)
+}
+
+// CreateDataset registers a new dataset of a kind (one of the models.DatasetKind* values) for the import of
+// sourceFile, in staging status. Staging datasets of the same kind left by interrupted imports can't be resumed
+// anymore, so they're marked as failed and their staged rows and checkpoints removed.
+func (r *repository) CreateDataset(ctx context.Context, kind, sourceFile, checksum string) (int64, error) {
+	table, err := datasetTable(kind)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		staging := `SELECT id
+                      FROM ` + tableDatasets + `
+                     WHERE kind = $1
+                       AND status = $2`
+
+		deleteRows := `DELETE FROM ` + table + `
+                        WHERE valid_from IS NULL
+                          AND dataset_id IN (` + staging + `)`
+		if _, err := tx.ExecContext(ctx, deleteRows, kind, models.DatasetStatusStaging); err != nil {
+			return err
+		}
+
+		deleteCheckpoints := `DELETE FROM ` + tableImportCheckpoints + `
+                               WHERE dataset_id IN (` + staging + `)`
+		if _, err := tx.ExecContext(ctx, deleteCheckpoints, kind, models.DatasetStatusStaging); err != nil {
+			return err
+		}
+
+		failDatasets := `UPDATE ` + tableDatasets + `
+                            SET status = $1, finished_at = now()
+                          WHERE kind = $2
+                            AND status = $3`
+		_, err := tx.ExecContext(ctx, failDatasets, models.DatasetStatusFailed, kind, models.DatasetStatusStaging)
+		if err != nil {
+			return err
+		}
 
-	_, err := r.db.ExecContext(ctx, q, locationInfo.IpAddress, locationInfo.CountryCode, locationInfo.Country,
-		locationInfo.City, locationInfo.Latitude, locationInfo.Longitude, locationInfo.MysteryValue)
+		createDataset := `INSERT INTO ` + tableDatasets + `(kind, source_file, checksum, status)
+                          VALUES ($1, $2, $3, $4)
+                          RETURNING id`
+
+		return tx.QueryRowContext(ctx, createDataset, kind, sourceFile, checksum, models.DatasetStatusStaging).
+			Scan(&id)
+	})
 	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// DatasetImported tells if a file with the given checksum was already imported successfully, i.e. a dataset with
+// that checksum is or was active.
+func (r *repository) DatasetImported(ctx context.Context, checksum string) (bool, error) {
+	q := `SELECT EXISTS (SELECT 1
+                           FROM ` + tableDatasets + `
+                          WHERE checksum = $1
+                            AND status IN ($2, $3))`
+
+	var imported bool
+	err := r.db.QueryRowContext(ctx, q, checksum, models.DatasetStatusActive, models.DatasetStatusRetired).
+		Scan(&imported)
+
+	return imported, err
+}
+
+// ActiveDatasetLines gets the number of rows of the active dataset of a kind, 0 if there's none.
+func (r *repository) ActiveDatasetLines(ctx context.Context, kind string) (uint64, error) {
+	q := `SELECT coalesce(max(accepted_lines), 0)
+            FROM ` + tableDatasets + `
+           WHERE kind = $1
+             AND status = $2`
+
+	var lines uint64
+	err := r.db.QueryRowContext(ctx, q, kind, models.DatasetStatusActive).Scan(&lines)
+
+	return lines, err
+}
+
+// ActiveDatasetID gets the ID of the active dataset of a kind, 0 if there's none.
+func (r *repository) ActiveDatasetID(ctx context.Context, kind string) (int64, error) {
+	q := `SELECT coalesce(max(id), 0)
+            FROM ` + tableDatasets + `
+           WHERE kind = $1
+             AND status = $2`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, q, kind, models.DatasetStatusActive).Scan(&id)
+
+	return id, err
+}
+
+// ActivateDataset makes the staged rows of a dataset the current ones, retiring the previously active dataset of
+// the same kind.
+func (r *repository) ActivateDataset(ctx context.Context, id int64, lines models.LineCounts) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now()
+
+		kind, table, err := datasetKind(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		retireRows := `UPDATE ` + table + `
+                          SET valid_to = $1
+                        WHERE valid_from IS NOT NULL
+                          AND valid_to IS NULL`
+		if _, err := tx.ExecContext(ctx, retireRows, now); err != nil {
+			return err
+		}
+
+		activateRows := `UPDATE ` + table + `
+                            SET valid_from = $1
+                          WHERE dataset_id = $2`
+		if _, err := tx.ExecContext(ctx, activateRows, now, id); err != nil {
+			return err
+		}
+
+		retireDatasets := `UPDATE ` + tableDatasets + `
+                              SET status = $1, retired_at = $2
+                            WHERE kind = $3
+                              AND status = $4`
+		_, err = tx.ExecContext(ctx, retireDatasets, models.DatasetStatusRetired, now, kind,
+			models.DatasetStatusActive)
+		if err != nil {
+			return err
+		}
+
+		activateDataset := `UPDATE ` + tableDatasets + `
+                               SET status = $1, total_lines = $2, accepted_lines = $3, invalid_lines = $4,
+                                   finished_at = $5, activated_at = $5
+                             WHERE id = $6`
+		_, err = tx.ExecContext(ctx, activateDataset, models.DatasetStatusActive, lines.Total, lines.Accepted,
+			lines.Invalid, now, id)
+
+		return err
+	})
+}
+
+// FailDataset marks a dataset as failed, removing its staged rows and checkpoints.
+func (r *repository) FailDataset(ctx context.Context, id int64, lines models.LineCounts) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		_, table, err := datasetKind(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		deleteRows := `DELETE FROM ` + table + `
+                        WHERE dataset_id = $1
+                          AND valid_from IS NULL`
+		if _, err := tx.ExecContext(ctx, deleteRows, id); err != nil {
+			return err
+		}
+
+		deleteCheckpoints := `DELETE FROM ` + tableImportCheckpoints + `
+                               WHERE dataset_id = $1`
+		if _, err := tx.ExecContext(ctx, deleteCheckpoints, id); err != nil {
+			return err
+		}
+
+		failDataset := `UPDATE ` + tableDatasets + `
+                           SET status = $1, total_lines = $2, accepted_lines = $3, invalid_lines = $4,
+                               finished_at = now()
+                         WHERE id = $5`
+		_, err = tx.ExecContext(ctx, failDataset, models.DatasetStatusFailed, lines.Total, lines.Accepted,
+			lines.Invalid, id)
+
 		return err
+	})
+}
+
+// datasetKind gets the kind of a dataset, along with the table of its rows.
+func datasetKind(ctx context.Context, tx *sql.Tx, id int64) (string, string, error) {
+	q := `SELECT kind
+            FROM ` + tableDatasets + `
+           WHERE id = $1`
+
+	var kind string
+	if err := tx.QueryRowContext(ctx, q, id).Scan(&kind); err != nil {
+		return "", "", err
 	}
 
-	return nil
+	table, err := datasetTable(kind)
+
+	return kind, table, err
+}
+
+// datasetTable returns the table of the rows of the datasets of a kind.
+func datasetTable(kind string) (string, error) {
+	switch kind {
+	case models.DatasetKindLocation:
+		return tableLocationInfo, nil
+	case models.DatasetKindASN:
+		return tableASNInfo, nil
+	default:
+		return "", fmt.Errorf("unknown dataset kind %q", kind)
+	}
 }
 
-func (r *repository) GetLocationInfoByIP(ctx context.Context, ipAddress string) (*models.Geolocation, error) {
-	q := `SELECT ip_address, country_code, country, city, latitude, longitude
+// locationColumns are the columns of a location, the optional ones defaulting to their zero values.
+const locationColumns = `ip_address, country_code, country, city, latitude, longitude, COALESCE(mystery_value, ''),
+                         attributes, COALESCE(region, ''), COALESCE(postal_code, ''), COALESCE(timezone, ''),
+                         COALESCE(accuracy_radius, 0), COALESCE(continent, '')`
+
+// GetLocationInfoByIP gets the location of an IP as of the given time, or the current location if asOf is zero.
+// The IP is expected in canonical form (see models.ParseIP), as stored by the importer.
+func (r *repository) GetLocationInfoByIP(ctx context.Context, ip netip.Addr,
+	asOf time.Time) (*models.Geolocation, error) {
+
+	q := `SELECT ` + locationColumns + `
 		    FROM ` + tableLocationInfo + `
-           WHERE ip_address = $1`
+           WHERE ip_address = $1
+             AND valid_from IS NOT NULL
+             AND valid_to IS NULL`
+	args := []interface{}{ip.String()}
+
+	if !asOf.IsZero() {
+		q = `SELECT ` + locationColumns + `
+		       FROM ` + tableLocationInfo + `
+              WHERE ip_address = $1
+                AND valid_from <= $2
+                AND (valid_to IS NULL OR valid_to > $2)`
+		args = append(args, asOf)
+	}
 
-	var response models.Geolocation
 	// err can be sql.ErrNoRows
-	err := r.db.QueryRowContext(ctx, q, ipAddress).Scan(&response.IpAddress, &response.CountryCode, &response.Country,
-		&response.City, &response.Latitude, &response.Longitude)
+	return scanLocation(r.db.QueryRowContext(ctx, q, args...))
+}
+
+// ListLocations lists up to limit current locations matching filter, sorted by IP (IPv4 before IPv6, like
+// netip.Addr.Compare), starting after the IP after (from the first one if it's the zero netip.Addr).
+func (r *repository) ListLocations(ctx context.Context, filter models.LocationFilter, after netip.Addr,
+	limit int) ([]models.Geolocation, error) {
+
+	q := `SELECT ` + locationColumns + `
+            FROM ` + tableLocationInfo + `
+           WHERE country_code = $1
+             AND valid_from IS NOT NULL
+             AND valid_to IS NULL`
+	args := []interface{}{filter.CountryCode}
+
+	if filter.City != "" {
+		args = append(args, filter.City)
+		q += ` AND city = $` + strconv.Itoa(len(args))
+	}
+
+	if after.IsValid() {
+		args = append(args, after.String())
+		q += ` AND ip_address > $` + strconv.Itoa(len(args))
+	}
+
+	args = append(args, limit)
+	q += ` ORDER BY ip_address LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &response, nil
+	defer func() { _ = rows.Close() }()
+
+	var result []models.Geolocation
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *location)
+	}
+
+	return result, rows.Err()
+}
+
+// scanLocation scans a row of locationColumns, followed by the columns scanned into extra.
+func scanLocation(row scanner, extra ...interface{}) (*models.Geolocation, error) {
+	var location models.Geolocation
+	var attributes []byte
+
+	dest := append([]interface{}{&location.IpAddress, &location.CountryCode, &location.Country, &location.City,
+		&location.Latitude, &location.Longitude, &location.MysteryValue, &attributes, &location.Region,
+		&location.PostalCode, &location.Timezone, &location.AccuracyRadius, &location.Continent}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(attributes, &location.Attributes); err != nil {
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+// marshalAttributes encodes the attributes of a location for the attributes column, which can't be null.
+func marshalAttributes(attributes map[string]string) (string, error) {
+	if len(attributes) == 0 {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(attributes)
+
+	return string(b), err
+}
+
+func (r *repository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
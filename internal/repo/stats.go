@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// currentLocations selects the rows of the active location dataset.
+const currentLocations = `FROM ` + tableLocationInfo + `
+                         WHERE valid_from IS NOT NULL
+                           AND valid_to IS NULL`
+
+// LocationStats summarizes the current locations, with up to topCities cities per country.
+//
+// Everything is read from the same snapshot of the database, so the stats are consistent even if a dataset is
+// activated in the meantime.
+func (r *repository) LocationStats(ctx context.Context, topCities int) (*models.Stats, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stats := &models.Stats{Countries: []models.CountryStats{}, Prefixes: []models.PrefixStats{}}
+
+	dataset := `SELECT id, source_file, activated_at
+                  FROM ` + tableDatasets + `
+                 WHERE kind = $1
+                   AND status = $2`
+	var activatedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, dataset, models.DatasetKindLocation, models.DatasetStatusActive).
+		Scan(&stats.DatasetID, &stats.SourceFile, &activatedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// No locations were imported yet
+		return stats, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if activatedAt.Valid {
+		stats.ActivatedAt = &activatedAt.Time
+	}
+
+	totals := `SELECT count(*), count(*) FILTER (WHERE family(ip_address) = 4),
+                      count(*) FILTER (WHERE family(ip_address) = 6)
+                 ` + currentLocations
+	err = tx.QueryRowContext(ctx, totals).Scan(&stats.TotalLocations, &stats.IPv4Locations, &stats.IPv6Locations)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Countries, err = countryStats(ctx, tx, topCities); err != nil {
+		return nil, err
+	}
+
+	if stats.Prefixes, err = prefixStats(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// countryStats counts the current locations per country, and per city of the top cities of each country.
+func countryStats(ctx context.Context, tx *sql.Tx, topCities int) ([]models.CountryStats, error) {
+	countries := `SELECT country_code, max(country), count(*)
+                    ` + currentLocations + `
+                   GROUP BY country_code
+                   ORDER BY count(*) DESC, country_code`
+
+	rows, err := tx.QueryContext(ctx, countries)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := []models.CountryStats{}
+	byCode := make(map[string]int)
+	for rows.Next() {
+		c := models.CountryStats{TopCities: []models.CityStats{}}
+		if err := rows.Scan(&c.CountryCode, &c.Country, &c.Locations); err != nil {
+			return nil, err
+		}
+
+		byCode[c.CountryCode] = len(result)
+		result = append(result, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cities := `SELECT country_code, city, locations
+                 FROM (SELECT country_code, city, count(*) AS locations,
+                              row_number() OVER (PARTITION BY country_code ORDER BY count(*) DESC, city) AS rank
+                         ` + currentLocations + `
+                        GROUP BY country_code, city) AS cities
+                WHERE rank <= $1
+                ORDER BY country_code, rank`
+
+	cityRows, err := tx.QueryContext(ctx, cities, topCities)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cityRows.Close() }()
+
+	for cityRows.Next() {
+		var countryCode string
+		var c models.CityStats
+		if err := cityRows.Scan(&countryCode, &c.City, &c.Locations); err != nil {
+			return nil, err
+		}
+
+		if i, ok := byCode[countryCode]; ok {
+			result[i].TopCities = append(result[i].TopCities, c)
+		}
+	}
+
+	return result, cityRows.Err()
+}
+
+// prefixStats counts the current IPv4 locations per /8 prefix.
+func prefixStats(ctx context.Context, tx *sql.Tx) ([]models.PrefixStats, error) {
+	q := `SELECT network(set_masklen(ip_address, 8)) AS prefix, count(*)
+            ` + currentLocations + `
+             AND family(ip_address) = 4
+           GROUP BY prefix
+           ORDER BY prefix`
+
+	rows, err := tx.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := []models.PrefixStats{}
+	for rows.Next() {
+		var p models.PrefixStats
+		if err := rows.Scan(&p.Prefix, &p.Locations); err != nil {
+			return nil, err
+		}
+
+		result = append(result, p)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,221 @@
+// Package resolver resolves hostnames into the IP addresses they're looked up by.
+//
+// Resolvers are pluggable: System resolves through the DNS of the host, and a Static resolver through a fixed list
+// of hosts (e.g. a hosts file), for tests and offline use. Any of them can be wrapped by a Cache, which limits how
+// long resolving takes and remembers the addresses of recently resolved hosts.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrHostNotFound is returned when a host has no addresses.
+var ErrHostNotFound = errors.New("host not found")
+
+// Resolver resolves a hostname into its IP addresses, in canonical form (see models.ParseIP) and without
+// duplicates. A host without addresses gets ErrHostNotFound.
+type Resolver interface {
+	Resolve(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// System resolves hostnames through the DNS of the host, as configured for the Go resolver.
+func System() Resolver {
+	return &systemResolver{resolver: net.DefaultResolver}
+}
+
+type systemResolver struct {
+	resolver *net.Resolver
+}
+
+func (r *systemResolver) Resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	addrs, err := r.resolver.LookupNetIP(ctx, "ip", host)
+
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		break
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return nil, ErrHostNotFound
+	case ctx.Err() != nil:
+		// Timeouts are reported as DNS errors, which don't tell if the deadline of ctx was exceeded
+		return nil, ctx.Err()
+	default:
+		return nil, err
+	}
+
+	return canonical(addrs)
+}
+
+// canonical returns addrs in canonical form, without duplicates, ErrHostNotFound if there are none.
+func canonical(addrs []netip.Addr) ([]netip.Addr, error) {
+	result := make([]netip.Addr, 0, len(addrs))
+	seen := make(map[netip.Addr]bool, len(addrs))
+	for _, addr := range addrs {
+		addr = addr.Unmap().WithZone("")
+		if !seen[addr] {
+			seen[addr] = true
+			result = append(result, addr)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrHostNotFound
+	}
+
+	return result, nil
+}
+
+// ValidHost reports if host is a hostname that can be resolved: labels of letters, digits and hyphens separated by
+// dots, the last one not being numeric, so malformed IPs (e.g. 1.2.3) aren't mistaken for hostnames.
+func ValidHost(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || len(host) > 253 {
+		return false
+	}
+
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return strings.Trim(labels[len(labels)-1], "0123456789") != ""
+}
+
+// Cache wraps a Resolver, limiting how long resolving a host takes and caching the addresses of resolved hosts.
+// Only hosts that were resolved are cached, so a host that wasn't found is looked up again the next time.
+type Cache struct {
+	resolver   Resolver
+	timeout    time.Duration
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+// CacheOption configures a Cache created via NewCache.
+type CacheOption func(*Cache)
+
+// WithTimeout sets how long resolving a host can take, 0 being no limit other than the deadline of the caller.
+// Defaults to 2 seconds.
+func WithTimeout(timeout time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.timeout = timeout
+	}
+}
+
+// WithTTL sets for how long the addresses of a host are cached, 0 disabling the cache. Defaults to 5 minutes.
+func WithTTL(ttl time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxEntries sets how many hosts are cached at most. Defaults to 1000.
+func WithMaxEntries(n int) CacheOption {
+	return func(c *Cache) {
+		c.maxEntries = n
+	}
+}
+
+// NewCache wraps resolver with a timeout and a cache.
+func NewCache(resolver Resolver, opts ...CacheOption) *Cache {
+	c := &Cache{
+		resolver:   resolver,
+		timeout:    2 * time.Second,
+		ttl:        5 * time.Minute,
+		maxEntries: 1000,
+		now:        time.Now,
+		entries:    make(map[string]cacheEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *Cache) Resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	key := hostKey(host)
+
+	if addrs, ok := c.cached(key); ok {
+		return addrs, nil
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	addrs, err := c.resolver.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(key, addrs)
+
+	return addrs, nil
+}
+
+// cached returns the addresses of host, if they're cached and haven't expired.
+func (c *Cache) cached(host string) ([]netip.Addr, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[host]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+
+	return entry.addrs, true
+}
+
+// store caches the addresses of host, making room for them if the cache is full.
+func (c *Cache) store(host string, addrs []netip.Addr) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[host]; !ok && len(c.entries) >= c.maxEntries {
+		for h, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, h)
+			}
+		}
+
+		// If not enough of them expired, any of them goes
+		for h := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, h)
+		}
+	}
+
+	c.entries[host] = cacheEntry{addrs: addrs, expires: now.Add(c.ttl)}
+}
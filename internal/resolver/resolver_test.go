@@ -0,0 +1,129 @@
+//go:build !integration
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingResolver counts the hosts it resolves, resolving them via resolve.
+type countingResolver struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	resolve func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+func (r *countingResolver) Resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	r.mu.Lock()
+	r.calls[host]++
+	r.mu.Unlock()
+
+	return r.resolve(ctx, host)
+}
+
+func newCountingResolver(resolver Resolver) *countingResolver {
+	return &countingResolver{calls: make(map[string]int), resolve: resolver.Resolve}
+}
+
+func Test_ValidHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "example.com", expected: true},
+		{host: "EXAMPLE.com.", expected: true},
+		{host: "localhost", expected: true},
+		{host: "a-1.example.com", expected: true},
+		{host: "1.example.com", expected: true},
+		{host: "1.2.3"},
+		{host: "1.2.3.4"},
+		{host: "::1"},
+		{host: ""},
+		{host: "example..com"},
+		{host: "-example.com"},
+		{host: "example.com/path"},
+		{host: "exa mple.com"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.host, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, ValidHost(test.host))
+		})
+	}
+}
+
+func Test_Cache(t *testing.T) {
+	static := NewStatic(map[string][]netip.Addr{
+		"example.com": {netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")},
+	})
+	counting := newCountingResolver(static)
+
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	cache := NewCache(counting, WithTTL(time.Minute))
+	cache.now = func() time.Time { return now }
+
+	expected := []netip.Addr{netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")}
+
+	// Resolved once while cached, whatever the case of the hostname
+	for _, host := range []string{"example.com", "Example.COM", "example.com."} {
+		addrs, err := cache.Resolve(context.Background(), host)
+		require.NoError(t, err)
+		assert.Equal(t, expected, addrs)
+	}
+	assert.Equal(t, 1, counting.calls["example.com"])
+
+	// Hosts that weren't found aren't cached
+	for i := 0; i < 2; i++ {
+		_, err := cache.Resolve(context.Background(), "missing.example.com")
+		assert.ErrorIs(t, err, ErrHostNotFound)
+	}
+	assert.Equal(t, 2, counting.calls["missing.example.com"])
+
+	// Resolved again once expired
+	now = now.Add(time.Minute)
+	_, err := cache.Resolve(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 2, counting.calls["example.com"])
+}
+
+func Test_Cache_maxEntries(t *testing.T) {
+	static := NewStatic(map[string][]netip.Addr{
+		"a.example.com": {netip.MustParseAddr("192.0.2.1")},
+		"b.example.com": {netip.MustParseAddr("192.0.2.2")},
+		"c.example.com": {netip.MustParseAddr("192.0.2.3")},
+	})
+	cache := NewCache(static, WithMaxEntries(2))
+
+	for _, host := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		_, err := cache.Resolve(context.Background(), host)
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, cache.entries, 2)
+	assert.Contains(t, cache.entries, "c.example.com")
+}
+
+func Test_Cache_timeout(t *testing.T) {
+	slow := &countingResolver{
+		calls: make(map[string]int),
+		resolve: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	cache := NewCache(slow, WithTimeout(10*time.Millisecond))
+
+	_, err := cache.Resolve(context.Background(), "example.com")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/tiagocesar/geolocation/internal/models"
+)
+
+// Static resolves hostnames from a fixed list of hosts, without any network access.
+type Static struct {
+	hosts map[string][]netip.Addr
+}
+
+// NewStatic creates a Static resolver of the given hosts and their addresses.
+func NewStatic(hosts map[string][]netip.Addr) *Static {
+	s := &Static{hosts: make(map[string][]netip.Addr, len(hosts))}
+	for host, addrs := range hosts {
+		key := hostKey(host)
+		s.hosts[key] = append(s.hosts[key], addrs...)
+	}
+
+	return s
+}
+
+// LoadHostsFile creates a Static resolver of the hosts listed in a file in the format of /etc/hosts (see ParseHosts).
+func LoadHostsFile(path string) (*Static, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolver - failed to open hosts file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return ParseHosts(f)
+}
+
+// ParseHosts creates a Static resolver of the hosts listed in the format of /etc/hosts: an IP per line followed by
+// its hostnames, separated by spaces, with comments starting with #. Hostnames listed in several lines resolve to
+// all of their IPs.
+func ParseHosts(r io.Reader) (*Static, error) {
+	hosts := make(map[string][]netip.Addr)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 1 {
+			return nil, fmt.Errorf("resolver - hosts line %d: no hostnames for %s", line, fields[0])
+		}
+
+		addr, err := models.ParseIP(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("resolver - hosts line %d: invalid IP %q", line, fields[0])
+		}
+
+		for _, host := range fields[1:] {
+			hosts[host] = append(hosts[host], addr)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("resolver - failed to read hosts: %w", err)
+	}
+
+	return NewStatic(hosts), nil
+}
+
+func (s *Static) Resolve(_ context.Context, host string) ([]netip.Addr, error) {
+	return canonical(s.hosts[hostKey(host)])
+}
+
+// hostKey is the form hostnames are compared in: case-insensitive, and without the dot of fully qualified names.
+func hostKey(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,90 @@
+//go:build !integration
+
+package resolver
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseHosts(t *testing.T) {
+	hosts := "# Unit tests\n" +
+		"192.0.2.1    example.com www.example.com\n" +
+		"\n" +
+		"2001:DB8::1  example.com # IPv6\n" +
+		"::ffff:192.0.2.2 mapped.example.com\n" +
+		"192.0.2.1    EXAMPLE.com\n"
+
+	static, err := ParseHosts(strings.NewReader(hosts))
+	require.NoError(t, err)
+
+	tests := []struct {
+		host        string
+		expected    []string
+		expectedErr error
+	}{
+		{host: "example.com", expected: []string{"192.0.2.1", "2001:db8::1"}},
+		{host: "WWW.example.com.", expected: []string{"192.0.2.1"}},
+		{host: "mapped.example.com", expected: []string{"192.0.2.2"}},
+		{host: "missing.example.com", expectedErr: ErrHostNotFound},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.host, func(t *testing.T) {
+			t.Parallel()
+
+			addrs, err := static.Resolve(context.Background(), test.host)
+			assert.Equal(t, test.expectedErr, err)
+
+			var got []string
+			for _, addr := range addrs {
+				got = append(got, addr.String())
+			}
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func Test_ParseHosts_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts string
+	}{
+		{name: "invalid IP", hosts: "192.0.2 example.com\n"},
+		{name: "IP with a zone", hosts: "fe80::1%eth0 example.com\n"},
+		{name: "no hostnames", hosts: "192.0.2.1\n"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseHosts(strings.NewReader(test.hosts))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_LoadHostsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(path, []byte("192.0.2.1 example.com\n"), 0o600))
+
+	static, err := LoadHostsFile(path)
+	require.NoError(t, err)
+
+	addrs, err := static.Resolve(context.Background(), "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("192.0.2.1")}, addrs)
+
+	_, err = LoadHostsFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,307 @@
+// Package watcher imports the dump files dropped into a directory.
+//
+// A file is imported once it's fully written: either a marker file with the same name plus a ".done" suffix exists
+// (e.g. dump.csv.done), or its size and modification time haven't changed for a while. Files are then moved to the
+// archive/ or failed/ subdirectories, depending on the result of the import; files whose contents were already
+// imported are archived without being imported again.
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/tiagocesar/geolocation/internal/processor"
+)
+
+const (
+	ArchiveDir = "archive"
+	FailedDir  = "failed"
+
+	// DoneSuffix is the suffix of the marker files telling that a file is fully written.
+	DoneSuffix = ".done"
+)
+
+// ImportFunc imports a file.
+type ImportFunc func(ctx context.Context, filename string) error
+
+type datasetChecker interface {
+	DatasetImported(ctx context.Context, checksum string) (bool, error)
+}
+
+type Watcher struct {
+	dir          string
+	repository   datasetChecker
+	importFile   ImportFunc
+	pollInterval time.Duration
+	stableFor    time.Duration
+	now          func() time.Time
+
+	// pending are the files found in the directory that aren't known to be fully written yet
+	pending map[string]fileState
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	// since is when the file was first seen with this size and modification time
+	since time.Time
+}
+
+// Option configures a Watcher created via New.
+type Option func(*Watcher)
+
+// WithPollInterval sets how often the directory is scanned, which is the only way new files are found if
+// filesystem notifications aren't available (e.g. on some network filesystems). Defaults to 1 minute.
+func WithPollInterval(interval time.Duration) Option {
+	return func(w *Watcher) {
+		w.pollInterval = interval
+	}
+}
+
+// WithStableFor sets for how long the size of a file without a marker must not change before it's imported.
+// Defaults to 10 seconds.
+func WithStableFor(d time.Duration) Option {
+	return func(w *Watcher) {
+		w.stableFor = d
+	}
+}
+
+// New creates a Watcher for dir, importing files via importFile. repository tells which files were already
+// imported.
+func New(dir string, repository datasetChecker, importFile ImportFunc, opts ...Option) *Watcher {
+	w := &Watcher{
+		dir:          dir,
+		repository:   repository,
+		importFile:   importFile,
+		pollInterval: time.Minute,
+		stableFor:    10 * time.Second,
+		now:          time.Now,
+		pending:      make(map[string]fileState),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// Run watches the directory, importing files one at a time, until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	for _, sub := range []string{ArchiveDir, FailedDir} {
+		if err := os.MkdirAll(filepath.Join(w.dir, sub), 0o755); err != nil {
+			return fmt.Errorf("watcher - failed to create %s directory: %w", sub, err)
+		}
+	}
+
+	events := w.notifications(ctx)
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	// Files waiting to be stable are checked again once they could be
+	recheck := time.NewTimer(w.stableFor)
+	defer recheck.Stop()
+
+	for {
+		if err := w.scan(ctx); err != nil {
+			log.Printf("Watching %s: %v\n", w.dir, err)
+		}
+
+		if len(w.pending) > 0 {
+			if !recheck.Stop() {
+				select {
+				case <-recheck.C:
+				default:
+				}
+			}
+			recheck.Reset(w.stableFor)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-events:
+		case <-ticker.C:
+		case <-recheck.C:
+		}
+	}
+}
+
+// notifications returns a channel receiving a value whenever the directory changes. If filesystem notifications
+// aren't available, the channel never receives anything and changes are only found by polling.
+func (w *Watcher) notifications(ctx context.Context) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	notifier, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = notifier.Add(w.dir)
+		if err != nil {
+			_ = notifier.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("Filesystem notifications unavailable for %s, polling every %s: %v\n", w.dir, w.pollInterval, err)
+		return changes
+	}
+
+	go func() {
+		defer func() { _ = notifier.Close() }()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-notifier.Events:
+				if !ok {
+					return
+				}
+
+				// Coalescing events, since every scan looks at the whole directory
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case err, ok := <-notifier.Errors:
+				if !ok {
+					return
+				}
+
+				log.Printf("Watching %s: %v\n", w.dir, err)
+			}
+		}
+	}()
+
+	return changes
+}
+
+// scan imports the files of the directory that are fully written, in name order.
+func (w *Watcher) scan(ctx context.Context) error {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	var ready []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, DoneSuffix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed in the meantime
+			continue
+		}
+
+		if names[name+DoneSuffix] || w.stable(name, info) {
+			ready = append(ready, name)
+		}
+	}
+
+	// Forgetting files that are gone
+	for name := range w.pending {
+		if !names[name] {
+			delete(w.pending, name)
+		}
+	}
+
+	sort.Strings(ready)
+	for _, name := range ready {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		delete(w.pending, name)
+		w.process(ctx, name)
+	}
+
+	return nil
+}
+
+// stable tells if a file's size and modification time haven't changed for the stable duration.
+func (w *Watcher) stable(name string, info os.FileInfo) bool {
+	now := w.now()
+
+	state, ok := w.pending[name]
+	if !ok || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+		w.pending[name] = fileState{size: info.Size(), modTime: info.ModTime(), since: now}
+		return false
+	}
+
+	return now.Sub(state.since) >= w.stableFor
+}
+
+// process imports a file, unless its contents were already imported, and moves it out of the directory.
+// Files are left in place if ctx is cancelled while they're imported, or another import is running, to be imported
+// again.
+func (w *Watcher) process(ctx context.Context, name string) {
+	filename := filepath.Join(w.dir, name)
+
+	checksum, err := processor.FileChecksum(filename)
+	if err != nil {
+		log.Printf("Failed to read %s: %v\n", filename, err)
+		w.move(name, FailedDir)
+		return
+	}
+
+	imported, err := w.repository.DatasetImported(ctx, checksum)
+	switch {
+	case err != nil:
+		// Trying again on the next scan
+		log.Printf("Failed to check if %s was already imported: %v\n", filename, err)
+		return
+	case imported:
+		log.Printf("Skipping %s, its contents were already imported\n", filename)
+		w.move(name, ArchiveDir)
+		return
+	}
+
+	log.Printf("Importing %s\n", filename)
+	err = w.importFile(ctx, filename)
+	switch {
+	case err == nil:
+		w.move(name, ArchiveDir)
+	case ctx.Err() != nil:
+		log.Printf("Import of %s cancelled, it will be imported again: %v\n", filename, err)
+	case errors.Is(err, processor.ErrImportRunning):
+		log.Printf("Import of %s postponed, another import is running\n", filename)
+	default:
+		log.Printf("Import of %s failed: %v\n", filename, err)
+		w.move(name, FailedDir)
+	}
+}
+
+// move moves a file, and its marker if any, to a subdirectory. Existing files aren't overwritten: the moved file
+// gets a timestamp added to its name instead.
+func (w *Watcher) move(name, sub string) {
+	if err := os.Remove(filepath.Join(w.dir, name+DoneSuffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove the marker of %s: %v\n", name, err)
+	}
+
+	target := filepath.Join(w.dir, sub, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(w.dir, sub,
+			fmt.Sprintf("%s-%s%s", strings.TrimSuffix(name, ext), w.now().Format("20060102T150405"), ext))
+	}
+
+	if err := os.Rename(filepath.Join(w.dir, name), target); err != nil {
+		log.Printf("Failed to move %s to %s: %v\n", name, sub, err)
+	}
+}
@@ -0,0 +1,162 @@
+//go:build !integration
+
+package watcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tiagocesar/geolocation/internal/processor"
+)
+
+type mockRepository struct {
+	imported map[string]bool
+}
+
+func (m *mockRepository) DatasetImported(_ context.Context, checksum string) (bool, error) {
+	return m.imported[checksum], nil
+}
+
+// importer records the files it imports, failing the ones named in fail. The first busy imports find another
+// import running.
+type importer struct {
+	mu       sync.Mutex
+	imported []string
+	fail     map[string]bool
+	busy     int
+}
+
+func (i *importer) importFile(_ context.Context, filename string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.imported = append(i.imported, filepath.Base(filename))
+	if i.busy > 0 {
+		i.busy--
+		return processor.ErrImportRunning
+	}
+	if i.fail[filepath.Base(filename)] {
+		return errors.New("import failed")
+	}
+
+	return nil
+}
+
+func (i *importer) files() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return append([]string(nil), i.imported...)
+}
+
+// run runs a watcher over dir until the test is over.
+func run(t *testing.T, dir string, repository datasetChecker, imp *importer, stableFor time.Duration) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	w := New(dir, repository, imp.importFile, WithPollInterval(10*time.Millisecond), WithStableFor(stableFor))
+	go func() {
+		defer close(done)
+		assert.NoError(t, w.Run(ctx))
+	}()
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func Test_Watcher(t *testing.T) {
+	dir := t.TempDir()
+
+	imp := &importer{fail: map[string]bool{"bad.csv": true}}
+	run(t, dir, &mockRepository{}, imp, 50*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "good.csv"), []byte("good"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.csv"), []byte("bad"), 0o600))
+
+	require.Eventually(t, func() bool {
+		return exists(filepath.Join(dir, ArchiveDir, "good.csv")) && exists(filepath.Join(dir, FailedDir, "bad.csv"))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.ElementsMatch(t, []string{"good.csv", "bad.csv"}, imp.files())
+	assert.False(t, exists(filepath.Join(dir, "good.csv")))
+	assert.False(t, exists(filepath.Join(dir, "bad.csv")))
+}
+
+func Test_Watcher_doneMarker(t *testing.T) {
+	dir := t.TempDir()
+
+	imp := &importer{}
+	// Files without a marker are only imported once they're stable for long enough, which won't happen here
+	run(t, dir, &mockRepository{}, imp, time.Hour)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partial.csv"), []byte("partial"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dump.csv"), []byte("dump"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dump.csv"+DoneSuffix), nil, 0o600))
+
+	require.Eventually(t, func() bool {
+		return exists(filepath.Join(dir, ArchiveDir, "dump.csv"))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"dump.csv"}, imp.files())
+	assert.False(t, exists(filepath.Join(dir, "dump.csv"+DoneSuffix)))
+	assert.True(t, exists(filepath.Join(dir, "partial.csv")))
+}
+
+func Test_Watcher_importRunning(t *testing.T) {
+	dir := t.TempDir()
+
+	imp := &importer{busy: 2}
+	run(t, dir, &mockRepository{}, imp, 20*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dump.csv"), []byte("dump"), 0o600))
+
+	// The file stays in place until the other import is done
+	require.Eventually(t, func() bool {
+		return exists(filepath.Join(dir, ArchiveDir, "dump.csv"))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []string{"dump.csv", "dump.csv", "dump.csv"}, imp.files())
+	assert.False(t, exists(filepath.Join(dir, FailedDir, "dump.csv")))
+}
+
+func Test_Watcher_alreadyImported(t *testing.T) {
+	dir := t.TempDir()
+
+	filename := filepath.Join(dir, "dump.csv")
+	require.NoError(t, os.WriteFile(filename, []byte("dump"), 0o600))
+
+	checksum, err := processor.FileChecksum(filename)
+	require.NoError(t, err)
+
+	// A previous copy of the file is already in the archive
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ArchiveDir), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ArchiveDir, "dump.csv"), []byte("dump"), 0o600))
+
+	imp := &importer{}
+	run(t, dir, &mockRepository{imported: map[string]bool{checksum: true}}, imp, 50*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return !exists(filename)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	entries, err := os.ReadDir(filepath.Join(dir, ArchiveDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Empty(t, imp.files())
+}
@@ -0,0 +1,70 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tiagocesar/geolocation/internal/config"
+	"github.com/tiagocesar/geolocation/internal/models"
+	"github.com/tiagocesar/geolocation/internal/processor"
+	"github.com/tiagocesar/geolocation/internal/repo"
+)
+
+// Test_ASNImport imports the sample ASN file (asn_dump_sample.csv), with 4 lines of which 1 is invalid, and looks up
+// the networks of a few IPs.
+func Test_ASNImport(t *testing.T) {
+	cfg, err := config.Load("integration", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrateUp(ctx, repository)
+
+	fp := processor.NewFileProcessor(repository, processor.WithDataset(models.DatasetKindASN))
+
+	result, err := fp.ExecuteFileImport(ctx, "asn_dump_sample.csv", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, models.LineCounts{Total: 4, Accepted: 3, Invalid: 1}, result.Lines)
+
+	// The most specific network containing the IP is found
+	block, err := repository.GetASNByIP(ctx, netip.MustParseAddr("1.1.1.1"), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ASNBlock{Network: "1.1.1.0/24", ASN: 64497, Organization: "Integration Testing",
+		ConnectionType: models.ConnectionTypeHosting}, block)
+
+	block, err = repository.GetASNByIP(ctx, netip.MustParseAddr("1.1.200.1"), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(64496), block.ASN)
+
+	// IPv6 networks contain IPv6 addresses the same way
+	block, err = repository.GetASNByIP(ctx, netip.MustParseAddr("2001:db8::1"), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::/32", block.Network)
+
+	_, err = repository.GetASNByIP(ctx, netip.MustParseAddr("9.9.9.9"), time.Time{})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	testRepository, err := NewRepositoryForIntegrationTesting(repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := testRepository.CleanASN(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
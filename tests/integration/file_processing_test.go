@@ -4,26 +4,23 @@ package integration
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"database/sql"
 	"log"
+	"net/netip"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/tiagocesar/geolocation/internal/config"
+	"github.com/tiagocesar/geolocation/internal/geo"
+	"github.com/tiagocesar/geolocation/internal/models"
 	"github.com/tiagocesar/geolocation/internal/processor"
 	"github.com/tiagocesar/geolocation/internal/repo"
 )
 
-const (
-	EnvDbUser   = "DB_USER"
-	EnvDbPass   = "DB_PASS"
-	EnvDbHost   = "DB_HOST"
-	EnvDbPort   = "DB_PORT"
-	EnvDbSchema = "DB_SCHEMA"
-)
-
 // Test_NewFileProcessor will do integration testing for the file processing package.
 // This acts on some assumptions based on the sample file available on this folder
 // (data_dump_sample.csv):
@@ -31,32 +28,39 @@ const (
 // Total lines to process: 10
 // Invalid lines: 3
 func Test_NewFileProcessor(t *testing.T) {
-	envVars, err := getEnvVars()
+	cfg, err := config.Load("integration", nil)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if err := cfg.DB.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	beforeImport := time.Now()
+
 	// Configuring access to the repository and opening the SQL connection
-	repository, err := repo.NewRepository(envVars[EnvDbUser], envVars[EnvDbPass], envVars[EnvDbHost],
-		envVars[EnvDbPort], envVars[EnvDbSchema])
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	ctx := context.Background()
+	migrateUp(ctx, repository)
+
 	fp := processor.NewFileProcessor(repository)
 
-	fp.ExecuteFileImport(ctx, "data_dump_sample.csv", 10)
+	result, err := fp.ExecuteFileImport(ctx, "data_dump_sample.csv", 10)
+	assert.NoError(t, err)
 
 	// Based on the sample file:
 	// Total lines to process: 10
 	// Invalid lines: 3
-	assert.Equal(t, uint64(10), fp.TotalLines)
-	assert.Equal(t, uint64(3), fp.InvalidLines)
+	assert.Equal(t, uint64(10), result.Lines.Total)
+	assert.Equal(t, uint64(3), result.Lines.Invalid)
 
 	// Configuring a new repository - with testing methods - to check the data that was inserted
-	testRepository, err := NewRepositoryForIntegrationTesting(envVars[EnvDbUser], envVars[EnvDbPass], envVars[EnvDbHost],
-		envVars[EnvDbPort], envVars[EnvDbSchema])
+	testRepository, err := NewRepositoryForIntegrationTesting(repoConfig(cfg.DB))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -72,6 +76,76 @@ func Test_NewFileProcessor(t *testing.T) {
 	// Lines that should have been inserted: 7
 	assert.True(t, len(rows) == 7)
 
+	// The imported dataset is the current one, but wasn't active before the import
+	location, err := repository.GetLocationInfoByIP(ctx, netip.MustParseAddr("1.1.1.1"), time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, "DuBuquemouth", location.City)
+	assert.Equal(t, "7823011346", location.MysteryValue)
+
+	_, err = repository.GetLocationInfoByIP(ctx, netip.MustParseAddr("1.1.1.1"), beforeImport)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// The imported locations are listed by country, sorted by IP, a page at a time
+	filter := models.LocationFilter{CountryCode: "ZZZ"}
+	page, err := repository.ListLocations(ctx, filter, netip.Addr{}, 5)
+	assert.NoError(t, err)
+	if assert.Len(t, page, 5) {
+		assert.Equal(t, "1.1.1.1", page[0].IpAddress)
+
+		page, err = repository.ListLocations(ctx, filter, netip.MustParseAddr(page[4].IpAddress), 5)
+		assert.NoError(t, err)
+		assert.Len(t, page, 2)
+	}
+
+	page, err = repository.ListLocations(ctx, models.LocationFilter{CountryCode: "ZZZ", City: "DuBuquemouth"},
+		netip.Addr{}, 5)
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+
+	// The imported locations are found by area
+	nearby, err := repository.LocationsNear(ctx, geo.Point{Latitude: -49, Longitude: -86}, 100, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, nearby, 1) {
+		assert.Equal(t, "1.1.1.3", nearby[0].IpAddress)
+		assert.InDelta(t, 19, nearby[0].DistanceKm, 1)
+	}
+
+	within, err := repository.LocationsWithin(ctx,
+		geo.Box{MinLatitude: -90, MaxLatitude: -30, MinLongitude: 140, MaxLongitude: -160}, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, within, 2) {
+		assert.Equal(t, "1.1.1.4", within[0].IpAddress)
+		assert.Equal(t, "1.1.1.6", within[1].IpAddress)
+	}
+
+	// The stats summarize the imported dataset
+	stats, err := repository.LocationStats(ctx, 3)
+	assert.NoError(t, err)
+	if assert.NotNil(t, stats) {
+		datasetID, err := repository.ActiveDatasetID(ctx, models.DatasetKindLocation)
+		assert.NoError(t, err)
+		assert.Equal(t, datasetID, stats.DatasetID)
+		assert.Equal(t, "data_dump_sample.csv", stats.SourceFile)
+		assert.Equal(t, uint64(7), stats.TotalLocations)
+		assert.Equal(t, uint64(7), stats.IPv4Locations)
+		assert.Equal(t, []models.PrefixStats{{Prefix: "1.0.0.0/8", Locations: 7}}, stats.Prefixes)
+		if assert.Len(t, stats.Countries, 1) {
+			assert.Equal(t, uint64(7), stats.Countries[0].Locations)
+			assert.Equal(t, []models.CityStats{{City: "DuBuquemouth", Locations: 1}, {City: "Elyseberg", Locations: 1},
+				{City: "Gradymouth", Locations: 1}}, stats.Countries[0].TopCities)
+		}
+	}
+
+	// The import was tracked, and is the most recent one
+	imports, err := repository.ListImports(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, imports, 1) {
+		assert.Equal(t, models.ImportStatusSucceeded, imports[0].Status)
+		assert.Equal(t, models.LineCounts{Total: 10, Accepted: 7, Invalid: 3}, imports[0].Lines)
+		assert.Equal(t, imports[0].FileSize, imports[0].BytesRead)
+		assert.NotNil(t, imports[0].FinishedAt)
+	}
+
 	// Cleaning up the db
 	err = testRepository.CleanDB(ctx)
 	if err != nil {
@@ -79,31 +153,182 @@ func Test_NewFileProcessor(t *testing.T) {
 	}
 }
 
-// getEnvVars gets all environment variables necessary for this service to run.
-func getEnvVars() (map[string]string, error) {
-	result := make(map[string]string)
-	var ok bool
+// Test_NewFileProcessor_resume checks that importing a file again resumes its interrupted import, based on the
+// same sample file as Test_NewFileProcessor.
+func Test_NewFileProcessor_resume(t *testing.T) {
+	cfg, err := config.Load("integration", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrateUp(ctx, repository)
+
+	checksum, err := processor.FileChecksum("data_dump_sample.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	contents, err := os.ReadFile("data_dump_sample.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Simulating an import interrupted after committing its first batch, of the first 2 lines after the header
+	datasetID, err := repository.CreateDataset(ctx, models.DatasetKindLocation, "data_dump_sample.csv", checksum)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lines := strings.SplitAfter(string(contents), "\n")
+	_, err = repository.SaveBatch(ctx, datasetID, []models.Geolocation{
+		{IpAddress: "1.1.1.1", CountryCode: "ZZZ", Country: "Integration Testing", City: "DuBuquemouth"},
+		{IpAddress: "1.1.1.2", CountryCode: "ZZZ", Country: "Integration Testing", City: "New Neva"},
+	}, models.Checkpoint{
+		Batch:     0,
+		BatchSize: 2,
+		EndLine:   2,
+		EndOffset: int64(len(strings.Join(lines[:3], ""))),
+		Lines:     models.LineCounts{Total: 2, Accepted: 2},
+	})
+	assert.NoError(t, err)
+
+	fp := processor.NewFileProcessor(repository, processor.WithBatchSize(2))
+
+	result, err := fp.ExecuteFileImport(ctx, "data_dump_sample.csv", 4)
+	assert.NoError(t, err)
+
+	// The counters include the batch committed before the import was resumed
+	assert.Equal(t, models.LineCounts{Total: 10, Accepted: 7, Invalid: 3}, result.Lines)
+
+	testRepository, err := NewRepositoryForIntegrationTesting(repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := testRepository.GetTestRows(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	assert.Len(t, rows, 7)
+
+	// The resumed dataset is now the current one
+	imports, err := repository.ListImports(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, imports, 1) {
+		assert.Equal(t, datasetID, imports[0].DatasetID)
+	}
+
+	err = testRepository.CleanDB(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Test_NewFileProcessor_interrupted checks that importing a file discards the staging dataset of an interrupted
+// import of another file, based on the same sample file as Test_NewFileProcessor.
+func Test_NewFileProcessor_interrupted(t *testing.T) {
+	cfg, err := config.Load("integration", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrateUp(ctx, repository)
+
+	// Simulating an import of another file interrupted after committing its first batch
+	datasetID, err := repository.CreateDataset(ctx, models.DatasetKindLocation, "other_dump.csv", "other")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = repository.SaveBatch(ctx, datasetID, []models.Geolocation{
+		{IpAddress: "9.9.9.9", CountryCode: "ZZZ", Country: "Integration Testing", City: "Interrupted"},
+	}, models.Checkpoint{Batch: 0, BatchSize: 1, EndLine: 1, EndOffset: 100, Lines: models.LineCounts{Total: 1}})
+	assert.NoError(t, err)
+
+	fp := processor.NewFileProcessor(repository)
+
+	_, err = fp.ExecuteFileImport(ctx, "data_dump_sample.csv", 4)
+	assert.NoError(t, err)
 
-	// DB vars
-	if result[EnvDbUser], ok = os.LookupEnv(EnvDbUser); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbUser))
+	testRepository, err := NewRepositoryForIntegrationTesting(repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Only the rows of the sample file are left
+	rows, err := testRepository.GetTestRows(ctx)
+	if err != nil {
+		log.Fatal(err)
 	}
+	assert.Len(t, rows, 7)
+
+	status, err := testRepository.GetDatasetStatus(ctx, datasetID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DatasetStatusFailed, status)
 
-	if result[EnvDbPass], ok = os.LookupEnv(EnvDbPass); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbPass))
+	// The interrupted import can't be resumed anymore
+	resumedID, _, err := repository.ResumableDataset(ctx, models.DatasetKindLocation, "other")
+	assert.NoError(t, err)
+	assert.Zero(t, resumedID)
+
+	err = testRepository.CleanDB(ctx)
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
-	if result[EnvDbHost], ok = os.LookupEnv(EnvDbHost); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbHost))
+// Test_NewFileProcessor_locked checks that a file isn't imported while another process holds the imports lock.
+func Test_NewFileProcessor_locked(t *testing.T) {
+	cfg, err := config.Load("integration", nil)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	if result[EnvDbPort], ok = os.LookupEnv(EnvDbPort); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbPort))
+	ctx := context.Background()
+
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	if result[EnvDbSchema], ok = os.LookupEnv(EnvDbSchema); !ok {
-		return nil, errors.New(fmt.Sprintf("environment variable %s not set", EnvDbSchema))
+	migrateUp(ctx, repository)
+
+	// Another process, with connections of its own
+	other, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	return result, nil
+	unlock, ok, err := other.LockImports(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, ok, err = repository.LockImports(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	fp := processor.NewFileProcessor(repository)
+	_, err = fp.ExecuteFileImport(ctx, "data_dump_sample.csv", 1)
+	assert.ErrorIs(t, err, processor.ErrImportRunning)
+
+	unlock()
+
+	relock, ok, err := repository.LockImports(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	relock()
 }
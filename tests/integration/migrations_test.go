@@ -0,0 +1,77 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tiagocesar/geolocation/internal/config"
+	"github.com/tiagocesar/geolocation/internal/repo"
+	"github.com/tiagocesar/geolocation/internal/repo/migrations"
+)
+
+// Test_Migrations reverts all migrations and applies them again, checking the schema version on each step.
+func Test_Migrations(t *testing.T) {
+	cfg, err := config.Load("integration", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	repository, err := repo.NewRepository(ctx, repoConfig(cfg.DB))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrator, err := repository.Migrator()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	all, err := migrations.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Starting from an up-to-date schema
+	_, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+
+	reverted, err := migrator.Down(ctx, len(all))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(all))
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.Nil(t, s.AppliedAt)
+	}
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(all))
+
+	// Nothing is pending anymore
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+// migrateUp brings the schema up-to-date before running tests that need it.
+func migrateUp(ctx context.Context, repository interface {
+	Migrator() (*migrations.Migrator, error)
+}) {
+	migrator, err := repository.Migrator()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -5,9 +5,10 @@ package integration
 import (
 	"context"
 	"database/sql"
-	"fmt"
 
+	"github.com/tiagocesar/geolocation/internal/config"
 	"github.com/tiagocesar/geolocation/internal/models"
+	"github.com/tiagocesar/geolocation/internal/repo"
 )
 
 // This file defines utility functions to interact with the db that will be used only for integration testing
@@ -16,10 +17,28 @@ type testRepository struct {
 	db *sql.DB
 }
 
-func NewRepositoryForIntegrationTesting(user, pass, host, port, schema string) (*testRepository, error) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, pass, host, port, schema)
+// repoConfig converts the db settings to the repository configuration.
+func repoConfig(db config.DB) repo.Config {
+	return repo.Config{
+		DSN:             db.DSN,
+		User:            db.User,
+		Password:        db.Pass,
+		Host:            db.Host,
+		Port:            db.Port,
+		Database:        db.Name,
+		Schema:          db.Schema,
+		SSLMode:         db.SSLMode,
+		SSLRootCert:     db.SSLRootCert,
+		MaxOpenConns:    db.MaxOpenConns,
+		MaxIdleConns:    db.MaxIdleConns,
+		ConnMaxLifetime: db.ConnMaxLifetime,
+		ConnMaxIdleTime: db.ConnMaxIdleTime,
+		ConnectTimeout:  db.ConnectTimeout,
+	}
+}
 
-	db, err := sql.Open("postgres", connStr)
+func NewRepositoryForIntegrationTesting(cfg repo.Config) (*testRepository, error) {
+	db, err := sql.Open("postgres", cfg.ConnectionString())
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +54,7 @@ func (tr *testRepository) GetTestRows(ctx context.Context) ([]models.Geolocation
 	var result []models.Geolocation
 
 	q := `SELECT ip_address, country_code, country, city, latitude, longitude
-            FROM public.location_info
+            FROM location_info
            WHERE country_code = 'ZZZ'
              AND country = 'Integration Testing'`
 
@@ -58,13 +77,31 @@ func (tr *testRepository) GetTestRows(ctx context.Context) ([]models.Geolocation
 	return result, nil
 }
 
+// GetDatasetStatus gets the status of a dataset.
+func (tr *testRepository) GetDatasetStatus(ctx context.Context, id int64) (string, error) {
+	var status string
+	err := tr.db.QueryRowContext(ctx, `SELECT status FROM datasets WHERE id = $1`, id).Scan(&status)
+
+	return status, err
+}
+
 // CleanDB will remove all testing data from the database.
 // to identify testing data we use "ZZZ" as country code and "Integration Testing" as country
 func (tr *testRepository) CleanDB(ctx context.Context) error {
 	_, err := tr.db.ExecContext(ctx,
-		`DELETE FROM public.location_info
+		`DELETE FROM location_info
                  WHERE country_code = 'ZZZ'
                    AND country = 'Integration Testing'`)
 
 	return err
 }
+
+// CleanASN will remove all testing data from the ASN table.
+// to identify testing data we use "Integration Testing" as organization
+func (tr *testRepository) CleanASN(ctx context.Context) error {
+	_, err := tr.db.ExecContext(ctx,
+		`DELETE FROM asn_info
+                 WHERE organization = 'Integration Testing'`)
+
+	return err
+}